@@ -0,0 +1,332 @@
+package main
+
+import (
+	"encoding/xml"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"io"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/kkettinger/tsactl/internal/util"
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/basicfont"
+	"golang.org/x/image/math/fixed"
+	"golang.org/x/image/vector"
+)
+
+// chartColors are the line colors of the chart series, repeated if there are more series.
+var chartColors = []color.RGBA{
+	{31, 119, 180, 255}, {255, 127, 14, 255}, {44, 160, 44, 255}, {214, 39, 40, 255},
+	{148, 103, 189, 255}, {140, 86, 75, 255}, {227, 119, 194, 255}, {127, 127, 127, 255},
+}
+
+var (
+	chartBlack = color.RGBA{0, 0, 0, 255}
+	chartWhite = color.RGBA{255, 255, 255, 255}
+	chartGrid  = color.RGBA{221, 221, 221, 255}
+	chartGray  = color.RGBA{136, 136, 136, 255}
+)
+
+const (
+	chartMarginLeft   = 64
+	chartMarginRight  = 40
+	chartMarginTop    = 40
+	chartMarginBottom = 48
+
+	// chartCharWidth is the width of a character, used to size the legend
+	chartCharWidth = 7
+)
+
+// chart is a line chart of trace levels over frequency.
+type chart struct {
+	title         string
+	unit          string
+	width, height int
+	min, max      *float64
+	series        []chartSeries
+	annotations   []chartAnnotation
+}
+
+type chartSeries struct {
+	name        string
+	frequencies []uint64
+	values      []float64
+}
+
+// chartAnnotation labels the point of a series with its frequency and level.
+type chartAnnotation struct {
+	series int
+	point  int
+}
+
+// chartCanvas is the drawing surface of a chart, with coordinates in pixels.
+type chartCanvas interface {
+	rect(x0, y0, x1, y1 float64, c color.RGBA)
+	polyline(xs, ys []float64, width float64, c color.RGBA)
+	circle(x, y, r float64, c color.RGBA)
+	// text draws s with its baseline at y, aligned at x by align (0 left, 0.5 centered, 1 right)
+	text(x, y float64, s string, align float64, c color.RGBA)
+}
+
+// frequencyRange returns the lowest and highest frequency of all series.
+func (ch *chart) frequencyRange() (uint64, uint64) {
+	start, stop := uint64(math.MaxUint64), uint64(0)
+	for _, s := range ch.series {
+		for _, freq := range s.frequencies {
+			start, stop = min(start, freq), max(stop, freq)
+		}
+	}
+	return start, stop
+}
+
+// levelRange returns the range of the level axis, fitted to the data unless set explicitly.
+func (ch *chart) levelRange() (float64, float64) {
+	lo, hi := math.Inf(1), math.Inf(-1)
+	for _, s := range ch.series {
+		for _, v := range s.values {
+			lo, hi = min(lo, v), max(hi, v)
+		}
+	}
+	if ch.min != nil {
+		lo = *ch.min
+	}
+	if ch.max != nil {
+		hi = *ch.max
+	}
+
+	step := niceStep(hi-lo, 6)
+	if ch.min == nil {
+		lo = math.Floor(lo/step) * step
+	}
+	if ch.max == nil {
+		hi = math.Ceil(hi/step) * step
+	}
+	if hi <= lo {
+		hi = lo + 10
+	}
+	return lo, hi
+}
+
+// draw renders the chart with the title on top, the level axis on the left, the frequency axis
+// below and the legend in the upper right corner of the plot area.
+func (ch *chart) draw(cv chartCanvas) {
+	w, h := float64(ch.width), float64(ch.height)
+	x0, y0 := float64(chartMarginLeft), float64(chartMarginTop)
+	x1, y1 := w-chartMarginRight, h-chartMarginBottom
+
+	start, stop := ch.frequencyRange()
+	span := float64(max(stop-start, 1))
+	lo, hi := ch.levelRange()
+	px := func(freq uint64) float64 { return x0 + float64(freq-start)/span*(x1-x0) }
+	py := func(v float64) float64 { return y1 - (min(max(v, lo), hi)-lo)/(hi-lo)*(y1-y0) }
+	// crisp keeps one pixel wide lines on the pixel grid
+	crisp := func(v float64) float64 { return math.Round(v) + 0.5 }
+
+	cv.rect(0, 0, w, h, chartWhite)
+	if ch.title != "" {
+		cv.text(w/2, 22, ch.title, 0.5, chartBlack)
+	}
+
+	// level axis with grid
+	step := niceStep(hi-lo, max(int(y1-y0)/60, 2))
+	precision := max(0, -int(math.Floor(math.Log10(step))))
+	for v := math.Ceil(lo/step) * step; v <= hi+step/1e6; v += step {
+		y := crisp(py(v))
+		cv.polyline([]float64{x0, x1}, []float64{y, y}, 1, chartGrid)
+		cv.polyline([]float64{x0 - 4, x0}, []float64{y, y}, 1, chartBlack)
+		cv.text(x0-7, y+4, strconv.FormatFloat(v, 'f', precision, 64), 1, chartBlack)
+	}
+	cv.text(x0-7, y0-12, "Level ("+ch.unit+")", 0, chartBlack)
+
+	// frequency axis with grid
+	if stop > start {
+		step := uint64(niceStep(float64(stop-start), max(int(x1-x0)/120, 2)))
+		for freq := (start + step - 1) / step * step; freq <= stop; freq += step {
+			x := crisp(px(freq))
+			cv.polyline([]float64{x, x}, []float64{y0, y1}, 1, chartGrid)
+			cv.polyline([]float64{x, x}, []float64{y1, y1 + 4}, 1, chartBlack)
+			cv.text(x, y1+18, util.FormatFrequency(freq), 0.5, chartBlack)
+		}
+	} else {
+		cv.text(x0, y1+18, util.FormatFrequency(start), 0.5, chartBlack)
+	}
+	cv.text((x0+x1)/2, h-10, "Frequency", 0.5, chartBlack)
+
+	strokeRect(cv, crisp(x0), crisp(y0), crisp(x1), crisp(y1), chartBlack)
+
+	// series
+	for i, s := range ch.series {
+		xs, ys := make([]float64, len(s.values)), make([]float64, len(s.values))
+		for j, v := range s.values {
+			xs[j], ys[j] = px(s.frequencies[j]), py(v)
+		}
+		cv.polyline(xs, ys, 1.5, chartColors[i%len(chartColors)])
+	}
+
+	// annotations, the label is placed left of the point in the right part of the plot
+	// and moved up or down until it doesn't overlap a previous label
+	var labels []image.Rectangle
+	for _, a := range ch.annotations {
+		s := ch.series[a.series]
+		c := chartColors[a.series%len(chartColors)]
+		x, y := px(s.frequencies[a.point]), py(s.values[a.point])
+		label := fmt.Sprintf("%s %s %s", util.FormatFrequency(s.frequencies[a.point]),
+			strconv.FormatFloat(s.values[a.point], 'f', -1, 64), ch.unit)
+		cv.circle(x, y, 3.5, c)
+
+		lx, align := x+7, 0.0
+		if x > x0+(x1-x0)*0.7 {
+			lx, align = x-7, 1.0
+		}
+		lw := float64(len(label) * chartCharWidth)
+		base := min(max(y-7, y0+14), y1-4)
+		ly := base
+		for i := range 2 * len(ch.annotations) {
+			// try base, base+14, base-14, base+28, ...
+			ly = base + float64((i+1)/2*14*(i%2*2-1))
+			r := image.Rect(int(lx-lw*align)-2, int(ly)-11, int(lx-lw*align+lw)+2, int(ly)+3)
+			if ly >= y0+14 && ly <= y1-4 && !slices.ContainsFunc(labels, r.Overlaps) {
+				labels = append(labels, r)
+				break
+			}
+		}
+		cv.rect(lx-lw*align-2, ly-11, lx-lw*align+lw+2, ly+3, chartWhite)
+		cv.text(lx, ly, label, align, c)
+	}
+
+	// legend
+	width := 0
+	for _, s := range ch.series {
+		width = max(width, len(s.name))
+	}
+	lw, lh := float64(width*chartCharWidth+44), float64(len(ch.series)*18+8)
+	lx, ly := crisp(x1-lw-8), crisp(y0+8)
+	cv.rect(lx, ly, lx+lw, ly+lh, chartWhite)
+	strokeRect(cv, lx, ly, lx+lw, ly+lh, chartGray)
+	for i, s := range ch.series {
+		y := ly + 17 + float64(i)*18
+		cv.polyline([]float64{lx + 8, lx + 30}, []float64{y - 4, y - 4}, 2, chartColors[i%len(chartColors)])
+		cv.text(lx+36, y, s.name, 0, chartBlack)
+	}
+}
+
+// strokeRect draws the one pixel wide outline of a rectangle.
+func strokeRect(cv chartCanvas, x0, y0, x1, y1 float64, c color.RGBA) {
+	cv.polyline([]float64{x0, x0, x1, x1, x0}, []float64{y0, y1, y1, y0, y0}, 1, c)
+}
+
+// svgCanvas writes the chart as SVG.
+type svgCanvas struct {
+	b strings.Builder
+}
+
+func newSvgCanvas(width, height int) *svgCanvas {
+	cv := &svgCanvas{}
+	_, _ = fmt.Fprintf(&cv.b, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d" font-family="sans-serif" font-size="12">`+"\n",
+		width, height, width, height)
+	return cv
+}
+
+func (cv *svgCanvas) rect(x0, y0, x1, y1 float64, c color.RGBA) {
+	_, _ = fmt.Fprintf(&cv.b, `<rect x="%s" y="%s" width="%s" height="%s" fill="%s"/>`+"\n",
+		svgNumber(x0), svgNumber(y0), svgNumber(x1-x0), svgNumber(y1-y0), svgColor(c))
+}
+
+func (cv *svgCanvas) polyline(xs, ys []float64, width float64, c color.RGBA) {
+	points := make([]string, len(xs))
+	for i := range xs {
+		points[i] = svgNumber(xs[i]) + "," + svgNumber(ys[i])
+	}
+	_, _ = fmt.Fprintf(&cv.b, `<polyline points="%s" fill="none" stroke="%s" stroke-width="%s" stroke-linejoin="round"/>`+"\n",
+		strings.Join(points, " "), svgColor(c), svgNumber(width))
+}
+
+func (cv *svgCanvas) circle(x, y, r float64, c color.RGBA) {
+	_, _ = fmt.Fprintf(&cv.b, `<circle cx="%s" cy="%s" r="%s" fill="%s"/>`+"\n",
+		svgNumber(x), svgNumber(y), svgNumber(r), svgColor(c))
+}
+
+func (cv *svgCanvas) text(x, y float64, s string, align float64, c color.RGBA) {
+	anchor := "start"
+	if align == 0.5 {
+		anchor = "middle"
+	} else if align == 1 {
+		anchor = "end"
+	}
+	_, _ = fmt.Fprintf(&cv.b, `<text x="%s" y="%s" text-anchor="%s" fill="%s">`, svgNumber(x), svgNumber(y), anchor, svgColor(c))
+	_ = xml.EscapeText(&cv.b, []byte(s))
+	cv.b.WriteString("</text>\n")
+}
+
+func (cv *svgCanvas) writeTo(w io.Writer) error {
+	_, err := io.WriteString(w, cv.b.String()+"</svg>\n")
+	return err
+}
+
+func svgNumber(v float64) string {
+	return strconv.FormatFloat(math.Round(v*100)/100, 'f', -1, 64)
+}
+
+func svgColor(c color.RGBA) string {
+	return fmt.Sprintf("#%02x%02x%02x", c.R, c.G, c.B)
+}
+
+// pngCanvas draws the chart to an image, with anti-aliased lines.
+type pngCanvas struct {
+	img *image.RGBA
+}
+
+func newPngCanvas(width, height int) *pngCanvas {
+	return &pngCanvas{img: image.NewRGBA(image.Rect(0, 0, width, height))}
+}
+
+func (cv *pngCanvas) rect(x0, y0, x1, y1 float64, c color.RGBA) {
+	r := image.Rect(int(math.Round(x0)), int(math.Round(y0)), int(math.Round(x1)), int(math.Round(y1)))
+	draw.Draw(cv.img, r, image.NewUniform(c), image.Point{}, draw.Over)
+}
+
+// polyline strokes each segment as a quad, all quads have the same winding so overlaps don't cancel out.
+func (cv *pngCanvas) polyline(xs, ys []float64, width float64, c color.RGBA) {
+	z := vector.NewRasterizer(cv.img.Bounds().Dx(), cv.img.Bounds().Dy())
+	for i := 1; i < len(xs); i++ {
+		dx, dy := xs[i]-xs[i-1], ys[i]-ys[i-1]
+		length := math.Hypot(dx, dy)
+		if length == 0 {
+			continue
+		}
+		// extend the segment by half the width to close the gaps at the joints
+		ex, ey := dx/length*width/2, dy/length*width/2
+		nx, ny := -ey, ex
+		ax, ay := xs[i-1]-ex, ys[i-1]-ey
+		bx, by := xs[i]+ex, ys[i]+ey
+		z.MoveTo(float32(ax+nx), float32(ay+ny))
+		z.LineTo(float32(bx+nx), float32(by+ny))
+		z.LineTo(float32(bx-nx), float32(by-ny))
+		z.LineTo(float32(ax-nx), float32(ay-ny))
+		z.ClosePath()
+	}
+	z.Draw(cv.img, cv.img.Bounds(), image.NewUniform(c), image.Point{})
+}
+
+func (cv *pngCanvas) circle(x, y, r float64, c color.RGBA) {
+	z := vector.NewRasterizer(cv.img.Bounds().Dx(), cv.img.Bounds().Dy())
+	z.MoveTo(float32(x+r), float32(y))
+	for i := 1; i < 24; i++ {
+		a := float64(i) / 24 * 2 * math.Pi
+		z.LineTo(float32(x+r*math.Cos(a)), float32(y+r*math.Sin(a)))
+	}
+	z.ClosePath()
+	z.Draw(cv.img, cv.img.Bounds(), image.NewUniform(c), image.Point{})
+}
+
+func (cv *pngCanvas) text(x, y float64, s string, align float64, c color.RGBA) {
+	drawer := &font.Drawer{Dst: cv.img, Src: image.NewUniform(c), Face: basicfont.Face7x13}
+	width := drawer.MeasureString(s).Round()
+	drawer.Dot = fixed.P(int(math.Round(x))-int(float64(width)*align), int(math.Round(y)))
+	drawer.DrawString(s)
+}
@@ -0,0 +1,222 @@
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"math"
+	"os"
+	"os/signal"
+	"slices"
+	"strings"
+	"syscall"
+	"text/tabwriter"
+	"time"
+
+	"github.com/kkettinger/go-tinysa"
+	"github.com/kkettinger/tsactl/internal/util"
+)
+
+const (
+	channelModeSweep = "sweep"
+	channelModeCW    = "cw"
+)
+
+type ChannelsCmd struct {
+	Plan  string        `help:"Channel plan CSV with the columns name, center and bandwidth" short:"p" required:"" type:"existingfile" group:"Channels flags:" placeholder:"PATH"`
+	Trace uint          `help:"Trace to measure" short:"t" default:"1" group:"Channels flags:"`
+	Mode  string        `help:"Sweep over each channel and integrate its power, or measure at the center in zero span (${enum})" short:"m" default:"sweep" enum:"sweep,cw" group:"Channels flags:"`
+	RBW   Frequency     `help:"Resolution bandwidth of the sweep, defaults to the point spacing like the automatic RBW of the device" name:"rbw" group:"Channels flags:" placeholder:"FREQ"`
+	Dwell time.Duration `help:"Time to wait after retuning before reading a channel, should be at least one sweep time" default:"500ms" group:"Channels flags:"`
+}
+
+// planChannel is a channel of the channel plan.
+type planChannel struct {
+	Name      string
+	Center    uint64
+	Bandwidth uint64
+}
+
+type channelLevelInfo struct {
+	Name          string  `json:"name" yaml:"name"`
+	Center        uint64  `json:"center" yaml:"center"`
+	Bandwidth     uint64  `json:"bandwidth" yaml:"bandwidth"`
+	Power         float64 `json:"power_dbm" yaml:"power_dbm"`
+	PeakLevel     float64 `json:"peak_dbm" yaml:"peak_dbm"`
+	PeakFrequency uint64  `json:"peak_frequency" yaml:"peak_frequency"`
+}
+
+func (c *ChannelsCmd) Run(globals *Globals) (err error) {
+	plan, err := loadChannelPlan(c.Plan, c.Mode == channelModeSweep)
+	if err != nil {
+		return err
+	}
+
+	d, err := initDevice(globals)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+
+	limit := deviceLimit(d.Model())
+	for _, ch := range plan {
+		if ch.Center+ch.Bandwidth/2 > limit.maxFrequency {
+			return fmt.Errorf("channel '%s' exceeds the maximum of %s of the %s", ch.Name, util.FormatFrequency(limit.maxFrequency), d.Model())
+		}
+	}
+
+	trace, err := d.GetTrace(c.Trace)
+	if err != nil {
+		return fmt.Errorf("failed to get trace: %w", err)
+	}
+
+	// restore the sweep settings when done, also on errors and Ctrl-C
+	tuner, err := newSweepTuner(d)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if rerr := tuner.restore(); rerr != nil && err == nil {
+			err = rerr
+		}
+	}()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	infos := make([]channelLevelInfo, 0, len(plan))
+	for _, ch := range plan {
+		if c.Mode == channelModeCW {
+			if err := tuner.continuousWave(ch.Center); err != nil {
+				return fmt.Errorf("failed to set zero span for channel '%s': %w", ch.Name, err)
+			}
+		} else {
+			target := tinysa.Sweep{Start: ch.Center - ch.Bandwidth/2, Stop: ch.Center + ch.Bandwidth/2, Points: tuner.original.Points}
+			if err := tuner.tune(target); err != nil {
+				return fmt.Errorf("failed to set sweep for channel '%s': %w", ch.Name, err)
+			}
+		}
+
+		// wait for a complete sweep with the new settings
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("measurement interrupted")
+		case <-time.After(c.Dwell):
+		}
+
+		data, err := d.GetTraceData(c.Trace)
+		if err != nil {
+			return fmt.Errorf("failed to get trace data for channel '%s': %w", ch.Name, err)
+		}
+		info, err := c.measureChannel(ch, data, trace.Unit)
+		if err != nil {
+			return fmt.Errorf("failed to measure channel '%s': %w", ch.Name, err)
+		}
+		infos = append(infos, info)
+	}
+
+	return printChannelLevels(globals.Format, infos)
+}
+
+// measureChannel returns the integrated power and the peak of the channel. In zero span, the power is
+// the average of all points in linear power.
+func (c *ChannelsCmd) measureChannel(ch planChannel, data []tinysa.TraceData, unit tinysa.TraceUnit) (channelLevelInfo, error) {
+	info := channelLevelInfo{Name: ch.Name, Center: ch.Center, Bandwidth: ch.Bandwidth}
+	if len(data) == 0 {
+		return info, fmt.Errorf("trace contains no points")
+	}
+
+	levels := make([]float64, len(data))
+	for i, dp := range data {
+		dBm, err := toDBm(dp.Value, unit)
+		if err != nil {
+			return info, err
+		}
+		levels[i] = dBm
+	}
+	peak := slices.Index(levels, slices.Max(levels))
+	info.PeakLevel, info.PeakFrequency = levels[peak], data[peak].Frequency
+
+	if c.Mode == channelModeCW {
+		avg, err := averagePower(levels, tinysa.TraceUnitDBm)
+		if err != nil {
+			return info, err
+		}
+		info.Power = avg
+		return info, nil
+	}
+
+	spec, err := newSpectrum(data, unit, c.RBW.Value)
+	if err != nil {
+		return info, err
+	}
+	power, err := spec.channelPower(ch.Center-ch.Bandwidth/2, ch.Center+ch.Bandwidth/2)
+	if err != nil {
+		return info, err
+	}
+	// remove the float noise of the integration like the average of the cw mode
+	info.Power = math.Round(power*1e6) / 1e6
+	return info, nil
+}
+
+func printChannelLevels(format string, infos []channelLevelInfo) error {
+	if format != formatText {
+		return printStructured(format, infos)
+	}
+
+	w := tabwriter.NewWriter(stdout, 0, 0, 3, ' ', 0)
+	_, _ = fmt.Fprintln(w, "Channel\tCenter\tBandwidth\tPower\tPeak")
+	for _, info := range infos {
+		_, _ = fmt.Fprintf(w, "%s\t%s\t%s\t%.2f dBm\t%.2f dBm at %s\n", info.Name, util.FormatFrequency(info.Center),
+			util.FormatFrequency(info.Bandwidth), info.Power, info.PeakLevel, util.FormatFrequency(info.PeakFrequency))
+	}
+	return w.Flush()
+}
+
+// loadChannelPlan reads a channel plan CSV with a header of name, center and bandwidth. Frequencies may have
+// a unit suffix like 446.00625M, lines starting with # are skipped. The bandwidth is optional unless required.
+func loadChannelPlan(path string, requireBandwidth bool) ([]planChannel, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open plan '%s': %w", path, err)
+	}
+	defer file.Close()
+
+	r := csv.NewReader(file)
+	r.Comment = '#'
+	r.TrimLeadingSpace = true
+	records, err := r.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read plan '%s': %w", path, err)
+	}
+	if len(records) == 0 || !slices.Equal(records[0], []string{"name", "center", "bandwidth"}) {
+		return nil, fmt.Errorf("plan '%s' must start with a header of name, center and bandwidth", path)
+	}
+	if len(records) == 1 {
+		return nil, fmt.Errorf("plan '%s' contains no channels", path)
+	}
+
+	plan := make([]planChannel, 0, len(records)-1)
+	for i, row := range records[1:] {
+		ch := planChannel{Name: strings.TrimSpace(row[0])}
+		if ch.Name == "" {
+			return nil, fmt.Errorf("failed to parse plan '%s' line %d: missing name", path, i+2)
+		}
+		if ch.Center, err = util.ParseFrequency(row[1]); err != nil {
+			return nil, fmt.Errorf("failed to parse plan '%s' line %d: %w", path, i+2, err)
+		}
+		if bw := strings.TrimSpace(row[2]); bw != "" {
+			if ch.Bandwidth, err = util.ParseFrequency(bw); err != nil {
+				return nil, fmt.Errorf("failed to parse plan '%s' line %d: %w", path, i+2, err)
+			}
+		}
+		if requireBandwidth && ch.Bandwidth == 0 {
+			return nil, fmt.Errorf("channel '%s' of plan '%s' needs a bandwidth to sweep over", ch.Name, path)
+		}
+		if ch.Bandwidth/2 > ch.Center {
+			return nil, fmt.Errorf("channel '%s' of plan '%s' extends below 0 Hz", ch.Name, path)
+		}
+		plan = append(plan, ch)
+	}
+	return plan, nil
+}
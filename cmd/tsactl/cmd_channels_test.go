@@ -0,0 +1,131 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/kkettinger/go-tinysa"
+)
+
+func TestChannelsCmd(t *testing.T) {
+	plan := filepath.Join(t.TempDir(), "plan.csv")
+	content := "name,center,bandwidth\n# comment\nWide,450M,100M\nNarrow,450M,50M\n"
+	if err := os.WriteFile(plan, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	highPlan := filepath.Join(t.TempDir(), "high.csv")
+	if err := os.WriteFile(highPlan, []byte("name,center,bandwidth\nHigh,950M,40M\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	sweepCalls := []string{
+		"GetTrace(1)", "GetSweep()",
+		"SetSweepStart(400000000)", "SetSweepStop(500000000)", "GetTraceData(1)",
+		"SetSweepStart(425000000)", "SetSweepStop(475000000)", "GetTraceData(1)",
+		"SetSweepStart(400000000)", "SetSweepStop(500000000)",
+		"Close()",
+	}
+
+	runCliTests(t, []cliTest{
+		{
+			name: "sweep",
+			args: []string{"channels", "--plan", plan, "--dwell", "0"},
+			wantOut: "Channel   Center    Bandwidth   Power        Peak\n" +
+				"Wide      450 MHz   100 MHz     -40.50 dBm   -40.50 dBm at 450 MHz\n" +
+				"Narrow    450 MHz   50 MHz      -40.50 dBm   -40.50 dBm at 450 MHz\n",
+			wantCalls: sweepCalls,
+		},
+		{
+			name: "csv",
+			args: []string{"-F", "csv", "channels", "--plan", plan, "--dwell", "0"},
+			wantOut: "name,center,bandwidth,power_dbm,peak_dbm,peak_frequency\n" +
+				"Wide,450000000,100000000,-40.499951,-40.5,450000000\n" +
+				"Narrow,450000000,50000000,-40.5,-40.5,450000000\n",
+			wantCalls: sweepCalls,
+		},
+		{
+			name: "cw in json",
+			args: []string{"-F", "json", "channels", "--plan", plan, "--mode", "cw", "--dwell", "0"},
+			wantOut: `[
+  {
+    "name": "Wide",
+    "center": 450000000,
+    "bandwidth": 100000000,
+    "power_dbm": -45.271115,
+    "peak_dbm": -40.5,
+    "peak_frequency": 450000000
+  },
+  {
+    "name": "Narrow",
+    "center": 450000000,
+    "bandwidth": 50000000,
+    "power_dbm": -45.271115,
+    "peak_dbm": -40.5,
+    "peak_frequency": 450000000
+  }
+]
+`,
+			wantCalls: []string{
+				"GetTrace(1)", "GetSweep()",
+				"SetSweepContinuousWave(450000000)", "GetTraceData(1)",
+				"SetSweepContinuousWave(450000000)", "GetTraceData(1)",
+				"SetSweepStart(400000000)", "SetSweepStop(500000000)",
+				"Close()",
+			},
+		},
+		{
+			name:      "channel above model limit",
+			args:      []string{"channels", "--plan", highPlan},
+			setup:     func(d *fakeDevice) { d.model = tinysa.ModelBasic },
+			wantCalls: []string{"Close()"},
+			wantErr:   true,
+		},
+	})
+}
+
+func TestLoadChannelPlan(t *testing.T) {
+	tests := []struct {
+		name             string
+		content          string
+		requireBandwidth bool
+		want             []planChannel
+		wantErr          bool
+	}{
+		{
+			name:             "channels",
+			content:          "name,center,bandwidth\nPMR446 1, 446.00625M, 12.5k\nLoRa,868.1M,125k\n",
+			requireBandwidth: true,
+			want:             []planChannel{{"PMR446 1", 446_006_250, 12_500}, {"LoRa", 868_100_000, 125_000}},
+		},
+		{
+			name:    "bandwidth optional",
+			content: "name,center,bandwidth\nCW,100M,\n",
+			want:    []planChannel{{"CW", 100_000_000, 0}},
+		},
+		{name: "bandwidth required", content: "name,center,bandwidth\nCW,100M,\n", requireBandwidth: true, wantErr: true},
+		{name: "missing header", content: "CW,100M,1M\n", wantErr: true},
+		{name: "no channels", content: "name,center,bandwidth\n", wantErr: true},
+		{name: "invalid center", content: "name,center,bandwidth\nCW,abc,1M\n", wantErr: true},
+		{name: "missing name", content: "name,center,bandwidth\n,100M,1M\n", wantErr: true},
+		{name: "below 0 Hz", content: "name,center,bandwidth\nDC,1M,4M\n", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "plan.csv")
+			if err := os.WriteFile(path, []byte(tt.content), 0o600); err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := loadChannelPlan(path, tt.requireBandwidth)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !slices.Equal(got, tt.want) {
+				t.Errorf("plan = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,181 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"text/tabwriter"
+
+	"github.com/kkettinger/go-tinysa"
+	"github.com/kkettinger/tsactl/internal/util"
+)
+
+// exitCodeCheckFailed is the exit code of a failed check, other errors exit with 1.
+const exitCodeCheckFailed = 2
+
+type CheckCmd struct {
+	Trace   uint   `help:"Trace to check" short:"t" default:"1" group:"Check flags:"`
+	Mask    string `help:"Mask file with upper and/or lower limits (.yaml or .csv)" short:"m" required:"" type:"existingfile" group:"Check flags:" placeholder:"PATH"`
+	Capture string `help:"Save a screen capture to this path when the check fails, supports <date> and <time>" short:"c" type:"path" group:"Check flags:" placeholder:"PATH"`
+}
+
+type violationInfo struct {
+	Frequency  uint64  `json:"frequency" yaml:"frequency"`
+	Value      float64 `json:"value" yaml:"value"`
+	Limit      string  `json:"limit" yaml:"limit"`
+	LimitLevel float64 `json:"limit_level" yaml:"limit_level"`
+	Margin     float64 `json:"margin" yaml:"margin"`
+}
+
+type checkInfo struct {
+	Pass           bool            `json:"pass" yaml:"pass"`
+	Points         int             `json:"points" yaml:"points"`
+	WorstMargin    float64         `json:"worst_margin" yaml:"worst_margin"`
+	WorstFrequency uint64          `json:"worst_frequency" yaml:"worst_frequency"`
+	Violations     []violationInfo `json:"violations" yaml:"violations"`
+	Capture        string          `json:"capture,omitempty" yaml:"capture,omitempty"`
+}
+
+// checkFailedError is returned when the trace violates the mask.
+type checkFailedError struct {
+	trace      uint
+	violations int
+}
+
+func (e *checkFailedError) Error() string {
+	return fmt.Sprintf("check failed, trace %d violates the mask at %d points", e.trace, e.violations)
+}
+
+func (e *checkFailedError) ExitCode() int {
+	return exitCodeCheckFailed
+}
+
+func (c *CheckCmd) Run(globals *Globals) error {
+	m, err := loadMask(c.Mask)
+	if err != nil {
+		return err
+	}
+
+	d, err := initDevice(globals)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+
+	trace, err := d.GetTrace(c.Trace)
+	if err != nil {
+		return fmt.Errorf("failed to get trace: %w", err)
+	}
+	data, err := d.GetTraceData(c.Trace)
+	if err != nil {
+		return fmt.Errorf("failed to get trace data: %w", err)
+	}
+
+	info := checkMask(m, data)
+	if info.Points == 0 {
+		return fmt.Errorf("mask '%s' doesn't cover any point of trace %d", c.Mask, c.Trace)
+	}
+
+	if !info.Pass && c.Capture != "" {
+		info.Capture = replaceFilenamePlaceholdersDateTime(c.Capture)
+		if err := saveCapture(d, info.Capture); err != nil {
+			return err
+		}
+	}
+
+	if err := c.printResult(globals.Format, trace.Unit.String(), info); err != nil {
+		return err
+	}
+
+	if !info.Pass {
+		return &checkFailedError{trace: c.Trace, violations: len(info.Violations)}
+	}
+	return nil
+}
+
+func (c *CheckCmd) printResult(format, unit string, info checkInfo) error {
+	switch format {
+	case formatText:
+		// levels are compared in the unit of the trace
+		marginUnit := deltaUnit(unit)
+
+		if len(info.Violations) > 0 {
+			w := tabwriter.NewWriter(stdout, 0, 0, 3, ' ', 0)
+			_, _ = fmt.Fprintln(w, "Frequency\tLevel\tLimit\tMargin")
+			for _, v := range info.Violations {
+				_, _ = fmt.Fprintf(w, "%s\t%g %s\t%s %g %s\t%.2f %s\n", util.FormatFrequency(v.Frequency), v.Value, unit,
+					v.Limit, v.LimitLevel, unit, v.Margin, marginUnit)
+			}
+			_ = w.Flush()
+		}
+
+		result := "PASS"
+		if !info.Pass {
+			result = fmt.Sprintf("FAIL: %d of %d points violate the mask", len(info.Violations), info.Points)
+		}
+		_, _ = fmt.Fprintf(stdout, "%s, worst margin %.2f %s at %s\n", result, info.WorstMargin, marginUnit, util.FormatFrequency(info.WorstFrequency))
+		if info.Capture != "" {
+			_, _ = fmt.Fprintf(stdout, "capture saved to %s\n", info.Capture)
+		}
+		return nil
+	case formatCSV:
+		rows := make([][]string, len(info.Violations))
+		for i, v := range info.Violations {
+			rows[i] = []string{
+				strconv.FormatUint(v.Frequency, 10),
+				strconv.FormatFloat(v.Value, 'f', -1, 64),
+				v.Limit,
+				strconv.FormatFloat(v.LimitLevel, 'f', -1, 64),
+				strconv.FormatFloat(v.Margin, 'f', -1, 64),
+			}
+		}
+		return printCSV([]string{"frequency", "value", "limit", "limit_level", "margin"}, rows)
+	default:
+		return printStructured(format, info)
+	}
+}
+
+// checkMask compares every point covered by the mask against its limits. The margin is the distance
+// to the limit, negative if the point violates it.
+func checkMask(m *mask, data []tinysa.TraceData) checkInfo {
+	info := checkInfo{Pass: true, WorstMargin: math.Inf(1), Violations: []violationInfo{}}
+
+	for _, dp := range data {
+		covered := false
+		for _, limit := range []string{"upper", "lower"} {
+			var level, margin float64
+			var ok bool
+			if limit == "upper" {
+				level, ok = limitAt(m.Upper, dp.Frequency, true)
+				margin = level - dp.Value
+			} else {
+				level, ok = limitAt(m.Lower, dp.Frequency, false)
+				margin = dp.Value - level
+			}
+			if !ok {
+				continue
+			}
+			covered = true
+			// remove the float noise of the interpolation
+			level, margin = math.Round(level*1e6)/1e6, math.Round(margin*1e6)/1e6
+
+			if margin < info.WorstMargin {
+				info.WorstMargin, info.WorstFrequency = margin, dp.Frequency
+			}
+			if margin < 0 {
+				info.Pass = false
+				info.Violations = append(info.Violations, violationInfo{
+					Frequency: dp.Frequency, Value: dp.Value, Limit: limit, LimitLevel: level, Margin: margin,
+				})
+			}
+		}
+		if covered {
+			info.Points++
+		}
+	}
+
+	if info.Points == 0 {
+		info.WorstMargin = 0
+	}
+	return info
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCheckCmd(t *testing.T) {
+	const (
+		passMask = "upper:\n  - {frequency: 400M, level: -30}\n  - {frequency: 500M, level: -30}\n" +
+			"lower:\n  - {frequency: 400M, level: -100}\n  - {frequency: 500M, level: -100}\n"
+		failMask = "frequency,upper\n400M,-50\n500M,-50\n"
+	)
+
+	tests := []struct {
+		name      string
+		mask      string
+		args      []string
+		wantOut   string
+		wantCalls []string
+		wantFail  bool
+	}{
+		{
+			name:      "pass",
+			mask:      passMask,
+			args:      []string{"check", "--mask", "mask.yaml"},
+			wantOut:   "PASS, worst margin 9.75 dB at 400 MHz\n",
+			wantCalls: []string{"GetTrace(1)", "GetTraceData(1)", "Close()"},
+		},
+		{
+			name: "fail",
+			mask: failMask,
+			args: []string{"check", "--mask", "mask.csv"},
+			wantOut: "Frequency   Level       Limit           Margin\n" +
+				"450 MHz     -40.5 dBm   upper -50 dBm   -9.50 dB\n" +
+				"FAIL: 1 of 3 points violate the mask, worst margin -9.50 dB at 450 MHz\n",
+			wantCalls: []string{"GetTrace(1)", "GetTraceData(1)", "Close()"},
+			wantFail:  true,
+		},
+		{
+			name: "fail with capture",
+			mask: failMask,
+			args: []string{"check", "--mask", "mask.csv", "--capture", "fail.png"},
+			wantOut: "Frequency   Level       Limit           Margin\n" +
+				"450 MHz     -40.5 dBm   upper -50 dBm   -9.50 dB\n" +
+				"FAIL: 1 of 3 points violate the mask, worst margin -9.50 dB at 450 MHz\n" +
+				"capture saved to fail.png\n",
+			wantCalls: []string{"GetTrace(1)", "GetTraceData(1)", "Capture()", "Close()"},
+			wantFail:  true,
+		},
+		{
+			name:      "pass without capture",
+			mask:      passMask,
+			args:      []string{"check", "--mask", "mask.yaml", "--capture", "fail.png"},
+			wantOut:   "PASS, worst margin 9.75 dB at 400 MHz\n",
+			wantCalls: []string{"GetTrace(1)", "GetTraceData(1)", "Close()"},
+		},
+		{
+			name:      "csv",
+			mask:      failMask,
+			args:      []string{"-F", "csv", "check", "--mask", "mask.csv", "--trace", "2"},
+			wantOut:   "frequency,value,limit,limit_level,margin\n450000000,-35,upper,-50,-15\n",
+			wantCalls: []string{"GetTrace(2)", "GetTraceData(2)", "Close()"},
+			wantFail:  true,
+		},
+		{
+			name: "json",
+			mask: passMask,
+			args: []string{"-F", "json", "check", "--mask", "mask.yaml"},
+			wantOut: `{
+  "pass": true,
+  "points": 3,
+  "worst_margin": 9.75,
+  "worst_frequency": 400000000,
+  "violations": []
+}
+`,
+			wantCalls: []string{"GetTrace(1)", "GetTraceData(1)", "Close()"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Chdir(t.TempDir())
+			name := "mask.yaml"
+			if strings.HasPrefix(tt.mask, "frequency") {
+				name = "mask.csv"
+			}
+			if err := os.WriteFile(name, []byte(tt.mask), 0o600); err != nil {
+				t.Fatal(err)
+			}
+
+			d := newFakeDevice()
+			out, err := runCli(t, d, tt.args...)
+
+			var failed *checkFailedError
+			if tt.wantFail {
+				if !errors.As(err, &failed) || failed.ExitCode() != exitCodeCheckFailed {
+					t.Fatalf("error = %v, want failed check", err)
+				}
+			} else if err != nil {
+				t.Fatal(err)
+			}
+
+			// kong resolves the capture path, so only compare the file name
+			if got := strings.ReplaceAll(out, mustGetwd(t)+string(filepath.Separator), ""); got != tt.wantOut {
+				t.Errorf("output = %q, want %q", got, tt.wantOut)
+			}
+			if got := strings.Join(d.calls, "\n"); got != strings.Join(tt.wantCalls, "\n") {
+				t.Errorf("calls = %q, want %q", d.calls, tt.wantCalls)
+			}
+		})
+	}
+}
+
+func TestCheckCmdMaskOutsideSweep(t *testing.T) {
+	t.Chdir(t.TempDir())
+	if err := os.WriteFile("mask.csv", []byte("frequency,lower\n1G,-80\n2G,-80\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := runCli(t, newFakeDevice(), "check", "--mask", "mask.csv")
+	var failed *checkFailedError
+	if err == nil || errors.As(err, &failed) {
+		t.Errorf("error = %v, want error for mask outside of the sweep", err)
+	}
+}
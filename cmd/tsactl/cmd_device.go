@@ -2,12 +2,13 @@ package main
 
 import (
 	"fmt"
+	"text/tabwriter"
 
 	"github.com/alecthomas/kong"
-	"github.com/kkettinger/go-tinysa"
 )
 
 type DeviceCmd struct {
+	List             bool  `help:"List devices on all serial ports" short:"l" group:"Device flags:"`
 	Reset            bool  `help:"Reset device" short:"r" group:"Device flags:"`
 	ResetDfu         bool  `help:"Reset device in DFU mode" group:"Device flags:"`
 	GetId            bool  `help:"Get device id" name:"id" group:"Device flags:"`
@@ -16,10 +17,22 @@ type DeviceCmd struct {
 	BatteryOffset    bool  `help:"Get battery offset voltage (mV)" name:"bat-offset" group:"Device flags:"`
 	SetBatteryOffset *uint `help:"Set battery offset voltage (mV)" name:"set-bat-offset" placeholder:"mV" group:"Device flags:"`
 	Info             bool  `help:"Get firmware and hardware version" name:"info" short:"i" group:"Device flags:"`
+
+	OutputFlag `embed:""`
+
+	// info collects the query results for structured output
+	format  string
+	info    deviceInfo
+	hasInfo bool
 }
 
 func (c *DeviceCmd) Run(globals *Globals, ctx *kong.Context) error {
-	var ops []func(*tinysa.Device) error
+	// listing probes every port instead of connecting to a single device
+	if c.List {
+		return c.ListDevices(globals)
+	}
+
+	var ops []func(Device) error
 
 	if c.Reset {
 		ops = append(ops, c.ResetDevice)
@@ -64,77 +77,129 @@ func (c *DeviceCmd) Run(globals *Globals, ctx *kong.Context) error {
 	}
 	defer d.Close()
 
+	c.format = c.outputFormat(globals)
+
 	for _, op := range ops {
 		if err := op(d); err != nil {
 			return err
 		}
 	}
 
+	if c.format != formatText && c.hasInfo {
+		return printStructured(c.format, c.info)
+	}
+
 	return nil
 }
 
-func (c *DeviceCmd) ResetDevice(d *tinysa.Device) error {
-	fmt.Println("reset device")
+func (c *DeviceCmd) ListDevices(globals *Globals) error {
+	devices, err := listDevices(deviceOptions(globals)...)
+	if err != nil {
+		return err
+	}
+
+	if format := c.outputFormat(globals); format != formatText {
+		return printStructured(format, devices)
+	}
+
+	if len(devices) == 0 {
+		_, _ = fmt.Fprintln(stdout, "no devices found")
+		return nil
+	}
+
+	w := tabwriter.NewWriter(stdout, 0, 0, 3, ' ', 0)
+	_, _ = fmt.Fprintln(w, "Port\tModel\tFirmware\tHardware\tID")
+	for _, d := range devices {
+		id := "-"
+		if d.ID != nil {
+			id = fmt.Sprint(*d.ID)
+		}
+		_, _ = fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", d.Port, d.Model, d.Firmware, d.Hardware, id)
+	}
+	return w.Flush()
+}
+
+func (c *DeviceCmd) ResetDevice(d Device) error {
+	_, _ = fmt.Fprintln(stdout, "reset device")
 	if err := d.Reset(false); err != nil {
 		return fmt.Errorf("failed to reset device: %w", err)
 	}
 	return nil
 }
 
-func (c *DeviceCmd) ResetDeviceDFU(d *tinysa.Device) error {
-	fmt.Printf("reset device in dfu mode")
+func (c *DeviceCmd) ResetDeviceDFU(d Device) error {
+	_, _ = fmt.Fprintf(stdout, "reset device in dfu mode")
 	if err := d.Reset(true); err != nil {
 		return fmt.Errorf("failed to reset device in dfu mode: %w", err)
 	}
 	return nil
 }
 
-func (c *DeviceCmd) GetDeviceId(d *tinysa.Device) error {
+func (c *DeviceCmd) GetDeviceId(d Device) error {
 	if id, err := d.GetDeviceID(); err != nil {
 		return fmt.Errorf("failed to get device id: %w", err)
 	} else {
-		fmt.Printf("Device id: %d\n", id)
+		c.setInfo(func(i *deviceInfo) { i.ID = &id })
+		if c.format == formatText {
+			_, _ = fmt.Fprintf(stdout, "Device id: %d\n", id)
+		}
 		return nil
 	}
 }
 
-func (c *DeviceCmd) SetDeviceId(d *tinysa.Device) error {
-	fmt.Println("set device id to", *c.SetId)
+func (c *DeviceCmd) SetDeviceId(d Device) error {
+	_, _ = fmt.Fprintln(stdout, "set device id to", *c.SetId)
 	if err := d.SetDeviceID(*c.SetId); err != nil {
 		return fmt.Errorf("failed to set device id to %d: %w", *c.SetId, err)
 	}
 	return nil
 }
 
-func (c *DeviceCmd) GetInfo(d *tinysa.Device) error {
-	fmt.Println("Model:", d.Model())
-	fmt.Println("Firmware version:", d.Version())
-	fmt.Println("Hardware version:", d.HardwareVersion())
+func (c *DeviceCmd) GetInfo(d Device) error {
+	c.setInfo(func(i *deviceInfo) {
+		i.Model, i.Firmware, i.Hardware = string(d.Model()), d.Version(), d.HardwareVersion()
+	})
+	if c.format == formatText {
+		_, _ = fmt.Fprintln(stdout, "Model:", d.Model())
+		_, _ = fmt.Fprintln(stdout, "Firmware version:", d.Version())
+		_, _ = fmt.Fprintln(stdout, "Hardware version:", d.HardwareVersion())
+	}
 	return nil
 }
 
-func (c *DeviceCmd) GetBattery(d *tinysa.Device) error {
+func (c *DeviceCmd) GetBattery(d Device) error {
 	if bat, err := d.GetBatteryVoltage(); err != nil {
 		return fmt.Errorf("failed to get battery voltage: %w", err)
 	} else {
-		fmt.Printf("Battery voltage: %d mV\n", bat)
+		c.setInfo(func(i *deviceInfo) { i.Battery = &bat })
+		if c.format == formatText {
+			_, _ = fmt.Fprintf(stdout, "Battery voltage: %d mV\n", bat)
+		}
 	}
 	return nil
 }
 
-func (c *DeviceCmd) GetBatteryOffset(d *tinysa.Device) error {
+func (c *DeviceCmd) GetBatteryOffset(d Device) error {
 	if offset, err := d.GetBatteryOffsetVoltage(); err != nil {
 		return fmt.Errorf("failed to get battery offset voltage: %w", err)
 	} else {
-		fmt.Printf("Battery offset voltage: %d mV\n", offset)
+		c.setInfo(func(i *deviceInfo) { i.BatteryOffset = &offset })
+		if c.format == formatText {
+			_, _ = fmt.Fprintf(stdout, "Battery offset voltage: %d mV\n", offset)
+		}
 	}
 	return nil
 }
 
-func (c *DeviceCmd) SetBatteryOffsetVoltage(d *tinysa.Device) error {
-	fmt.Println("set battery offset voltage to", *c.SetBatteryOffset)
+func (c *DeviceCmd) SetBatteryOffsetVoltage(d Device) error {
+	_, _ = fmt.Fprintln(stdout, "set battery offset voltage to", *c.SetBatteryOffset)
 	if err := d.SetBatteryOffsetVoltage(*c.SetBatteryOffset); err != nil {
 		return fmt.Errorf("failed to set set battery voltage to %d: %w", *c.SetBatteryOffset, err)
 	}
 	return nil
 }
+
+func (c *DeviceCmd) setInfo(set func(i *deviceInfo)) {
+	set(&c.info)
+	c.hasInfo = true
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"errors"
+	"maps"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/kkettinger/go-tinysa"
+)
+
+func TestDeviceCmd(t *testing.T) {
+	runCliTests(t, []cliTest{
+		{
+			name:      "info",
+			args:      []string{"device", "--info"},
+			wantOut:   "Model: tinySA4\nFirmware version: 1.4-197-gaa78ccc\nHardware version: 0.4.5.1\n",
+			wantCalls: []string{"Close()"},
+		},
+		{
+			name:      "id and battery",
+			args:      []string{"device", "--id", "--bat", "--bat-offset"},
+			setup:     func(d *fakeDevice) { d.deviceID, d.batteryOffset = 3, 120 },
+			wantOut:   "Device id: 3\nBattery voltage: 4100 mV\nBattery offset voltage: 120 mV\n",
+			wantCalls: []string{"GetDeviceID()", "GetBatteryVoltage()", "GetBatteryOffsetVoltage()", "Close()"},
+		},
+		{
+			name: "info and battery json",
+			args: []string{"--format", "json", "device", "--info", "--bat"},
+			wantOut: `{
+  "model": "tinySA4",
+  "firmware": "1.4-197-gaa78ccc",
+  "hardware": "0.4.5.1",
+  "battery_mv": 4100
+}
+`,
+			wantCalls: []string{"GetBatteryVoltage()", "Close()"},
+		},
+		{
+			name:      "id yaml",
+			args:      []string{"--format", "yaml", "device", "--id"},
+			wantOut:   "id: 0\n",
+			wantCalls: []string{"GetDeviceID()", "Close()"},
+		},
+		{
+			name:      "set id and battery offset",
+			args:      []string{"device", "--set-id", "2", "--set-bat-offset", "100"},
+			wantOut:   "set device id to 2\nset battery offset voltage to 100\n",
+			wantCalls: []string{"SetDeviceID(2)", "SetBatteryOffsetVoltage(100)", "Close()"},
+		},
+		{
+			name:      "reset",
+			args:      []string{"device", "-r"},
+			wantOut:   "reset device\n",
+			wantCalls: []string{"Reset(false)", "Close()"},
+		},
+	})
+}
+
+// stubPorts replaces the serial ports with the given devices, nil simulating a port without device.
+func stubPorts(t *testing.T, ports map[string]*fakeDevice) {
+	t.Helper()
+
+	origList, origOpen := listPorts, openPort
+	t.Cleanup(func() { listPorts, openPort = origList, origOpen })
+
+	listPorts = func() ([]string, error) {
+		names := slices.Sorted(maps.Keys(ports))
+		return names, nil
+	}
+	openPort = func(port string, _ ...tinysa.DeviceOption) (Device, error) {
+		if d := ports[port]; d != nil {
+			return d, nil
+		}
+		return nil, errors.New("no device")
+	}
+}
+
+func TestDeviceCmdList(t *testing.T) {
+	basic := newFakeDevice()
+	basic.model, basic.version, basic.hwVersion, basic.deviceID = tinysa.ModelBasic, "1.4-143", "0.4.2", 1
+	ultra := newFakeDevice()
+	ultra.deviceID = 2
+	ultra.errs = map[string]error{"GetDeviceID": errors.New("timeout")}
+
+	stubPorts(t, map[string]*fakeDevice{
+		"/dev/ttyACM0": basic,
+		"/dev/ttyACM1": nil,
+		"/dev/ttyACM2": ultra,
+	})
+
+	out, err := runCli(t, nil, "device", "--list")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "Port           Model     Firmware           Hardware   ID\n" +
+		"/dev/ttyACM0   tinySA    1.4-143            0.4.2      1\n" +
+		"/dev/ttyACM2   tinySA4   1.4-197-gaa78ccc   0.4.5.1    -\n"
+	if out != want {
+		t.Errorf("output:\n%s\nwant:\n%s", out, want)
+	}
+	for _, d := range []*fakeDevice{basic, ultra} {
+		if got := strings.Join(d.calls, ","); got != "GetDeviceID(),Close()" {
+			t.Errorf("calls = %s", got)
+		}
+	}
+
+	out, err = runCli(t, nil, "-F", "json", "device", "-l")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out, `"port": "/dev/ttyACM0"`) || !strings.Contains(out, `"id": null`) {
+		t.Errorf("json output:\n%s", out)
+	}
+}
+
+func TestDeviceID(t *testing.T) {
+	first, second := newFakeDevice(), newFakeDevice()
+	first.deviceID, second.deviceID = 1, 2
+	stubPorts(t, map[string]*fakeDevice{"/dev/ttyACM0": first, "/dev/ttyACM1": second})
+
+	out, err := runCli(t, nil, "--device-id", "2", "sweep", "-p")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out != "pause sweep\n" {
+		t.Errorf("output = %q", out)
+	}
+	if got := strings.Join(first.calls, ","); got != "GetDeviceID(),Close()" {
+		t.Errorf("first calls = %s", got)
+	}
+	if got := strings.Join(second.calls, ","); got != "GetDeviceID(),PauseSweep(),Close()" {
+		t.Errorf("second calls = %s", got)
+	}
+
+	if _, err := runCli(t, nil, "--device-id", "3", "sweep", "-p"); err == nil {
+		t.Error("expected error for unknown device id")
+	}
+
+	// the device id is searched instead of opening the device port of the profile
+	config, err := loadConfig(writeTestConfig(t, testConfig+"default_profile: bench1\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	first.calls, second.calls = nil, nil
+	if _, err := runCliConfig(t, nil, config, "--device-id", "2", "sweep", "-p"); err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(first.calls, ","); got != "GetDeviceID(),Close()" {
+		t.Errorf("first calls with profile = %s", got)
+	}
+	if got := strings.Join(second.calls, ","); got != "GetDeviceID(),PauseSweep(),Close()" {
+		t.Errorf("second calls with profile = %s", got)
+	}
+}
@@ -0,0 +1,182 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"math"
+	"os"
+	"slices"
+	"strconv"
+
+	"github.com/kkettinger/go-tinysa"
+	"github.com/kkettinger/tsactl/internal/util"
+)
+
+type DiffCmd struct {
+	Trace     uint   `help:"Live trace to compare" short:"t" default:"1" group:"Diff flags:"`
+	Reference string `help:"Reference CSV file written by the save command" short:"r" required:"" type:"existingfile" group:"Diff flags:" placeholder:"PATH"`
+	Output    string `help:"Save the difference trace as CSV to this path, supports <date> and <time>" short:"o" type:"path" group:"Diff flags:" placeholder:"PATH"`
+}
+
+type diffPoint struct {
+	Trace      uint
+	Point      uint
+	Frequency  uint64
+	Value      float64
+	Reference  float64
+	Difference float64
+}
+
+type diffInfo struct {
+	Points        int     `json:"points" yaml:"points"`
+	Resampled     bool    `json:"resampled" yaml:"resampled"`
+	MaxDeviation  float64 `json:"max_deviation" yaml:"max_deviation"`
+	MaxFrequency  uint64  `json:"max_frequency" yaml:"max_frequency"`
+	RMSDeviation  float64 `json:"rms_deviation" yaml:"rms_deviation"`
+	MeanDeviation float64 `json:"mean_deviation" yaml:"mean_deviation"`
+	Output        string  `json:"output,omitempty" yaml:"output,omitempty"`
+}
+
+func (c *DiffCmd) Run(globals *Globals) error {
+	series, err := readTraceCSV(c.Reference)
+	if err != nil {
+		return err
+	}
+	ref, err := c.selectReference(series)
+	if err != nil {
+		return err
+	}
+
+	d, err := initDevice(globals)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+
+	trace, err := d.GetTrace(c.Trace)
+	if err != nil {
+		return fmt.Errorf("failed to get trace: %w", err)
+	}
+	data, err := d.GetTraceData(c.Trace)
+	if err != nil {
+		return fmt.Errorf("failed to get trace data: %w", err)
+	}
+
+	points, info := diffTrace(data, ref)
+	if info.Points == 0 {
+		return fmt.Errorf("reference '%s' doesn't overlap with the sweep of trace %d", c.Reference, c.Trace)
+	}
+
+	if c.Output != "" {
+		info.Output = replaceFilenamePlaceholdersDateTime(c.Output)
+		if err := writeDiffCSV(info.Output, points); err != nil {
+			return err
+		}
+	}
+
+	switch globals.Format {
+	case formatText:
+		unit := deltaUnit(trace.Unit.String())
+		resampled := ""
+		if info.Resampled {
+			resampled = ", resampled reference"
+		}
+		_, _ = fmt.Fprintf(stdout, "Compared %d points of trace %d with %s%s\n", info.Points, c.Trace, c.Reference, resampled)
+		_, _ = fmt.Fprintf(stdout, "Max deviation: %+.2f %s at %s\n", info.MaxDeviation, unit, util.FormatFrequency(info.MaxFrequency))
+		_, _ = fmt.Fprintf(stdout, "RMS deviation: %.2f %s\n", info.RMSDeviation, unit)
+		_, _ = fmt.Fprintf(stdout, "Mean deviation: %+.2f %s\n", info.MeanDeviation, unit)
+		if info.Output != "" {
+			_, _ = fmt.Fprintf(stdout, "difference saved to %s\n", info.Output)
+		}
+		return nil
+	case formatCSV:
+		return printCSV(diffCSVHeader, diffCSVRows(points))
+	default:
+		return printStructured(globals.Format, info)
+	}
+}
+
+// selectReference returns the trace of the reference file with the same id as the live trace,
+// or its only trace.
+func (c *DiffCmd) selectReference(series []traceSeries) (traceSeries, error) {
+	ids := make([]uint, len(series))
+	for i, s := range series {
+		if s.trace == c.Trace {
+			return s, nil
+		}
+		ids[i] = s.trace
+	}
+	if len(series) == 1 {
+		return series[0], nil
+	}
+	return traceSeries{}, fmt.Errorf("reference '%s' contains the traces %v, but not trace %d", c.Reference, ids, c.Trace)
+}
+
+// diffTrace subtracts the reference from the live trace. If the sweep points differ, the reference
+// is interpolated onto the frequencies of the live trace and points outside of the reference are skipped.
+func diffTrace(data []tinysa.TraceData, ref traceSeries) ([]diffPoint, diffInfo) {
+	info := diffInfo{Resampled: len(data) != len(ref.frequencies)}
+	points := make([]diffPoint, 0, len(data))
+
+	var sum, sumSquares float64
+	for i, dp := range data {
+		if !info.Resampled && dp.Frequency != ref.frequencies[i] {
+			info.Resampled = true
+		}
+		refValue, ok := ref.valueAt(dp.Frequency)
+		if !ok {
+			continue
+		}
+
+		diff := dp.Value - refValue
+		// remove the float noise of the interpolation
+		diff, refValue = math.Round(diff*1e6)/1e6, math.Round(refValue*1e6)/1e6
+		points = append(points, diffPoint{Trace: dp.Trace, Point: dp.Point, Frequency: dp.Frequency, Value: dp.Value, Reference: refValue, Difference: diff})
+
+		if len(points) == 1 || math.Abs(diff) > math.Abs(info.MaxDeviation) {
+			info.MaxDeviation, info.MaxFrequency = diff, dp.Frequency
+		}
+		sum += diff
+		sumSquares += diff * diff
+	}
+
+	info.Points = len(points)
+	if info.Points > 0 {
+		info.MeanDeviation = sum / float64(info.Points)
+		info.RMSDeviation = math.Sqrt(sumSquares / float64(info.Points))
+	}
+	return points, info
+}
+
+// diffCSVHeader extends the single trace layout of the save command, so the live trace of a difference file
+// can be read again, e.g. by plot or as reference of another diff.
+var diffCSVHeader = []string{"trace", "point", "frequency", "value", "reference", "difference"}
+
+func diffCSVRows(points []diffPoint) [][]string {
+	rows := make([][]string, len(points))
+	for i, p := range points {
+		rows[i] = []string{
+			strconv.FormatUint(uint64(p.Trace), 10),
+			strconv.FormatUint(uint64(p.Point), 10),
+			strconv.FormatUint(p.Frequency, 10),
+			strconv.FormatFloat(p.Value, 'f', -1, 64),
+			strconv.FormatFloat(p.Reference, 'f', -1, 64),
+			strconv.FormatFloat(p.Difference, 'f', -1, 64),
+		}
+	}
+	return rows
+}
+
+func writeDiffCSV(path string, points []diffPoint) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to open file '%s': %w", path, err)
+	}
+	defer file.Close()
+
+	w := csv.NewWriter(file)
+	if err := w.WriteAll(slices.Concat([][]string{diffCSVHeader}, diffCSVRows(points))); err != nil {
+		return fmt.Errorf("failed to write file '%s': %w", path, err)
+	}
+	return nil
+}
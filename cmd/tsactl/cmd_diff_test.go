@@ -0,0 +1,156 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestDiffCmd(t *testing.T) {
+	const (
+		sameGrid = "trace,point,frequency,value\n1,0,400000000,-90\n1,1,450000000,-42\n1,2,500000000,-89.75\n"
+		// trace 1 is interpolated to -90 at 450 MHz
+		coarseGrid = "point,frequency,value_t1,value_t2\n0,400000000,-90,-85\n1,500000000,-90,-85\n"
+	)
+
+	tests := []struct {
+		name      string
+		reference string
+		args      []string
+		wantOut   string
+		wantCalls []string
+		wantFile  string
+		wantErr   bool
+	}{
+		{
+			name:      "same grid",
+			reference: sameGrid,
+			args:      []string{"diff", "--reference", "golden.csv"},
+			wantOut: "Compared 3 points of trace 1 with golden.csv\n" +
+				"Max deviation: +1.50 dB at 450 MHz\n" +
+				"RMS deviation: 0.88 dB\n" +
+				"Mean deviation: +0.42 dB\n",
+			wantCalls: []string{"GetTrace(1)", "GetTraceData(1)", "Close()"},
+		},
+		{
+			name:      "resampled",
+			reference: coarseGrid,
+			args:      []string{"diff", "--reference", "golden.csv", "-o", "diff.csv"},
+			wantOut: "Compared 3 points of trace 1 with golden.csv, resampled reference\n" +
+				"Max deviation: +49.50 dB at 450 MHz\n" +
+				"RMS deviation: 28.58 dB\n" +
+				"Mean deviation: +16.50 dB\n" +
+				"difference saved to diff.csv\n",
+			wantCalls: []string{"GetTrace(1)", "GetTraceData(1)", "Close()"},
+			wantFile: "trace,point,frequency,value,reference,difference\n" +
+				"1,0,400000000,-90.25,-90,-0.25\n" +
+				"1,1,450000000,-40.5,-90,49.5\n" +
+				"1,2,500000000,-89.75,-90,0.25\n",
+		},
+		{
+			name:      "csv of matching trace",
+			reference: coarseGrid,
+			args:      []string{"-F", "csv", "diff", "--reference", "golden.csv", "--trace", "2"},
+			wantOut: "trace,point,frequency,value,reference,difference\n" +
+				"2,0,400000000,-84.78,-85,0.22\n" +
+				"2,1,450000000,-35,-85,50\n" +
+				"2,2,500000000,-85.75,-85,-0.75\n",
+			wantCalls: []string{"GetTrace(2)", "GetTraceData(2)", "Close()"},
+		},
+		{
+			name:      "json",
+			reference: sameGrid,
+			args:      []string{"-F", "json", "diff", "--reference", "golden.csv"},
+			wantOut: `{
+  "points": 3,
+  "resampled": false,
+  "max_deviation": 1.5,
+  "max_frequency": 450000000,
+  "rms_deviation": 0.8779711460710616,
+  "mean_deviation": 0.4166666666666667
+}
+`,
+			wantCalls: []string{"GetTrace(1)", "GetTraceData(1)", "Close()"},
+		},
+		{
+			name:      "trace missing in reference",
+			reference: coarseGrid,
+			args:      []string{"diff", "--reference", "golden.csv", "--trace", "3"},
+			wantErr:   true,
+		},
+		{
+			name:      "reference outside of sweep",
+			reference: "trace,point,frequency,value\n1,0,100000000,-90\n1,1,200000000,-90\n",
+			args:      []string{"diff", "--reference", "golden.csv"},
+			wantCalls: []string{"GetTrace(1)", "GetTraceData(1)", "Close()"},
+			wantErr:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Chdir(t.TempDir())
+			if err := os.WriteFile("golden.csv", []byte(tt.reference), 0o600); err != nil {
+				t.Fatal(err)
+			}
+
+			d := newFakeDevice()
+			out, err := runCli(t, d, tt.args...)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			// kong resolves the paths, so only compare the file names
+			if got := strings.ReplaceAll(out, mustGetwd(t)+string(filepath.Separator), ""); got != tt.wantOut {
+				t.Errorf("output = %q, want %q", got, tt.wantOut)
+			}
+			if got := strings.Join(d.calls, "\n"); got != strings.Join(tt.wantCalls, "\n") {
+				t.Errorf("calls = %q, want %q", d.calls, tt.wantCalls)
+			}
+
+			if tt.wantFile != "" {
+				data, err := os.ReadFile("diff.csv")
+				if err != nil {
+					t.Fatal(err)
+				}
+				if string(data) != tt.wantFile {
+					t.Errorf("file:\n%s\nwant:\n%s", data, tt.wantFile)
+				}
+			}
+		})
+	}
+}
+
+func TestDiffCmdRoundTrip(t *testing.T) {
+	t.Chdir(t.TempDir())
+	if err := os.WriteFile("golden.csv", []byte("point,frequency,value_t1\n0,400000000,-90\n1,500000000,-90\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := runCli(t, newFakeDevice(), "diff", "-r", "golden.csv", "-o", "diff.csv"); err != nil {
+		t.Fatal(err)
+	}
+
+	// the difference file is readable as trace CSV, as reference of another diff and by plot
+	series, err := readTraceCSV("diff.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(series) != 1 || series[0].trace != 1 || !slices.Equal(series[0].values, []float64{-90.25, -40.5, -89.75}) {
+		t.Errorf("series = %+v", series)
+	}
+
+	out, err := runCli(t, newFakeDevice(), "diff", "-r", "diff.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out, "Max deviation: +0.00 dB") {
+		t.Errorf("output = %q", out)
+	}
+
+	if _, err := runCli(t, nil, "plot", "diff.csv", "-o", "diff.svg"); err != nil {
+		t.Fatal(err)
+	}
+}
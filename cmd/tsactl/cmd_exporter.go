@@ -0,0 +1,263 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kkettinger/go-tinysa"
+)
+
+type ExporterCmd struct {
+	Listen   string          `help:"Address to listen on" short:"l" default:":9120" placeholder:"ADDR" group:"Exporter flags:"`
+	Interval time.Duration   `help:"Polling interval" short:"i" default:"10s" group:"Exporter flags:"`
+	Band     []FrequencyBand `help:"Export the peak of this band, e.g. wifi=2.4G:2.5G (repeatable)" short:"b" placeholder:"[NAME=]START:STOP" group:"Exporter flags:"`
+	Trace    uint            `help:"Trace to find the band peaks in" short:"t" default:"1" group:"Exporter flags:"`
+}
+
+func (c *ExporterCmd) Run(globals *Globals) error {
+	if c.Interval <= 0 {
+		return fmt.Errorf("interval must be greater than zero")
+	}
+
+	d, err := initDevice(globals)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+
+	exp := newExporter(d, c.Trace, c.Band)
+
+	// stop polling before the device is closed
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	defer func() {
+		cancel()
+		<-done
+	}()
+
+	go func() {
+		defer close(done)
+		ticker := time.NewTicker(c.Interval)
+		defer ticker.Stop()
+		for {
+			if err := exp.poll(); err != nil {
+				_, _ = fmt.Fprintf(stdout, "failed to poll device: %v\n", err)
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+
+	return listenAndServe(c.Listen, exp.handler())
+}
+
+// exporter polls the device and provides the results as Prometheus metrics.
+type exporter struct {
+	d      Device
+	trace  uint
+	bands  []FrequencyBand
+	labels []metricLabel // model and device id, added to every sample
+
+	mu      sync.Mutex
+	metrics []metric // of the last poll
+	errors  uint64
+}
+
+func newExporter(d Device, trace uint, bands []FrequencyBand) *exporter {
+	// the device id is only available on newer firmwares
+	deviceID := ""
+	if id, err := d.GetDeviceID(); err == nil {
+		deviceID = strconv.FormatUint(uint64(id), 10)
+	}
+
+	return &exporter{
+		d:     d,
+		trace: trace,
+		bands: bands,
+		labels: []metricLabel{
+			{"model", string(d.Model())},
+			{"device_id", deviceID},
+		},
+	}
+}
+
+// poll reads the device and replaces the metrics. On errors, only tinysa_up is reported as 0.
+func (e *exporter) poll() error {
+	metrics, err := e.collect()
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	up := 1.0
+	if err != nil {
+		up = 0
+		e.errors++
+		metrics = nil
+	}
+
+	e.metrics = append([]metric{
+		{name: "tinysa_up", help: "Whether the last poll of the device succeeded.", typ: "gauge",
+			samples: []metricSample{e.sample(up)}},
+		{name: "tinysa_poll_errors_total", help: "Number of failed polls.", typ: "counter",
+			samples: []metricSample{e.sample(float64(e.errors))}},
+	}, metrics...)
+
+	return err
+}
+
+func (e *exporter) collect() ([]metric, error) {
+	voltage, err := e.d.GetBatteryVoltage()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get battery voltage: %w", err)
+	}
+
+	sweep, err := e.d.GetSweep()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get sweep: %w", err)
+	}
+
+	markers, err := e.d.GetMarkerAll()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get markers: %w", err)
+	}
+
+	metrics := []metric{
+		{name: "tinysa_info", help: "Firmware and hardware version of the device.", typ: "gauge",
+			samples: []metricSample{e.sample(1,
+				metricLabel{"firmware", e.d.Version()}, metricLabel{"hardware", e.d.HardwareVersion()})}},
+		{name: "tinysa_battery_voltage_volts", help: "Battery voltage.", typ: "gauge",
+			samples: []metricSample{e.sample(float64(voltage) / 1000)}},
+		{name: "tinysa_sweep_start_hz", help: "Sweep start frequency.", typ: "gauge",
+			samples: []metricSample{e.sample(float64(sweep.Start))}},
+		{name: "tinysa_sweep_stop_hz", help: "Sweep stop frequency.", typ: "gauge",
+			samples: []metricSample{e.sample(float64(sweep.Stop))}},
+		{name: "tinysa_sweep_points", help: "Number of sweep points.", typ: "gauge",
+			samples: []metricSample{e.sample(float64(sweep.Points))}},
+	}
+
+	traces, err := e.d.GetTraceAll()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get traces: %w", err)
+	}
+
+	markerFreq := metric{name: "tinysa_marker_frequency_hz", help: "Marker frequency.", typ: "gauge"}
+	markerLevel := metric{name: "tinysa_marker_level", help: "Marker level in the unit of its trace.", typ: "gauge"}
+	for _, m := range markers {
+		labels := []metricLabel{{"marker", strconv.FormatUint(uint64(m.Marker), 10)}}
+		// the device doesn't report the trace of a marker, it is only known with a single enabled trace
+		if len(traces) == 1 {
+			labels = append(labels, metricLabel{"trace", strconv.FormatUint(uint64(traces[0].Trace), 10)})
+		}
+		markerFreq.samples = append(markerFreq.samples, e.sample(float64(m.Frequency), labels...))
+		markerLevel.samples = append(markerLevel.samples, e.sample(m.Value, labels...))
+	}
+	metrics = append(metrics, markerFreq, markerLevel)
+
+	if len(e.bands) == 0 {
+		return metrics, nil
+	}
+
+	data, err := e.d.GetTraceData(e.trace)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get trace data: %w", err)
+	}
+
+	bandFreq := metric{name: "tinysa_band_peak_frequency_hz", help: "Frequency of the band peak.", typ: "gauge"}
+	bandLevel := metric{name: "tinysa_band_peak_level", help: "Level of the band peak in the unit of its trace.", typ: "gauge"}
+	for _, band := range e.bands {
+		peak, ok := bandPeak(data, band)
+		if !ok {
+			// the band is outside the current sweep
+			continue
+		}
+		labels := []metricLabel{{"trace", strconv.FormatUint(uint64(e.trace), 10)}, {"band", band.String()}}
+		bandFreq.samples = append(bandFreq.samples, e.sample(float64(peak.Frequency), labels...))
+		bandLevel.samples = append(bandLevel.samples, e.sample(peak.Value, labels...))
+	}
+	metrics = append(metrics, bandFreq, bandLevel)
+
+	return metrics, nil
+}
+
+func (e *exporter) sample(value float64, labels ...metricLabel) metricSample {
+	return metricSample{labels: append(append([]metricLabel{}, e.labels...), labels...), value: value}
+}
+
+// bandPeak returns the data point with the highest value within band.
+func bandPeak(data []tinysa.TraceData, band FrequencyBand) (tinysa.TraceData, bool) {
+	var (
+		peak  tinysa.TraceData
+		found bool
+	)
+	for _, dp := range data {
+		if dp.Frequency < band.Start || dp.Frequency > band.Stop {
+			continue
+		}
+		if !found || dp.Value > peak.Value {
+			peak, found = dp, true
+		}
+	}
+	return peak, found
+}
+
+func (e *exporter) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /metrics", func(w http.ResponseWriter, r *http.Request) {
+		var buf bytes.Buffer
+
+		e.mu.Lock()
+		writeMetrics(&buf, e.metrics)
+		e.mu.Unlock()
+
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		_, _ = w.Write(buf.Bytes())
+	})
+	return mux
+}
+
+type metricLabel struct {
+	name  string
+	value string
+}
+
+type metricSample struct {
+	labels []metricLabel
+	value  float64
+}
+
+// metric is a metric family in the Prometheus text format.
+type metric struct {
+	name    string
+	help    string
+	typ     string
+	samples []metricSample
+}
+
+var labelValueReplacer = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func writeMetrics(w io.Writer, metrics []metric) {
+	for _, m := range metrics {
+		if len(m.samples) == 0 {
+			continue
+		}
+		_, _ = fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", m.name, m.help, m.name, m.typ)
+		for _, s := range m.samples {
+			labels := make([]string, len(s.labels))
+			for i, l := range s.labels {
+				labels[i] = fmt.Sprintf(`%s="%s"`, l.name, labelValueReplacer.Replace(l.value))
+			}
+			_, _ = fmt.Fprintf(w, "%s{%s} %s\n",
+				m.name, strings.Join(labels, ","), strconv.FormatFloat(s.value, 'f', -1, 64))
+		}
+	}
+}
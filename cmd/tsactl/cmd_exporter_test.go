@@ -0,0 +1,190 @@
+package main
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/kkettinger/go-tinysa"
+)
+
+func TestExporter(t *testing.T) {
+	tests := []struct {
+		name      string
+		bands     []FrequencyBand
+		setup     func(d *fakeDevice)
+		want      string
+		wantCalls []string
+	}{
+		{
+			name: "metrics",
+			bands: []FrequencyBand{
+				{Name: "ism", Start: 430_000_000, Stop: 440_000_000}, // outside of the trace points
+				{Start: 440_000_000, Stop: 460_000_000},
+			},
+			setup: func(d *fakeDevice) { d.deviceID = 3 },
+			want: `# HELP tinysa_up Whether the last poll of the device succeeded.
+# TYPE tinysa_up gauge
+tinysa_up{model="tinySA4",device_id="3"} 1
+# HELP tinysa_poll_errors_total Number of failed polls.
+# TYPE tinysa_poll_errors_total counter
+tinysa_poll_errors_total{model="tinySA4",device_id="3"} 0
+# HELP tinysa_info Firmware and hardware version of the device.
+# TYPE tinysa_info gauge
+tinysa_info{model="tinySA4",device_id="3",firmware="1.4-197-gaa78ccc",hardware="0.4.5.1"} 1
+# HELP tinysa_battery_voltage_volts Battery voltage.
+# TYPE tinysa_battery_voltage_volts gauge
+tinysa_battery_voltage_volts{model="tinySA4",device_id="3"} 4.1
+# HELP tinysa_sweep_start_hz Sweep start frequency.
+# TYPE tinysa_sweep_start_hz gauge
+tinysa_sweep_start_hz{model="tinySA4",device_id="3"} 400000000
+# HELP tinysa_sweep_stop_hz Sweep stop frequency.
+# TYPE tinysa_sweep_stop_hz gauge
+tinysa_sweep_stop_hz{model="tinySA4",device_id="3"} 500000000
+# HELP tinysa_sweep_points Number of sweep points.
+# TYPE tinysa_sweep_points gauge
+tinysa_sweep_points{model="tinySA4",device_id="3"} 450
+# HELP tinysa_marker_frequency_hz Marker frequency.
+# TYPE tinysa_marker_frequency_hz gauge
+tinysa_marker_frequency_hz{model="tinySA4",device_id="3",marker="1",trace="1"} 410000000
+tinysa_marker_frequency_hz{model="tinySA4",device_id="3",marker="2",trace="1"} 447700000
+# HELP tinysa_marker_level Marker level in the unit of its trace.
+# TYPE tinysa_marker_level gauge
+tinysa_marker_level{model="tinySA4",device_id="3",marker="1",trace="1"} -89.4
+tinysa_marker_level{model="tinySA4",device_id="3",marker="2",trace="1"} -67.9
+# HELP tinysa_band_peak_frequency_hz Frequency of the band peak.
+# TYPE tinysa_band_peak_frequency_hz gauge
+tinysa_band_peak_frequency_hz{model="tinySA4",device_id="3",trace="1",band="440 MHz:460 MHz"} 450000000
+# HELP tinysa_band_peak_level Level of the band peak in the unit of its trace.
+# TYPE tinysa_band_peak_level gauge
+tinysa_band_peak_level{model="tinySA4",device_id="3",trace="1",band="440 MHz:460 MHz"} -40.5
+`,
+			wantCalls: []string{"GetDeviceID()", "GetBatteryVoltage()", "GetSweep()", "GetMarkerAll()", "GetTraceAll()", "GetTraceData(1)"},
+		},
+		{
+			name: "no marker trace with several traces",
+			setup: func(d *fakeDevice) {
+				d.markers = []tinysa.Marker{
+					{Marker: 1, Index: 1, Frequency: 450_000_000, Value: -40.5},
+					{Marker: 2, Index: 1, Frequency: 450_000_000, Value: -35.1},
+					{Marker: 3, Index: 5, Frequency: 450_000_000, Value: -35},
+				}
+				d.traces = append(d.traces, tinysa.Trace{Trace: 2, Unit: tinysa.TraceUnitDBm})
+			},
+			want: `# HELP tinysa_up Whether the last poll of the device succeeded.
+# TYPE tinysa_up gauge
+tinysa_up{model="tinySA4",device_id="0"} 1
+# HELP tinysa_poll_errors_total Number of failed polls.
+# TYPE tinysa_poll_errors_total counter
+tinysa_poll_errors_total{model="tinySA4",device_id="0"} 0
+# HELP tinysa_info Firmware and hardware version of the device.
+# TYPE tinysa_info gauge
+tinysa_info{model="tinySA4",device_id="0",firmware="1.4-197-gaa78ccc",hardware="0.4.5.1"} 1
+# HELP tinysa_battery_voltage_volts Battery voltage.
+# TYPE tinysa_battery_voltage_volts gauge
+tinysa_battery_voltage_volts{model="tinySA4",device_id="0"} 4.1
+# HELP tinysa_sweep_start_hz Sweep start frequency.
+# TYPE tinysa_sweep_start_hz gauge
+tinysa_sweep_start_hz{model="tinySA4",device_id="0"} 400000000
+# HELP tinysa_sweep_stop_hz Sweep stop frequency.
+# TYPE tinysa_sweep_stop_hz gauge
+tinysa_sweep_stop_hz{model="tinySA4",device_id="0"} 500000000
+# HELP tinysa_sweep_points Number of sweep points.
+# TYPE tinysa_sweep_points gauge
+tinysa_sweep_points{model="tinySA4",device_id="0"} 450
+# HELP tinysa_marker_frequency_hz Marker frequency.
+# TYPE tinysa_marker_frequency_hz gauge
+tinysa_marker_frequency_hz{model="tinySA4",device_id="0",marker="1"} 450000000
+tinysa_marker_frequency_hz{model="tinySA4",device_id="0",marker="2"} 450000000
+tinysa_marker_frequency_hz{model="tinySA4",device_id="0",marker="3"} 450000000
+# HELP tinysa_marker_level Marker level in the unit of its trace.
+# TYPE tinysa_marker_level gauge
+tinysa_marker_level{model="tinySA4",device_id="0",marker="1"} -40.5
+tinysa_marker_level{model="tinySA4",device_id="0",marker="2"} -35.1
+tinysa_marker_level{model="tinySA4",device_id="0",marker="3"} -35
+`,
+			wantCalls: []string{"GetDeviceID()", "GetBatteryVoltage()", "GetSweep()", "GetMarkerAll()", "GetTraceAll()"},
+		},
+		{
+			name: "device error",
+			setup: func(d *fakeDevice) {
+				d.errs = map[string]error{"GetDeviceID": errors.New("unknown"), "GetSweep": errors.New("timeout")}
+			},
+			want: `# HELP tinysa_up Whether the last poll of the device succeeded.
+# TYPE tinysa_up gauge
+tinysa_up{model="tinySA4",device_id=""} 0
+# HELP tinysa_poll_errors_total Number of failed polls.
+# TYPE tinysa_poll_errors_total counter
+tinysa_poll_errors_total{model="tinySA4",device_id=""} 1
+`,
+			wantCalls: []string{"GetDeviceID()", "GetBatteryVoltage()", "GetSweep()"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := newFakeDevice()
+			if tt.setup != nil {
+				tt.setup(d)
+			}
+
+			exp := newExporter(d, 1, tt.bands)
+			_ = exp.poll()
+
+			srv := httptest.NewServer(exp.handler())
+			defer srv.Close()
+
+			resp, err := http.Get(srv.URL + "/metrics")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer resp.Body.Close()
+			body, _ := io.ReadAll(resp.Body)
+
+			if ct := resp.Header.Get("Content-Type"); !strings.HasPrefix(ct, "text/plain; version=0.0.4") {
+				t.Errorf("content type = %s", ct)
+			}
+			if string(body) != tt.want {
+				t.Errorf("body:\n%s\nwant:\n%s", body, tt.want)
+			}
+			if got := strings.Join(d.calls, "\n"); got != strings.Join(tt.wantCalls, "\n") {
+				t.Errorf("calls:\n%s\nwant:\n%s", got, strings.Join(tt.wantCalls, "\n"))
+			}
+		})
+	}
+}
+
+func TestParseFrequencyBand(t *testing.T) {
+	tests := []struct {
+		val     string
+		want    FrequencyBand
+		wantErr string
+	}{
+		{"2.4G:2.5G", FrequencyBand{Start: 2_400_000_000, Stop: 2_500_000_000}, ""},
+		{"ism=433.05M:434.79M", FrequencyBand{Name: "ism", Start: 433_050_000, Stop: 434_790_000}, ""},
+		{"433M", FrequencyBand{}, "invalid band '433M', must be START:STOP"},
+		{"434M:433M", FrequencyBand{}, "band start must be lower than stop"},
+		{"x:433M", FrequencyBand{}, "failed to parse band start: invalid frequency format: x"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.val, func(t *testing.T) {
+			got, err := parseFrequencyBand(tt.val)
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Fatalf("error = %v, want %s", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("band = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
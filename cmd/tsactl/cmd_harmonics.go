@@ -0,0 +1,110 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"os"
+	"os/signal"
+	"syscall"
+	"text/tabwriter"
+	"time"
+
+	"github.com/kkettinger/tsactl/internal/util"
+)
+
+type HarmonicsCmd struct {
+	Fundamental Frequency     `help:"Frequency of the fundamental" short:"f" required:"" group:"Harmonics flags:" placeholder:"FREQ"`
+	Count       uint          `help:"Highest harmonic to measure, the fundamental is the first" short:"n" default:"5" group:"Harmonics flags:"`
+	Span        Frequency     `help:"Span around each harmonic to search the peak in" short:"s" default:"1M" group:"Harmonics flags:" placeholder:"FREQ"`
+	Trace       uint          `help:"Trace to read" short:"t" default:"1" group:"Harmonics flags:"`
+	Dwell       time.Duration `help:"Time to wait after retuning before reading a harmonic, should be at least one sweep time" default:"500ms" group:"Harmonics flags:"`
+}
+
+type harmonicInfo struct {
+	Harmonic      uint    `json:"harmonic" yaml:"harmonic"`
+	Frequency     uint64  `json:"frequency" yaml:"frequency"`
+	PeakFrequency uint64  `json:"peak_frequency" yaml:"peak_frequency"`
+	Level         float64 `json:"level_dbm" yaml:"level_dbm"`
+	DBc           float64 `json:"dbc" yaml:"dbc"`
+}
+
+func (c *HarmonicsCmd) Run(globals *Globals) (err error) {
+	if c.Count == 0 {
+		return fmt.Errorf("count must be at least 1")
+	}
+	if c.Span.Value == 0 || c.Span.Value/2 >= c.Fundamental.Value {
+		return fmt.Errorf("span must be greater than zero and less than twice the fundamental")
+	}
+
+	d, err := initDevice(globals)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+
+	// harmonics above the frequency limit of the model are skipped
+	limit := deviceLimit(d.Model())
+	count := min(c.Count, uint((limit.maxFrequency-c.Span.Value/2)/c.Fundamental.Value))
+	if count == 0 {
+		return fmt.Errorf("fundamental exceeds the maximum of %s of the %s", util.FormatFrequency(limit.maxFrequency), d.Model())
+	}
+
+	trace, err := d.GetTrace(c.Trace)
+	if err != nil {
+		return fmt.Errorf("failed to get trace: %w", err)
+	}
+
+	// restore the sweep settings when done, also on errors and Ctrl-C
+	tuner, err := newSweepTuner(d)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if rerr := tuner.restore(); rerr != nil && err == nil {
+			err = rerr
+		}
+	}()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	infos := make([]harmonicInfo, 0, count)
+	for n := uint64(1); n <= uint64(count); n++ {
+		freq := n * c.Fundamental.Value
+		peak, level, err := tuner.peakAround(ctx, c.Trace, trace.Unit, freq, c.Span.Value, c.Dwell)
+		if err != nil {
+			return fmt.Errorf("failed to measure harmonic %d: %w", n, err)
+		}
+
+		info := harmonicInfo{Harmonic: uint(n), Frequency: freq, PeakFrequency: peak.Frequency, Level: level}
+		if n > 1 {
+			// remove the float noise of the subtraction
+			info.DBc = math.Round((level-infos[0].Level)*1e6) / 1e6
+		}
+		infos = append(infos, info)
+	}
+
+	if err := printHarmonics(globals.Format, infos); err != nil {
+		return err
+	}
+	if count < c.Count && globals.Format == formatText {
+		_, _ = fmt.Fprintf(stdout, "harmonics %d to %d skipped, above the maximum of %s of the %s\n",
+			count+1, c.Count, util.FormatFrequency(limit.maxFrequency), d.Model())
+	}
+	return nil
+}
+
+func printHarmonics(format string, infos []harmonicInfo) error {
+	if format != formatText {
+		return printStructured(format, infos)
+	}
+
+	w := tabwriter.NewWriter(stdout, 0, 0, 3, ' ', 0)
+	_, _ = fmt.Fprintln(w, "Harmonic\tFrequency\tPeak\tLevel\tRelative")
+	for _, info := range infos {
+		_, _ = fmt.Fprintf(w, "%d\t%s\t%s\t%.2f dBm\t%.2f dBc\n", info.Harmonic, util.FormatFrequency(info.Frequency),
+			util.FormatFrequency(info.PeakFrequency), info.Level, info.DBc)
+	}
+	return w.Flush()
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/kkettinger/go-tinysa"
+)
+
+// harmonicDevice returns a sweep with a peak at its center, 15 dB lower for every harmonic of 100 MHz.
+type harmonicDevice struct {
+	*sweepDevice
+}
+
+func (d *harmonicDevice) GetTraceData(traceID uint) ([]tinysa.TraceData, error) {
+	s := d.sweep
+	center := (s.Start + s.Stop) / 2
+	level := -20 - 15*float64(center/100_000_000-1)
+	data := []tinysa.TraceData{
+		{Trace: traceID, Point: 0, Frequency: s.Start, Value: -150},
+		{Trace: traceID, Point: 1, Frequency: center, Value: level},
+		{Trace: traceID, Point: 2, Frequency: s.Stop, Value: -150},
+	}
+	return data, d.record("GetTraceData", traceID)
+}
+
+func TestHarmonicsCmd(t *testing.T) {
+	tests := []struct {
+		name      string
+		args      []string
+		model     tinysa.Model
+		wantOut   string
+		wantCalls []string
+	}{
+		{
+			name:  "text",
+			args:  []string{"harmonics", "--fundamental", "100M", "--count", "3", "--span", "2M", "--dwell", "0"},
+			model: tinysa.ModelUltra,
+			wantOut: "Harmonic   Frequency   Peak      Level        Relative\n" +
+				"1          100 MHz     100 MHz   -20.00 dBm   0.00 dBc\n" +
+				"2          200 MHz     200 MHz   -35.00 dBm   -15.00 dBc\n" +
+				"3          300 MHz     300 MHz   -50.00 dBm   -30.00 dBc\n",
+			wantCalls: []string{
+				"GetTrace(1)", "GetSweep()",
+				"SetSweepStart(99000000)", "SetSweepStop(101000000)", "GetTraceData(1)",
+				"SetSweepStop(201000000)", "SetSweepStart(199000000)", "GetTraceData(1)",
+				"SetSweepStop(301000000)", "SetSweepStart(299000000)", "GetTraceData(1)",
+				"SetSweepStop(500000000)", "SetSweepStart(400000000)",
+				"Close()",
+			},
+		},
+		{
+			name:  "skipped above model limit",
+			args:  []string{"-F", "csv", "harmonics", "-f", "400M", "--dwell", "0"},
+			model: tinysa.ModelBasic,
+			wantOut: "harmonic,frequency,peak_frequency,level_dbm,dbc\n" +
+				"1,400000000,400000000,-65,0\n" +
+				"2,800000000,800000000,-125,-60\n",
+			wantCalls: []string{
+				"GetTrace(1)", "GetSweep()",
+				"SetSweepStart(399500000)", "SetSweepStop(400500000)", "GetTraceData(1)",
+				"SetSweepStop(800500000)", "SetSweepStart(799500000)", "GetTraceData(1)",
+				"SetSweepStart(400000000)", "SetSweepStop(500000000)",
+				"Close()",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := &harmonicDevice{&sweepDevice{fakeDevice: newFakeDevice()}}
+			d.model = tt.model
+
+			out, err := runCli(t, d, tt.args...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if out != tt.wantOut {
+				t.Errorf("output = %q, want %q", out, tt.wantOut)
+			}
+			if got := strings.Join(d.calls, "\n"); got != strings.Join(tt.wantCalls, "\n") {
+				t.Errorf("calls = %q, want %q", d.calls, tt.wantCalls)
+			}
+		})
+	}
+}
+
+func TestHarmonicsCmdSkipNote(t *testing.T) {
+	d := &harmonicDevice{&sweepDevice{fakeDevice: newFakeDevice()}}
+	d.model = tinysa.ModelBasic
+
+	out, err := runCli(t, d, "harmonics", "-f", "400M", "--dwell", "0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "harmonics 3 to 5 skipped, above the maximum of 960 MHz of the tinySA\n"; !strings.HasSuffix(out, want) {
+		t.Errorf("output = %q, want suffix %q", out, want)
+	}
+}
+
+func TestHarmonicsCmdErrors(t *testing.T) {
+	runCliTests(t, []cliTest{
+		{
+			name:    "span too wide",
+			args:    []string{"harmonics", "-f", "1M", "--span", "2M"},
+			wantErr: true,
+		},
+		{
+			name:      "fundamental above model limit",
+			args:      []string{"harmonics", "-f", "7G"},
+			wantCalls: []string{"Close()"},
+			wantErr:   true,
+		},
+	})
+}
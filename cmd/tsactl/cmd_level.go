@@ -4,7 +4,6 @@ import (
 	"fmt"
 
 	"github.com/alecthomas/kong"
-	"github.com/kkettinger/go-tinysa"
 )
 
 type LevelCmd struct {
@@ -24,7 +23,7 @@ func (c *LevelCmd) Validate() error {
 }
 
 func (c *LevelCmd) Run(globals *Globals, ctx *kong.Context) error {
-	var ops []func(*tinysa.Device) error
+	var ops []func(Device) error
 
 	if c.Unit.Valid {
 		ops = append(ops, c.SetUnit)
@@ -59,6 +58,7 @@ func (c *LevelCmd) Run(globals *Globals, ctx *kong.Context) error {
 	if err != nil {
 		return err
 	}
+	defer d.Close()
 
 	for _, op := range ops {
 		if err := op(d); err != nil {
@@ -69,48 +69,48 @@ func (c *LevelCmd) Run(globals *Globals, ctx *kong.Context) error {
 	return nil
 }
 
-func (c *LevelCmd) SetUnit(d *tinysa.Device) error {
-	fmt.Println("set trace unit to", c.Unit.Unit.String())
+func (c *LevelCmd) SetUnit(d Device) error {
+	_, _ = fmt.Fprintln(stdout, "set trace unit to", c.Unit.Unit.String())
 	if err := d.SetTraceUnit(c.Unit.Unit); err != nil {
 		return fmt.Errorf("failed to set trace unit to %s: %w", c.Unit.Unit.String(), err)
 	}
 	return nil
 }
 
-func (c *LevelCmd) SetRefLevel(d *tinysa.Device) error {
-	fmt.Println("set reference level to", *c.RefLevel)
+func (c *LevelCmd) SetRefLevel(d Device) error {
+	_, _ = fmt.Fprintln(stdout, "set reference level to", *c.RefLevel)
 	if err := d.SetTraceRefLevel(*c.RefLevel); err != nil {
 		return fmt.Errorf("failed to set reference level to %d: %w", *c.RefLevel, err)
 	}
 	return nil
 }
 
-func (c *LevelCmd) SetRefLevelAuto(d *tinysa.Device) error {
-	fmt.Println("set reference level to auto")
+func (c *LevelCmd) SetRefLevelAuto(d Device) error {
+	_, _ = fmt.Fprintln(stdout, "set reference level to auto")
 	if err := d.SetTraceRefLevelAuto(); err != nil {
 		return fmt.Errorf("failed to set reference level to auto: %w", err)
 	}
 	return nil
 }
 
-func (c *LevelCmd) SetScale(d *tinysa.Device) error {
-	fmt.Println("set display scale to", *c.Scale)
+func (c *LevelCmd) SetScale(d Device) error {
+	_, _ = fmt.Fprintln(stdout, "set display scale to", *c.Scale)
 	if err := d.SetTraceScale(*c.Scale); err != nil {
 		return fmt.Errorf("failed to set scale to %f: %w", *c.Scale, err)
 	}
 	return nil
 }
 
-func (c *LevelCmd) EnableLNA(d *tinysa.Device) error {
-	fmt.Println("enable lna")
+func (c *LevelCmd) EnableLNA(d Device) error {
+	_, _ = fmt.Fprintln(stdout, "enable lna")
 	if err := d.EnableLNA(); err != nil {
 		return fmt.Errorf("failed to enable lna: %w", err)
 	}
 	return nil
 }
 
-func (c *LevelCmd) DisableLNA(d *tinysa.Device) error {
-	fmt.Println("disable lna")
+func (c *LevelCmd) DisableLNA(d Device) error {
+	_, _ = fmt.Fprintln(stdout, "disable lna")
 	if err := d.DisableLNA(); err != nil {
 		return fmt.Errorf("failed to disable lna: %w", err)
 	}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestLevelCmd(t *testing.T) {
+	runCliTests(t, []cliTest{
+		{
+			name:      "unit",
+			args:      []string{"level", "--unit", "vpp"},
+			wantOut:   "set trace unit to Vpp\n",
+			wantCalls: []string{"SetTraceUnit(Vpp)", "Close()"},
+		},
+		{
+			name:      "ref and scale",
+			args:      []string{"level", "--ref", "-40", "--scale", "5"},
+			wantOut:   "set reference level to -40\nset display scale to 5\n",
+			wantCalls: []string{"SetTraceRefLevel(-40)", "SetTraceScale(5)", "Close()"},
+		},
+		{
+			name:      "ref auto and lna",
+			args:      []string{"level", "--ref-auto", "--lna"},
+			wantOut:   "set reference level to auto\nenable lna\n",
+			wantCalls: []string{"SetTraceRefLevelAuto()", "EnableLNA()", "Close()"},
+		},
+		{
+			name:      "no lna",
+			args:      []string{"level", "--no-lna"},
+			wantOut:   "disable lna\n",
+			wantCalls: []string{"DisableLNA()", "Close()"},
+		},
+		{
+			name:    "ref and ref auto",
+			args:    []string{"level", "--ref", "-40", "--ref-auto"},
+			wantErr: true,
+		},
+		{
+			name:    "invalid unit",
+			args:    []string{"level", "--unit", "dbw"},
+			wantErr: true,
+		},
+	})
+}
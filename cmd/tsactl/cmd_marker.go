@@ -5,7 +5,6 @@ import (
 	"github.com/alecthomas/kong"
 	"github.com/kkettinger/go-tinysa"
 	"github.com/kkettinger/tsactl/internal/util"
-	"os"
 	"text/tabwriter"
 )
 
@@ -18,6 +17,8 @@ type MarkerCmd struct {
 	Delta     MarkerDelta  `help:"Enable delta mode (off or reference marker)" group:"Marker flags:" placeholder:"<OFF|MARKER>"`
 	Tracking  *bool        `help:"Enable tracking mode" name:"track" negatable:"" group:"Marker flags:"`
 
+	OutputFlag `embed:""`
+
 	Marker uint `arg:"" name:"id" help:"Marker id" optional:""`
 }
 
@@ -30,7 +31,7 @@ func (c *MarkerCmd) Validate(ctx *kong.Context) error {
 }
 
 func (c *MarkerCmd) Run(globals *Globals, ctx *kong.Context) error {
-	var ops []func(*tinysa.Device) error
+	var ops []func(Device) error
 
 	hasMarker := c.Marker != 0
 
@@ -78,6 +79,9 @@ func (c *MarkerCmd) Run(globals *Globals, ctx *kong.Context) error {
 	if err != nil {
 		return err
 	}
+	defer d.Close()
+
+	format := c.outputFormat(globals)
 
 	// apply operations on marker
 	if hasMarker && len(ops) > 0 {
@@ -89,26 +93,37 @@ func (c *MarkerCmd) Run(globals *Globals, ctx *kong.Context) error {
 		return nil
 	}
 
-	w := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
-
 	// show details about a specific marker when no flags are given
 	if hasMarker {
 		m, err := d.GetMarker(c.Marker)
 		if err != nil {
 			return err
 		}
+		if format != formatText {
+			return printStructured(format, newMarkerInfo(m))
+		}
+		w := tabwriter.NewWriter(stdout, 0, 0, 3, ' ', 0)
 		printMarkerInfo(w, m)
 		_ = w.Flush()
 		return nil
 	}
 
 	// default: list details about all active marker
-	fmt.Println("Active markers:")
 	mAll, err := d.GetMarkerAll()
 	if err != nil {
 		return err
 	}
 
+	if format != formatText {
+		infos := make([]markerInfo, len(mAll))
+		for i, m := range mAll {
+			infos[i] = newMarkerInfo(m)
+		}
+		return printStructured(format, infos)
+	}
+
+	_, _ = fmt.Fprintln(stdout, "Active markers:")
+	w := tabwriter.NewWriter(stdout, 0, 0, 3, ' ', 0)
 	for _, m := range mAll {
 		printMarkerInfo(w, m)
 	}
@@ -121,31 +136,35 @@ func printMarkerInfo(w *tabwriter.Writer, m tinysa.Marker) {
 	_, _ = fmt.Fprintf(w, "  Marker %d:\t%s\t%g\t(Index %d)\n", m.Marker, util.FormatFrequency(m.Frequency), m.Value, m.Index)
 }
 
-func (c *MarkerCmd) EnableMarker(d *tinysa.Device) error {
-	fmt.Printf("enable marker #%d\n", c.Marker)
+func newMarkerInfo(m tinysa.Marker) markerInfo {
+	return markerInfo{Marker: m.Marker, Frequency: m.Frequency, Value: m.Value, Index: m.Index}
+}
+
+func (c *MarkerCmd) EnableMarker(d Device) error {
+	_, _ = fmt.Fprintf(stdout, "enable marker #%d\n", c.Marker)
 	if err := d.EnableMarker(c.Marker); err != nil {
 		return fmt.Errorf("failed to enable marker #%d: %w", c.Marker, err)
 	}
 	return nil
 }
 
-func (c *MarkerCmd) DisableMarker(d *tinysa.Device) error {
-	fmt.Printf("disable marker #%d\n", c.Marker)
+func (c *MarkerCmd) DisableMarker(d Device) error {
+	_, _ = fmt.Fprintf(stdout, "disable marker #%d\n", c.Marker)
 	if err := d.DisableMarker(c.Marker); err != nil {
 		return fmt.Errorf("failed to disable marker #%d: %w", c.Marker, err)
 	}
 	return nil
 }
 
-func (c *MarkerCmd) AssignTrace(d *tinysa.Device) error {
-	fmt.Printf("assign marker #%d to trace #%d\n", c.Marker, *c.Trace)
+func (c *MarkerCmd) AssignTrace(d Device) error {
+	_, _ = fmt.Fprintf(stdout, "assign marker #%d to trace #%d\n", c.Marker, *c.Trace)
 	if err := d.SetMarkerTrace(c.Marker, *c.Trace); err != nil {
 		return fmt.Errorf("failed to assign marker #%d to trace #%d: %w", c.Marker, *c.Trace, err)
 	}
 	return nil
 }
 
-func (c *MarkerCmd) SetFrequency(d *tinysa.Device) error {
+func (c *MarkerCmd) SetFrequency(d Device) error {
 	freq := c.Frequency.Value
 
 	if c.Frequency.Relative {
@@ -162,47 +181,47 @@ func (c *MarkerCmd) SetFrequency(d *tinysa.Device) error {
 
 	uFreq := uint64(freq)
 
-	fmt.Printf("set marker #%d to frequency %s\n", c.Marker, util.FormatFrequency(uFreq))
+	_, _ = fmt.Fprintf(stdout, "set marker #%d to frequency %s\n", c.Marker, util.FormatFrequency(uFreq))
 	if err := d.SetMarkerFreq(c.Marker, uFreq); err != nil {
 		return fmt.Errorf("failed to set marker #%d to frequency #%d: %w", c.Marker, uFreq, err)
 	}
 	return nil
 }
 
-func (c *MarkerCmd) EnableDelta(d *tinysa.Device) error {
-	fmt.Printf("enable delta mode for marker #%d relative to marker #%d\n", c.Marker, c.Delta.RefMarker)
+func (c *MarkerCmd) EnableDelta(d Device) error {
+	_, _ = fmt.Fprintf(stdout, "enable delta mode for marker #%d relative to marker #%d\n", c.Marker, c.Delta.RefMarker)
 	if err := d.EnableMarkerDelta(c.Marker, c.Delta.RefMarker); err != nil {
 		return fmt.Errorf("failed to enable delta mode for marker #%d: %w", c.Marker, err)
 	}
 	return nil
 }
 
-func (c *MarkerCmd) DisableDelta(d *tinysa.Device) error {
-	fmt.Printf("disable delta mode for marker #%d\n", c.Marker)
+func (c *MarkerCmd) DisableDelta(d Device) error {
+	_, _ = fmt.Fprintf(stdout, "disable delta mode for marker #%d\n", c.Marker)
 	if err := d.DisableMarkerDelta(c.Marker); err != nil {
 		return fmt.Errorf("failed to disable delta mode for marker #%d: %w", c.Marker, err)
 	}
 	return nil
 }
 
-func (c *MarkerCmd) SetPeak(d *tinysa.Device) error {
-	fmt.Printf("set marker #%d to peak\n", c.Marker)
+func (c *MarkerCmd) SetPeak(d Device) error {
+	_, _ = fmt.Fprintf(stdout, "set marker #%d to peak\n", c.Marker)
 	if err := d.MoveMarkerPeak(c.Marker); err != nil {
 		return fmt.Errorf("failed to set marker #%d to peak: %w", c.Marker, err)
 	}
 	return nil
 }
 
-func (c *MarkerCmd) EnableTracking(d *tinysa.Device) error {
-	fmt.Printf("enable tracking for marker #%d\n", c.Marker)
+func (c *MarkerCmd) EnableTracking(d Device) error {
+	_, _ = fmt.Fprintf(stdout, "enable tracking for marker #%d\n", c.Marker)
 	if err := d.EnableMarkerTracking(c.Marker); err != nil {
 		return fmt.Errorf("failed to enable tracking for marker #%d: %w", c.Marker, err)
 	}
 	return nil
 }
 
-func (c *MarkerCmd) DisableTracking(d *tinysa.Device) error {
-	fmt.Printf("disable tracking for marker #%d\n", c.Marker)
+func (c *MarkerCmd) DisableTracking(d Device) error {
+	_, _ = fmt.Fprintf(stdout, "disable tracking for marker #%d\n", c.Marker)
 	if err := d.DisableMarkerTracking(c.Marker); err != nil {
 		return fmt.Errorf("failed to disable tracking for marker #%d: %w", c.Marker, err)
 	}
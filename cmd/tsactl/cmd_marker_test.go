@@ -0,0 +1,98 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestMarkerCmd(t *testing.T) {
+	runCliTests(t, []cliTest{
+		{
+			name: "list",
+			args: []string{"marker"},
+			wantOut: "Active markers:\n" +
+				"  Marker 1:   410 MHz     -89.4   (Index 45)\n" +
+				"  Marker 2:   447.7 MHz   -67.9   (Index 214)\n",
+			wantCalls: []string{"GetMarkerAll()", "Close()"},
+		},
+		{
+			name:      "single",
+			args:      []string{"marker", "2"},
+			wantOut:   "  Marker 2:   447.7 MHz   -67.9   (Index 214)\n",
+			wantCalls: []string{"GetMarker(2)", "Close()"},
+		},
+		{
+			name: "list json",
+			args: []string{"--format", "json", "marker"},
+			wantOut: `[
+  {
+    "marker": 1,
+    "frequency": 410000000,
+    "value": -89.4,
+    "index": 45
+  },
+  {
+    "marker": 2,
+    "frequency": 447700000,
+    "value": -67.9,
+    "index": 214
+  }
+]
+`,
+			wantCalls: []string{"GetMarkerAll()", "Close()"},
+		},
+		{
+			name:      "single yaml",
+			args:      []string{"--format", "yaml", "marker", "1"},
+			wantOut:   "marker: 1\nfrequency: 410000000\nvalue: -89.4\nindex: 45\n",
+			wantCalls: []string{"GetMarker(1)", "Close()"},
+		},
+		{
+			name:      "single output yaml",
+			args:      []string{"marker", "1", "--output", "yaml"},
+			wantOut:   "marker: 1\nfrequency: 410000000\nvalue: -89.4\nindex: 45\n",
+			wantCalls: []string{"GetMarker(1)", "Close()"},
+		},
+		{
+			name: "trace, peak and delta off",
+			args: []string{"marker", "2", "--trace", "2", "--peak", "--delta=off"},
+			wantOut: "assign marker #2 to trace #2\n" +
+				"disable delta mode for marker #2\n" +
+				"set marker #2 to peak\n",
+			wantCalls: []string{"SetMarkerTrace(2, 2)", "DisableMarkerDelta(2)", "MoveMarkerPeak(2)", "Close()"},
+		},
+		{
+			name:      "frequency",
+			args:      []string{"marker", "2", "--freq", "419mhz"},
+			wantOut:   "set marker #2 to frequency 419 MHz\n",
+			wantCalls: []string{"SetMarkerFreq(2, 419000000)", "Close()"},
+		},
+		{
+			name:      "relative frequency",
+			args:      []string{"marker", "1", "-f", "-1m"},
+			wantOut:   "set marker #1 to frequency 409 MHz\n",
+			wantCalls: []string{"GetMarker(1)", "SetMarkerFreq(1, 409000000)", "Close()"},
+		},
+		{
+			name:      "enable with delta and tracking",
+			args:      []string{"marker", "3", "-e", "--delta", "1", "--track"},
+			wantOut:   "enable marker #3\nenable delta mode for marker #3 relative to marker #1\nenable tracking for marker #3\n",
+			wantCalls: []string{"EnableMarker(3)", "EnableMarkerDelta(3, 1)", "EnableMarkerTracking(3)", "Close()"},
+		},
+		{
+			name:      "disable and no tracking",
+			args:      []string{"marker", "3", "-d", "--no-track"},
+			wantOut:   "disable marker #3\ndisable tracking for marker #3\n",
+			wantCalls: []string{"DisableMarker(3)", "DisableMarkerTracking(3)", "Close()"},
+		},
+		{
+			name:    "flags without id",
+			args:    []string{"marker", "--peak"},
+			wantErr: true,
+		},
+		{
+			name:    "enable and disable",
+			args:    []string{"marker", "1", "-e", "-d"},
+			wantErr: true,
+		},
+	})
+}
@@ -0,0 +1,191 @@
+package main
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/alecthomas/kong"
+	"github.com/kkettinger/go-tinysa"
+	"github.com/kkettinger/tsactl/internal/util"
+)
+
+type MeasureCmd struct {
+	Power MeasurePowerCmd `help:"Measure channel power and occupied bandwidth of a trace (default)" cmd:"" default:"withargs"`
+	Noise MeasureNoiseCmd `help:"Estimate the noise floor of a trace and the SNR of a signal" cmd:""`
+	TOI   MeasureTOICmd   `help:"Measure the third-order intercept of a two-tone signal" cmd:"" name:"toi"`
+}
+
+type MeasurePowerCmd struct {
+	Trace        uint       `help:"Trace to measure" short:"t" default:"1" group:"Measure flags:"`
+	ChannelPower Channel    `help:"Measure the integrated power of a channel" name:"channel-power" group:"Measure flags:" placeholder:"CENTER:BW"`
+	OBW          Percentage `help:"Measure the bandwidth containing this percentage of the total power, e.g. 99%" name:"obw" group:"Measure flags:" placeholder:"PERCENT"`
+	RBW          Frequency  `help:"Resolution bandwidth of the sweep, defaults to the point spacing like the automatic RBW of the device" name:"rbw" group:"Measure flags:" placeholder:"FREQ"`
+}
+
+type channelPowerInfo struct {
+	Center    uint64  `json:"center" yaml:"center"`
+	Bandwidth uint64  `json:"bandwidth" yaml:"bandwidth"`
+	Power     float64 `json:"power_dbm" yaml:"power_dbm"`
+}
+
+type occupiedBandwidthInfo struct {
+	Percent    float64 `json:"percent" yaml:"percent"`
+	Bandwidth  uint64  `json:"bandwidth" yaml:"bandwidth"`
+	Start      uint64  `json:"start" yaml:"start"`
+	Stop       uint64  `json:"stop" yaml:"stop"`
+	TotalPower float64 `json:"total_power_dbm" yaml:"total_power_dbm"`
+}
+
+type measureInfo struct {
+	ChannelPower      *channelPowerInfo      `json:"channel_power,omitempty" yaml:"channel_power,omitempty"`
+	OccupiedBandwidth *occupiedBandwidthInfo `json:"occupied_bandwidth,omitempty" yaml:"occupied_bandwidth,omitempty"`
+}
+
+func (c *MeasurePowerCmd) Run(globals *Globals, ctx *kong.Context) error {
+	if !c.ChannelPower.Valid && !c.OBW.Valid {
+		_ = ctx.PrintUsage(false)
+		return nil
+	}
+
+	d, err := initDevice(globals)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+
+	spec, err := getSpectrum(d, c.Trace, c.RBW.Value)
+	if err != nil {
+		return err
+	}
+
+	var info measureInfo
+	if c.ChannelPower.Valid {
+		power, err := spec.channelPower(c.ChannelPower.Start(), c.ChannelPower.Stop())
+		if err != nil {
+			return err
+		}
+		info.ChannelPower = &channelPowerInfo{Center: c.ChannelPower.Center, Bandwidth: c.ChannelPower.Bandwidth, Power: power}
+	}
+	if c.OBW.Valid {
+		start, stop := spec.occupiedBandwidth(c.OBW.Value / 100)
+		info.OccupiedBandwidth = &occupiedBandwidthInfo{
+			Percent:    c.OBW.Value,
+			Bandwidth:  stop - start,
+			Start:      start,
+			Stop:       stop,
+			TotalPower: milliwattToDBm(spec.sum()),
+		}
+	}
+
+	if globals.Format != formatText {
+		return printStructured(globals.Format, info)
+	}
+
+	if p := info.ChannelPower; p != nil {
+		_, _ = fmt.Fprintf(stdout, "Channel power: %.2f dBm (%s, %s bandwidth)\n",
+			p.Power, util.FormatFrequency(p.Center), util.FormatFrequency(p.Bandwidth))
+	}
+	if o := info.OccupiedBandwidth; o != nil {
+		_, _ = fmt.Fprintf(stdout, "Occupied bandwidth: %s (%g%%, %s to %s)\n",
+			util.FormatFrequency(o.Bandwidth), o.Percent, util.FormatFrequency(o.Start), util.FormatFrequency(o.Stop))
+		_, _ = fmt.Fprintf(stdout, "Total power: %.2f dBm\n", o.TotalPower)
+	}
+
+	return nil
+}
+
+// spectrum is the linear power of a trace, split into bins around the points. The power of each bin
+// is scaled from the resolution bandwidth to the bin width, so the bins add up to the integrated power.
+type spectrum struct {
+	edges []float64 // bin edges in Hz, one more than bins
+	power []float64 // bin power in mW
+}
+
+// getSpectrum fetches a trace and converts it to a spectrum. The rbw defaults to the point spacing when zero.
+func getSpectrum(d Device, traceID uint, rbw uint64) (*spectrum, error) {
+	trace, err := d.GetTrace(traceID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get trace: %w", err)
+	}
+	data, err := d.GetTraceData(traceID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get trace data: %w", err)
+	}
+	return newSpectrum(data, trace.Unit, rbw)
+}
+
+func newSpectrum(data []tinysa.TraceData, unit tinysa.TraceUnit, rbw uint64) (*spectrum, error) {
+	n := len(data)
+	if n < 2 || data[n-1].Frequency <= data[0].Frequency {
+		return nil, fmt.Errorf("trace needs at least two points over a frequency span")
+	}
+
+	s := &spectrum{edges: make([]float64, n+1), power: make([]float64, n)}
+	s.edges[0] = float64(data[0].Frequency) - float64(data[1].Frequency-data[0].Frequency)/2
+	for i := 1; i < n; i++ {
+		s.edges[i] = float64(data[i-1].Frequency+data[i].Frequency) / 2
+	}
+	s.edges[n] = float64(data[n-1].Frequency) + float64(data[n-1].Frequency-data[n-2].Frequency)/2
+
+	bw := float64(rbw)
+	if rbw == 0 {
+		bw = float64(data[n-1].Frequency-data[0].Frequency) / float64(n-1)
+	}
+	for i, dp := range data {
+		dBm, err := toDBm(dp.Value, unit)
+		if err != nil {
+			return nil, err
+		}
+		s.power[i] = dBmToMilliwatt(dBm) * (s.edges[i+1] - s.edges[i]) / bw
+	}
+
+	return s, nil
+}
+
+// channelPower returns the power in dBm between start and stop, with partially covered bins weighted by their overlap.
+func (s *spectrum) channelPower(start, stop uint64) (float64, error) {
+	lo, hi := float64(start), float64(stop)
+	if lo < s.edges[0] || hi > s.edges[len(s.edges)-1] {
+		return 0, fmt.Errorf("channel %s to %s exceeds the sweep", util.FormatFrequency(start), util.FormatFrequency(stop))
+	}
+
+	var mW float64
+	for i, p := range s.power {
+		overlap := min(hi, s.edges[i+1]) - max(lo, s.edges[i])
+		if overlap > 0 {
+			mW += p * overlap / (s.edges[i+1] - s.edges[i])
+		}
+	}
+	return milliwattToDBm(mW), nil
+}
+
+// sum returns the power of the whole sweep in mW.
+func (s *spectrum) sum() float64 {
+	var mW float64
+	for _, p := range s.power {
+		mW += p
+	}
+	return mW
+}
+
+// occupiedBandwidth returns the frequencies between which the fraction of the total power is contained,
+// with the remaining power split evenly below and above.
+func (s *spectrum) occupiedBandwidth(fraction float64) (uint64, uint64) {
+	total := s.sum()
+	start := s.cumulativeFrequency(total * (1 - fraction) / 2)
+	stop := s.cumulativeFrequency(total * (1 + fraction) / 2)
+	return uint64(math.Round(max(start, 0))), uint64(math.Round(stop))
+}
+
+// cumulativeFrequency returns the frequency at which the power summed up from the start of the sweep
+// reaches target, interpolated within the bin.
+func (s *spectrum) cumulativeFrequency(target float64) float64 {
+	var sum float64
+	for i, p := range s.power {
+		if p > 0 && sum+p >= target {
+			return s.edges[i] + (target-sum)/p*(s.edges[i+1]-s.edges[i])
+		}
+		sum += p
+	}
+	return s.edges[len(s.edges)-1]
+}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"slices"
+
+	"github.com/kkettinger/go-tinysa"
+	"github.com/kkettinger/tsactl/internal/util"
+)
+
+type MeasureNoiseCmd struct {
+	Trace      uint       `help:"Trace to measure" short:"t" default:"1" group:"Noise flags:"`
+	Percentile Percentage `help:"Percentile of the point levels taken as noise floor, 50 is the median" short:"p" default:"50" group:"Noise flags:" placeholder:"PERCENT"`
+	RBW        Frequency  `help:"Resolution bandwidth of the sweep, defaults to the point spacing, which is not read from the device and may differ from its actual RBW" name:"rbw" group:"Noise flags:" placeholder:"FREQ"`
+	Signal     Carrier    `help:"Report the SNR of the highest point within the RBW around this frequency, or of the highest peak with peak" short:"s" group:"Noise flags:" placeholder:"FREQ|peak"`
+}
+
+type snrInfo struct {
+	Frequency uint64  `json:"frequency" yaml:"frequency"`
+	Level     float64 `json:"level_dbm" yaml:"level_dbm"`
+	SNR       float64 `json:"snr_db" yaml:"snr_db"`
+}
+
+type noiseInfo struct {
+	Points       int      `json:"points" yaml:"points"`
+	Percentile   float64  `json:"percentile" yaml:"percentile"`
+	NoiseFloor   float64  `json:"noise_floor_dbm" yaml:"noise_floor_dbm"`
+	RBW          uint64   `json:"rbw" yaml:"rbw"`
+	NoiseDensity float64  `json:"noise_density_dbm_hz" yaml:"noise_density_dbm_hz"`
+	Signal       *snrInfo `json:"signal,omitempty" yaml:"signal,omitempty"`
+}
+
+func (c *MeasureNoiseCmd) Run(globals *Globals) error {
+	d, err := initDevice(globals)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+
+	trace, err := d.GetTrace(c.Trace)
+	if err != nil {
+		return fmt.Errorf("failed to get trace: %w", err)
+	}
+	data, err := d.GetTraceData(c.Trace)
+	if err != nil {
+		return fmt.Errorf("failed to get trace data: %w", err)
+	}
+
+	info, err := c.measureNoise(data, trace.Unit)
+	if err != nil {
+		return err
+	}
+
+	if globals.Format != formatText {
+		return printStructured(globals.Format, info)
+	}
+
+	_, _ = fmt.Fprintf(stdout, "Noise floor: %.2f dBm (percentile %g of %d points)\n", info.NoiseFloor, info.Percentile, info.Points)
+	_, _ = fmt.Fprintf(stdout, "Noise density: %.2f dBm/Hz (%s RBW)\n", info.NoiseDensity, util.FormatFrequency(info.RBW))
+	if s := info.Signal; s != nil {
+		_, _ = fmt.Fprintf(stdout, "Signal: %.2f dBm at %s\n", s.Level, util.FormatFrequency(s.Frequency))
+		_, _ = fmt.Fprintf(stdout, "SNR: %.2f dB\n", s.SNR)
+	}
+
+	return nil
+}
+
+// measureNoise takes the percentile of the point levels as noise floor, which is robust against the few points
+// of carriers and spurs, and normalizes it by the RBW to a noise density.
+func (c *MeasureNoiseCmd) measureNoise(data []tinysa.TraceData, unit tinysa.TraceUnit) (noiseInfo, error) {
+	n := len(data)
+	if n < 2 || data[n-1].Frequency <= data[0].Frequency {
+		return noiseInfo{}, fmt.Errorf("trace needs at least two points over a frequency span")
+	}
+
+	levels := make([]float64, n)
+	for i, dp := range data {
+		dBm, err := toDBm(dp.Value, unit)
+		if err != nil {
+			return noiseInfo{}, err
+		}
+		levels[i] = dBm
+	}
+
+	rbw := c.RBW.Value
+	if rbw == 0 {
+		rbw = (data[n-1].Frequency - data[0].Frequency) / uint64(n-1)
+	}
+
+	// remove the float noise of the interpolation and the logarithm
+	round := func(x float64) float64 { return math.Round(x*1e6) / 1e6 }
+
+	info := noiseInfo{Points: n, Percentile: c.Percentile.Value, RBW: rbw}
+	info.NoiseFloor = round(percentile(levels, c.Percentile.Value))
+	info.NoiseDensity = round(info.NoiseFloor - 10*math.Log10(float64(rbw)))
+
+	if c.Signal.Valid {
+		var i int
+		if c.Signal.Peak {
+			i = slices.Index(levels, slices.Max(levels))
+		} else {
+			if c.Signal.Frequency < data[0].Frequency || c.Signal.Frequency > data[n-1].Frequency {
+				return noiseInfo{}, fmt.Errorf("signal at %s is outside of the sweep", util.FormatFrequency(c.Signal.Frequency))
+			}
+			i = peakNear(data, levels, c.Signal.Frequency, rbw)
+		}
+		info.Signal = &snrInfo{Frequency: data[i].Frequency, Level: levels[i], SNR: round(levels[i] - info.NoiseFloor)}
+	}
+
+	return info, nil
+}
+
+// percentile returns the p-th percentile of values, linearly interpolated between the closest ranks.
+func percentile(values []float64, p float64) float64 {
+	sorted := slices.Sorted(slices.Values(values))
+	rank := p / 100 * float64(len(sorted)-1)
+	lo := int(math.Floor(rank))
+	if lo >= len(sorted)-1 {
+		return sorted[len(sorted)-1]
+	}
+	return sorted[lo] + (rank-float64(lo))*(sorted[lo+1]-sorted[lo])
+}
+
+// peakNear returns the index of the highest level within freq ± span, so a carrier between two points or slightly
+// off its nominal frequency is found. Without points in the range, it is the point closest to freq.
+func peakNear(data []tinysa.TraceData, levels []float64, freq, span uint64) int {
+	peak := -1
+	for i, dp := range data {
+		if absDiff(dp.Frequency, freq) <= span && (peak < 0 || levels[i] > levels[peak]) {
+			peak = i
+		}
+	}
+	if peak < 0 {
+		return nearestPoint(data, freq)
+	}
+	return peak
+}
+
+// nearestPoint returns the index of the point closest to freq.
+func nearestPoint(data []tinysa.TraceData, freq uint64) int {
+	nearest := 0
+	for i, dp := range data {
+		if absDiff(dp.Frequency, freq) < absDiff(data[nearest].Frequency, freq) {
+			nearest = i
+		}
+	}
+	return nearest
+}
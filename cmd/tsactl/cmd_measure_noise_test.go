@@ -0,0 +1,84 @@
+package main
+
+import (
+	"math"
+	"testing"
+)
+
+func TestMeasureNoiseCmd(t *testing.T) {
+	runCliTests(t, []cliTest{
+		{
+			name: "median",
+			args: []string{"measure", "noise"},
+			wantOut: "Noise floor: -89.75 dBm (percentile 50 of 3 points)\n" +
+				"Noise density: -166.74 dBm/Hz (50 MHz RBW)\n",
+			wantCalls: []string{"GetTrace(1)", "GetTraceData(1)", "Close()"},
+		},
+		{
+			name: "snr of highest peak",
+			args: []string{"measure", "noise", "--trace", "2", "--percentile", "10%", "--signal", "peak"},
+			wantOut: "Noise floor: -85.56 dBm (percentile 10 of 3 points)\n" +
+				"Noise density: -162.55 dBm/Hz (50 MHz RBW)\n" +
+				"Signal: -35.00 dBm at 450 MHz\n" +
+				"SNR: 50.56 dB\n",
+			wantCalls: []string{"GetTrace(2)", "GetTraceData(2)", "Close()"},
+		},
+		{
+			name: "highest point around carrier",
+			args: []string{"measure", "noise", "--signal", "480M"},
+			wantOut: "Noise floor: -89.75 dBm (percentile 50 of 3 points)\n" +
+				"Noise density: -166.74 dBm/Hz (50 MHz RBW)\n" +
+				"Signal: -40.50 dBm at 450 MHz\n" +
+				"SNR: 49.25 dB\n",
+			wantCalls: []string{"GetTrace(1)", "GetTraceData(1)", "Close()"},
+		},
+		{
+			// no point within the rbw, the nearest one is used
+			name: "json with carrier and rbw",
+			args: []string{"-F", "json", "measure", "noise", "--signal", "480M", "--rbw", "10k"},
+			wantOut: `{
+  "points": 3,
+  "percentile": 50,
+  "noise_floor_dbm": -89.75,
+  "rbw": 10000,
+  "noise_density_dbm_hz": -129.75,
+  "signal": {
+    "frequency": 500000000,
+    "level_dbm": -89.75,
+    "snr_db": 0
+  }
+}
+`,
+			wantCalls: []string{"GetTrace(1)", "GetTraceData(1)", "Close()"},
+		},
+		{
+			name:      "carrier outside of sweep",
+			args:      []string{"measure", "noise", "--signal", "600M"},
+			wantCalls: []string{"GetTrace(1)", "GetTraceData(1)", "Close()"},
+			wantErr:   true,
+		},
+		{
+			name:    "invalid carrier",
+			args:    []string{"measure", "noise", "--signal", "highest"},
+			wantErr: true,
+		},
+	})
+}
+
+func TestPercentile(t *testing.T) {
+	values := []float64{4, 1, 3, 2, 5}
+	tests := []struct {
+		p    float64
+		want float64
+	}{
+		{p: 50, want: 3},
+		{p: 10, want: 1.4},
+		{p: 75, want: 4},
+		{p: 99, want: 4.96},
+	}
+	for _, tt := range tests {
+		if got := percentile(values, tt.p); math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("percentile(%g) = %g, want %g", tt.p, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"math"
+	"testing"
+
+	"github.com/kkettinger/go-tinysa"
+)
+
+func TestMeasureCmd(t *testing.T) {
+	runCliTests(t, []cliTest{
+		{
+			name:      "channel power",
+			args:      []string{"measure", "--channel-power", "450M:50M"},
+			wantOut:   "Channel power: -40.50 dBm (450 MHz, 50 MHz bandwidth)\n",
+			wantCalls: []string{"GetTrace(1)", "GetTraceData(1)", "Close()"},
+		},
+		{
+			name: "occupied bandwidth",
+			args: []string{"measure", "--trace", "2", "--obw", "99%"},
+			wantOut: "Occupied bandwidth: 49.500937 MHz (99%, 425.249479 MHz to 474.750416 MHz)\n" +
+				"Total power: -35.00 dBm\n",
+			wantCalls: []string{"GetTrace(2)", "GetTraceData(2)", "Close()"},
+		},
+		{
+			name: "json with rbw",
+			args: []string{"-F", "json", "measure", "--channel-power", "450M:50M", "--rbw", "5M"},
+			wantOut: `{
+  "channel_power": {
+    "center": 450000000,
+    "bandwidth": 50000000,
+    "power_dbm": -30.5
+  }
+}
+`,
+			wantCalls: []string{"GetTrace(1)", "GetTraceData(1)", "Close()"},
+		},
+		{
+			name:      "channel outside of sweep",
+			args:      []string{"measure", "--channel-power", "530M:20M"},
+			wantCalls: []string{"GetTrace(1)", "GetTraceData(1)", "Close()"},
+			wantErr:   true,
+		},
+		{
+			name:    "invalid percentage",
+			args:    []string{"measure", "--obw", "100%"},
+			wantErr: true,
+		},
+		{
+			name:    "invalid channel",
+			args:    []string{"measure", "--channel-power", "450M"},
+			wantErr: true,
+		},
+	})
+}
+
+func TestSpectrum(t *testing.T) {
+	// flat -30 dBm (1 µW) from 10 MHz to 20 MHz, the bins are 1 MHz wide
+	data := make([]tinysa.TraceData, 11)
+	for i := range data {
+		data[i] = tinysa.TraceData{Point: uint(i), Frequency: uint64(10_000_000 + i*1_000_000), Value: -30} // #nosec G115
+	}
+	s, err := newSpectrum(data, tinysa.TraceUnitDBm, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := milliwattToDBm(s.sum()); math.Abs(got-(-30+10*math.Log10(11))) > 1e-9 {
+		t.Errorf("total power = %v", got)
+	}
+
+	tests := []struct {
+		start, stop uint64
+		want        float64
+	}{
+		{12_500_000, 17_500_000, -30 + 10*math.Log10(5)},
+		// half of two bins
+		{14_000_000, 15_000_000, -30},
+		{9_500_000, 20_500_000, -30 + 10*math.Log10(11)},
+	}
+	for _, tt := range tests {
+		got, err := s.channelPower(tt.start, tt.stop)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("channelPower(%d, %d) = %v, want %v", tt.start, tt.stop, got, tt.want)
+		}
+	}
+	if _, err := s.channelPower(9_000_000, 12_000_000); err == nil {
+		t.Error("expected error for channel below the sweep")
+	}
+
+	// the flat spectrum spans 11 MHz between the outer bin edges, 5% of the power are cut on each side
+	start, stop := s.occupiedBandwidth(0.9)
+	if start != 10_050_000 || stop != 19_950_000 {
+		t.Errorf("occupied bandwidth = %d..%d, want 10050000..19950000", start, stop)
+	}
+
+	if _, err := newSpectrum(data[:1], tinysa.TraceUnitDBm, 0); err == nil {
+		t.Error("expected error for single point")
+	}
+	if _, err := newSpectrum(data, tinysa.TraceUnitRaw, 0); err == nil {
+		t.Error("expected error for raw unit")
+	}
+}
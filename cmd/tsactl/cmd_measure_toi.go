@@ -0,0 +1,178 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"os"
+	"os/signal"
+	"syscall"
+	"text/tabwriter"
+	"time"
+
+	"github.com/kkettinger/tsactl/internal/util"
+)
+
+type MeasureTOICmd struct {
+	F1    Frequency     `help:"Frequency of the first tone" name:"f1" required:"" group:"TOI flags:" placeholder:"FREQ"`
+	F2    Frequency     `help:"Frequency of the second tone" name:"f2" required:"" group:"TOI flags:" placeholder:"FREQ"`
+	Span  Frequency     `help:"Span around each tone and product to search the peak in, defaults to half the tone spacing" short:"s" group:"TOI flags:" placeholder:"FREQ"`
+	Gain  float64       `help:"Gain of the device under test in dB, to refer the intercept to its input" short:"g" default:"0" group:"TOI flags:"`
+	Trace uint          `help:"Trace to read" short:"t" default:"1" group:"TOI flags:"`
+	Dwell time.Duration `help:"Time to wait after retuning before reading a tone or product, should be at least one sweep time" default:"500ms" group:"TOI flags:"`
+}
+
+type toiLevelInfo struct {
+	Signal        string  `json:"signal" yaml:"signal"`
+	Frequency     uint64  `json:"frequency" yaml:"frequency"`
+	PeakFrequency uint64  `json:"peak_frequency" yaml:"peak_frequency"`
+	Level         float64 `json:"level_dbm" yaml:"level_dbm"`
+	DBc           float64 `json:"dbc" yaml:"dbc"`
+}
+
+type toiInfo struct {
+	Levels     []toiLevelInfo `json:"levels" yaml:"levels"`
+	OutputTOI  float64        `json:"output_toi_dbm" yaml:"output_toi_dbm"`
+	OutputLow  float64        `json:"output_toi_lower_dbm" yaml:"output_toi_lower_dbm"`
+	OutputHigh float64        `json:"output_toi_upper_dbm" yaml:"output_toi_upper_dbm"`
+	Gain       float64        `json:"gain_db" yaml:"gain_db"`
+	InputTOI   float64        `json:"input_toi_dbm" yaml:"input_toi_dbm"`
+}
+
+// toiCSVInfo is the single CSV row of a measurement with the levels of the signals as prefixed columns.
+type toiCSVInfo struct {
+	Tone1      toiLevelInfo `json:"tone1"`
+	Tone2      toiLevelInfo `json:"tone2"`
+	IM3Lower   toiLevelInfo `json:"im3_lower"`
+	IM3Upper   toiLevelInfo `json:"im3_upper"`
+	OutputTOI  float64      `json:"output_toi_dbm"`
+	OutputLow  float64      `json:"output_toi_lower_dbm"`
+	OutputHigh float64      `json:"output_toi_upper_dbm"`
+	Gain       float64      `json:"gain_db"`
+	InputTOI   float64      `json:"input_toi_dbm"`
+}
+
+func (c *MeasureTOICmd) Run(globals *Globals) (err error) {
+	f1, f2 := min(c.F1.Value, c.F2.Value), max(c.F1.Value, c.F2.Value)
+	if f1 == f2 {
+		return fmt.Errorf("tones must have different frequencies")
+	}
+	spacing := f2 - f1
+	span := c.Span.Value
+	if span == 0 {
+		span = spacing / 2
+	}
+	if span == 0 || span >= spacing {
+		return fmt.Errorf("span must be greater than zero and less than the tone spacing of %s", util.FormatFrequency(spacing))
+	}
+	if 2*f1 <= f2 || 2*f1-f2 <= span/2 {
+		return fmt.Errorf("lower product at 2*f1-f2 is below 0 Hz, the tone spacing must be less than the first tone")
+	}
+	// the intermodulation products of third order
+	lower, upper := 2*f1-f2, 2*f2-f1
+
+	d, err := initDevice(globals)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+
+	limit := deviceLimit(d.Model())
+	if upper+span/2 > limit.maxFrequency {
+		return fmt.Errorf("upper product at %s exceeds the maximum of %s of the %s", util.FormatFrequency(upper),
+			util.FormatFrequency(limit.maxFrequency), d.Model())
+	}
+
+	trace, err := d.GetTrace(c.Trace)
+	if err != nil {
+		return fmt.Errorf("failed to get trace: %w", err)
+	}
+
+	// restore the sweep settings when done, also on errors and Ctrl-C
+	tuner, err := newSweepTuner(d)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if rerr := tuner.restore(); rerr != nil && err == nil {
+			err = rerr
+		}
+	}()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// measured from low to high frequency, so the sweep only moves up
+	signals := []struct {
+		name string
+		freq uint64
+	}{
+		{"IM3 lower", lower},
+		{"Tone 1", f1},
+		{"Tone 2", f2},
+		{"IM3 upper", upper},
+	}
+	levels := make([]toiLevelInfo, len(signals))
+	for i, s := range signals {
+		peak, level, err := tuner.peakAround(ctx, c.Trace, trace.Unit, s.freq, span, c.Dwell)
+		if err != nil {
+			return fmt.Errorf("failed to measure %s: %w", s.name, err)
+		}
+		levels[i] = toiLevelInfo{Signal: s.name, Frequency: s.freq, PeakFrequency: peak.Frequency, Level: level}
+	}
+
+	info := toiResult(levels[1], levels[2], levels[0], levels[3], c.Gain)
+	return printTOI(globals.Format, info)
+}
+
+// toiResult computes the IM3 levels relative to the adjacent tone and the intercept of both products. The
+// intercept is where the products, rising 3 dB per dB of tone level, would reach the tones. The worst
+// of both is reported as the output TOI.
+func toiResult(tone1, tone2, im3Lower, im3Upper toiLevelInfo, gain float64) toiInfo {
+	// remove the float noise of the subtractions
+	round := func(x float64) float64 { return math.Round(x*1e6) / 1e6 }
+
+	im3Lower.DBc = round(im3Lower.Level - tone1.Level)
+	im3Upper.DBc = round(im3Upper.Level - tone2.Level)
+
+	info := toiInfo{
+		Levels:     []toiLevelInfo{tone1, tone2, im3Lower, im3Upper},
+		OutputLow:  round(tone1.Level - im3Lower.DBc/2),
+		OutputHigh: round(tone2.Level - im3Upper.DBc/2),
+		Gain:       gain,
+	}
+	info.OutputTOI = min(info.OutputLow, info.OutputHigh)
+	info.InputTOI = round(info.OutputTOI - gain)
+	return info
+}
+
+func printTOI(format string, info toiInfo) error {
+	switch format {
+	case formatText:
+		w := tabwriter.NewWriter(stdout, 0, 0, 3, ' ', 0)
+		_, _ = fmt.Fprintln(w, "Signal\tFrequency\tPeak\tLevel\tRelative")
+		for _, l := range info.Levels {
+			_, _ = fmt.Fprintf(w, "%s\t%s\t%s\t%.2f dBm\t%.2f dBc\n", l.Signal, util.FormatFrequency(l.Frequency),
+				util.FormatFrequency(l.PeakFrequency), l.Level, l.DBc)
+		}
+		_ = w.Flush()
+		_, _ = fmt.Fprintf(stdout, "Output TOI: %.2f dBm (lower %.2f dBm, upper %.2f dBm)\n", info.OutputTOI, info.OutputLow, info.OutputHigh)
+		_, _ = fmt.Fprintf(stdout, "Input TOI: %.2f dBm (gain %g dB)\n", info.InputTOI, info.Gain)
+		return nil
+	case formatCSV:
+		// one row per measurement, so repeated measurements can be appended to a log
+		return printStructured(format, toiCSVInfo{
+			Tone1:      info.Levels[0],
+			Tone2:      info.Levels[1],
+			IM3Lower:   info.Levels[2],
+			IM3Upper:   info.Levels[3],
+			OutputTOI:  info.OutputTOI,
+			OutputLow:  info.OutputLow,
+			OutputHigh: info.OutputHigh,
+			Gain:       info.Gain,
+			InputTOI:   info.InputTOI,
+		})
+	default:
+		return printStructured(format, info)
+	}
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/kkettinger/go-tinysa"
+)
+
+// toneDevice returns a sweep with a peak at its center, with the level of a two-tone test at 100 and 101 MHz.
+type toneDevice struct {
+	*sweepDevice
+}
+
+func (d *toneDevice) GetTraceData(traceID uint) ([]tinysa.TraceData, error) {
+	s := d.sweep
+	center := (s.Start + s.Stop) / 2
+	levels := map[uint64]float64{99_000_000: -60, 100_000_000: -10, 101_000_000: -12, 102_000_000: -64}
+	data := []tinysa.TraceData{
+		{Trace: traceID, Point: 0, Frequency: s.Start, Value: -150},
+		{Trace: traceID, Point: 1, Frequency: center, Value: levels[center]},
+		{Trace: traceID, Point: 2, Frequency: s.Stop, Value: -150},
+	}
+	return data, d.record("GetTraceData", traceID)
+}
+
+func TestMeasureTOICmd(t *testing.T) {
+	tests := []struct {
+		name      string
+		args      []string
+		wantOut   string
+		wantCalls []string
+	}{
+		{
+			name: "text",
+			args: []string{"measure", "toi", "--f1", "100M", "--f2", "101M", "--gain", "20", "--dwell", "0"},
+			wantOut: "Signal      Frequency   Peak      Level        Relative\n" +
+				"Tone 1      100 MHz     100 MHz   -10.00 dBm   0.00 dBc\n" +
+				"Tone 2      101 MHz     101 MHz   -12.00 dBm   0.00 dBc\n" +
+				"IM3 lower   99 MHz      99 MHz    -60.00 dBm   -50.00 dBc\n" +
+				"IM3 upper   102 MHz     102 MHz   -64.00 dBm   -52.00 dBc\n" +
+				"Output TOI: 14.00 dBm (lower 15.00 dBm, upper 14.00 dBm)\n" +
+				"Input TOI: -6.00 dBm (gain 20 dB)\n",
+			wantCalls: []string{
+				"GetTrace(1)", "GetSweep()",
+				"SetSweepStart(98750000)", "SetSweepStop(99250000)", "GetTraceData(1)",
+				"SetSweepStop(100250000)", "SetSweepStart(99750000)", "GetTraceData(1)",
+				"SetSweepStop(101250000)", "SetSweepStart(100750000)", "GetTraceData(1)",
+				"SetSweepStop(102250000)", "SetSweepStart(101750000)", "GetTraceData(1)",
+				"SetSweepStop(500000000)", "SetSweepStart(400000000)",
+				"Close()",
+			},
+		},
+		{
+			name: "csv with swapped tones and span",
+			args: []string{"-F", "csv", "measure", "toi", "--f1", "101M", "--f2", "100M", "--span", "200k", "--gain", "10", "--dwell", "0"},
+			wantOut: "tone1_signal,tone1_frequency,tone1_peak_frequency,tone1_level_dbm,tone1_dbc," +
+				"tone2_signal,tone2_frequency,tone2_peak_frequency,tone2_level_dbm,tone2_dbc," +
+				"im3_lower_signal,im3_lower_frequency,im3_lower_peak_frequency,im3_lower_level_dbm,im3_lower_dbc," +
+				"im3_upper_signal,im3_upper_frequency,im3_upper_peak_frequency,im3_upper_level_dbm,im3_upper_dbc," +
+				"output_toi_dbm,output_toi_lower_dbm,output_toi_upper_dbm,gain_db,input_toi_dbm\n" +
+				"Tone 1,100000000,100000000,-10,0," +
+				"Tone 2,101000000,101000000,-12,0," +
+				"IM3 lower,99000000,99000000,-60,-50," +
+				"IM3 upper,102000000,102000000,-64,-52," +
+				"14,15,14,10,4\n",
+			wantCalls: []string{
+				"GetTrace(1)", "GetSweep()",
+				"SetSweepStart(98900000)", "SetSweepStop(99100000)", "GetTraceData(1)",
+				"SetSweepStop(100100000)", "SetSweepStart(99900000)", "GetTraceData(1)",
+				"SetSweepStop(101100000)", "SetSweepStart(100900000)", "GetTraceData(1)",
+				"SetSweepStop(102100000)", "SetSweepStart(101900000)", "GetTraceData(1)",
+				"SetSweepStop(500000000)", "SetSweepStart(400000000)",
+				"Close()",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := &toneDevice{&sweepDevice{fakeDevice: newFakeDevice()}}
+
+			out, err := runCli(t, d, tt.args...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if out != tt.wantOut {
+				t.Errorf("output = %q, want %q", out, tt.wantOut)
+			}
+			if got := strings.Join(d.calls, "\n"); got != strings.Join(tt.wantCalls, "\n") {
+				t.Errorf("calls = %q, want %q", d.calls, tt.wantCalls)
+			}
+		})
+	}
+}
+
+func TestMeasureTOICmdErrors(t *testing.T) {
+	runCliTests(t, []cliTest{
+		{
+			name:    "same frequencies",
+			args:    []string{"measure", "toi", "--f1", "100M", "--f2", "100M"},
+			wantErr: true,
+		},
+		{
+			name:    "span wider than tone spacing",
+			args:    []string{"measure", "toi", "--f1", "100M", "--f2", "101M", "--span", "1M"},
+			wantErr: true,
+		},
+		{
+			name:    "lower product below 0 Hz",
+			args:    []string{"measure", "toi", "--f1", "100M", "--f2", "200M"},
+			wantErr: true,
+		},
+		{
+			name:      "upper product above model limit",
+			args:      []string{"measure", "toi", "--f1", "5.9G", "--f2", "5.95G"},
+			wantCalls: []string{"Close()"},
+			wantErr:   true,
+		},
+		{
+			name:    "missing tone",
+			args:    []string{"measure", "toi", "--f1", "100M"},
+			wantErr: true,
+		},
+	})
+}
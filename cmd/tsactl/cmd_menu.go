@@ -14,12 +14,13 @@ func (c *MenuCmd) Run(globals *Globals) error {
 	if err != nil {
 		return err
 	}
+	defer d.Close()
 
 	idsStr := make([]string, len(c.MenuIds))
 	for i, v := range c.MenuIds {
 		idsStr[i] = fmt.Sprintf("%d", v)
 	}
-	fmt.Printf("trigger menu %s\n", strings.Join(idsStr, ", "))
+	_, _ = fmt.Fprintf(stdout, "trigger menu %s\n", strings.Join(idsStr, ", "))
 	if err := d.TriggerMenu(c.MenuIds); err != nil {
 		return fmt.Errorf("failed to trigger menu: %w", err)
 	}
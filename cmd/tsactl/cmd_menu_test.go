@@ -0,0 +1,16 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestMenuCmd(t *testing.T) {
+	runCliTests(t, []cliTest{
+		{
+			name:      "waterfall",
+			args:      []string{"menu", "6", "2"},
+			wantOut:   "trigger menu 6, 2\n",
+			wantCalls: []string{"TriggerMenu([6 2])", "Close()"},
+		},
+	})
+}
@@ -0,0 +1,286 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/kkettinger/go-tinysa"
+)
+
+const (
+	filenameMonitorDefault = "SA_<date>_<time>_monitor.csv"
+
+	// timestampFormat is the format of the timestamps in monitor logs
+	timestampFormat = "2006-01-02T15:04:05.000Z07:00"
+)
+
+type MonitorCmd struct {
+	Trace       []uint        `help:"Trace(s) to poll" short:"t" default:"1" group:"Monitor flags:"`
+	Interval    time.Duration `help:"Polling interval" short:"i" default:"1s" group:"Monitor flags:"`
+	Duration    time.Duration `help:"Stop after this duration, otherwise run until interrupted" short:"d" group:"Monitor flags:"`
+	Count       uint          `help:"Stop after this number of polls" short:"n" group:"Monitor flags:"`
+	Output      string        `help:"Output filepath, NDJSON for .ndjson or .jsonl, otherwise CSV" short:"o" type:"path" group:"Monitor flags:" placeholder:"PATH"`
+	RotateEvery time.Duration `help:"Start a new file after this duration" group:"Monitor flags:" placeholder:"DURATION"`
+	RotateSize  uint          `help:"Start a new file when the file exceeds this size (MB)" group:"Monitor flags:" placeholder:"MB"`
+}
+
+func (c *MonitorCmd) Run(globals *Globals) error {
+	if c.Interval <= 0 {
+		return fmt.Errorf("interval must be greater than zero")
+	}
+
+	settings, err := globals.settings()
+	if err != nil {
+		return err
+	}
+
+	output := c.Output
+	if output == "" {
+		output = filepath.Join(settings.OutputDir, settings.Filenames.Monitor)
+		if settings.OutputDir != "" {
+			if err := os.MkdirAll(settings.OutputDir, 0o755); err != nil {
+				return fmt.Errorf("failed to create output directory '%s': %w", settings.OutputDir, err)
+			}
+		}
+	}
+
+	d, err := initDevice(globals)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+
+	log := newMonitorLog(output, c.RotateEvery, int64(c.RotateSize)*1_000_000) //nolint:gosec
+	defer log.Close()
+
+	// finish the current poll on Ctrl-C, so no partial rows are written
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	var deadline <-chan time.Time
+	if c.Duration > 0 {
+		timer := time.NewTimer(c.Duration)
+		defer timer.Stop()
+		deadline = timer.C
+	}
+
+	ticker := time.NewTicker(c.Interval)
+	defer ticker.Stop()
+
+	_, _ = fmt.Fprintf(stdout, "monitoring traces %v every %s, press Ctrl-C to stop\n", c.Trace, c.Interval)
+
+	var polls uint
+loop:
+	for {
+		if err := c.poll(d, log); err != nil {
+			return err
+		}
+		polls++
+
+		if c.Count > 0 && polls >= c.Count {
+			break
+		}
+
+		select {
+		case <-ctx.Done():
+			break loop
+		case <-deadline:
+			break loop
+		case <-ticker.C:
+		}
+	}
+
+	if err := log.Close(); err != nil {
+		return err
+	}
+
+	_, _ = fmt.Fprintf(stdout, "%d polls logged to %s\n", polls, strings.Join(log.files, ", "))
+
+	return nil
+}
+
+func (c *MonitorCmd) poll(d Device, log *monitorLog) error {
+	ts := time.Now()
+
+	data := make([][]tinysa.TraceData, len(c.Trace))
+	for i, traceId := range c.Trace {
+		traceData, err := d.GetTraceData(traceId)
+		if err != nil {
+			return fmt.Errorf("failed to get trace data: %w", err)
+		}
+		data[i] = traceData
+	}
+
+	return log.write(ts, data)
+}
+
+// monitorLog appends polled trace data to CSV or NDJSON files, which are rotated by age or size.
+type monitorLog struct {
+	template string
+	ndjson   bool
+	every    time.Duration
+	maxSize  int64
+
+	file   *os.File
+	opened time.Time
+	size   int64
+	files  []string
+}
+
+func newMonitorLog(template string, every time.Duration, maxSize int64) *monitorLog {
+	ext := strings.ToLower(filepath.Ext(template))
+
+	// rotated files need distinct names
+	if (every > 0 || maxSize > 0) && !strings.Contains(template, "<time>") {
+		template = strings.TrimSuffix(template, filepath.Ext(template)) + "_<date>_<time>" + filepath.Ext(template)
+	}
+
+	return &monitorLog{
+		template: template,
+		ndjson:   ext == ".ndjson" || ext == ".jsonl",
+		every:    every,
+		maxSize:  maxSize,
+	}
+}
+
+// write appends the data of a single poll.
+func (l *monitorLog) write(ts time.Time, data [][]tinysa.TraceData) error {
+	rotate := l.file == nil ||
+		(l.every > 0 && ts.Sub(l.opened) >= l.every) ||
+		(l.maxSize > 0 && l.size >= l.maxSize)
+	if rotate {
+		if err := l.open(ts); err != nil {
+			return err
+		}
+	}
+
+	var buf bytes.Buffer
+	var err error
+	if l.ndjson {
+		err = writeNDJSON(&buf, ts, data)
+	} else {
+		err = writeCSV(&buf, ts, data, l.size == 0)
+	}
+	if err != nil {
+		return err
+	}
+
+	// the rows of a poll are written at once, so an interrupted monitor never leaves partial rows
+	n, err := l.file.Write(buf.Bytes())
+	l.size += int64(n)
+	if err != nil {
+		return fmt.Errorf("failed to write to file '%s': %w", l.file.Name(), err)
+	}
+	return nil
+}
+
+func (l *monitorLog) open(ts time.Time) error {
+	if err := l.Close(); err != nil {
+		return err
+	}
+
+	name := replaceFilenamePlaceholdersDateTime(l.template)
+
+	// don't append to a file of this run again, e.g. when rotating within a second
+	base, ext := strings.TrimSuffix(name, filepath.Ext(name)), filepath.Ext(name)
+	for i := 1; slices.Contains(l.files, name); i++ {
+		name = fmt.Sprintf("%s_%d%s", base, i, ext)
+	}
+
+	// existing files are appended to, never truncated
+	file, err := os.OpenFile(name, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to open file '%s': %w", name, err)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		_ = file.Close()
+		return fmt.Errorf("failed to open file '%s': %w", name, err)
+	}
+
+	l.file, l.opened, l.size = file, ts, info.Size()
+	l.files = append(l.files, name)
+	return nil
+}
+
+func (l *monitorLog) Close() error {
+	if l.file == nil {
+		return nil
+	}
+	err := l.file.Close()
+	l.file = nil
+	return err
+}
+
+func writeCSV(buf *bytes.Buffer, ts time.Time, data [][]tinysa.TraceData, header bool) error {
+	writer := csv.NewWriter(buf)
+
+	if header {
+		if err := writer.Write([]string{"timestamp", "trace", "point", "frequency", "value"}); err != nil {
+			return err
+		}
+	}
+
+	timestamp := ts.Format(timestampFormat)
+	for _, traceData := range data {
+		for _, dp := range traceData {
+			row := []string{
+				timestamp,
+				strconv.FormatUint(uint64(dp.Trace), 10),
+				strconv.FormatUint(uint64(dp.Point), 10),
+				strconv.FormatUint(dp.Frequency, 10),
+				strconv.FormatFloat(dp.Value, 'f', -1, 64),
+			}
+			if err := writer.Write(row); err != nil {
+				return err
+			}
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+// monitorRecord is a line of NDJSON monitor logs.
+type monitorRecord struct {
+	Timestamp   string    `json:"timestamp"`
+	Trace       uint      `json:"trace"`
+	Frequencies []uint64  `json:"frequencies"`
+	Values      []float64 `json:"values"`
+}
+
+func writeNDJSON(buf *bytes.Buffer, ts time.Time, data [][]tinysa.TraceData) error {
+	enc := json.NewEncoder(buf)
+
+	for _, traceData := range data {
+		if len(traceData) == 0 {
+			continue
+		}
+		record := monitorRecord{
+			Timestamp:   ts.Format(timestampFormat),
+			Trace:       traceData[0].Trace,
+			Frequencies: make([]uint64, len(traceData)),
+			Values:      make([]float64, len(traceData)),
+		}
+		for i, dp := range traceData {
+			record.Frequencies[i] = dp.Frequency
+			record.Values[i] = dp.Value
+		}
+		if err := enc.Encode(record); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kkettinger/go-tinysa"
+)
+
+var timestampRegex = regexp.MustCompile(`\d{4}-\d{2}-\d{2}T\d{2}:\d{2}:\d{2}\.\d{3}(Z|[+-]\d{2}:\d{2})`)
+
+func TestMonitorCmd(t *testing.T) {
+	tests := []struct {
+		name      string
+		args      []string
+		existing  string
+		wantFile  string
+		wantCalls []string
+	}{
+		{
+			name: "csv",
+			args: []string{"-t", "1,2", "-n", "2", "-i", "1ms"},
+			wantFile: "timestamp,trace,point,frequency,value\n" +
+				"<ts>,1,0,400000000,-90.25\n<ts>,1,1,450000000,-40.5\n<ts>,1,2,500000000,-89.75\n" +
+				"<ts>,2,0,400000000,-84.78\n<ts>,2,1,450000000,-35\n<ts>,2,2,500000000,-85.75\n" +
+				"<ts>,1,0,400000000,-90.25\n<ts>,1,1,450000000,-40.5\n<ts>,1,2,500000000,-89.75\n" +
+				"<ts>,2,0,400000000,-84.78\n<ts>,2,1,450000000,-35\n<ts>,2,2,500000000,-85.75\n",
+			wantCalls: []string{"GetTraceData(1)", "GetTraceData(2)", "GetTraceData(1)", "GetTraceData(2)", "Close()"},
+		},
+		{
+			name:     "csv append",
+			args:     []string{"-n", "1"},
+			existing: "timestamp,trace,point,frequency,value\n<ts>,1,0,400000000,-91\n",
+			wantFile: "timestamp,trace,point,frequency,value\n<ts>,1,0,400000000,-91\n" +
+				"<ts>,1,0,400000000,-90.25\n<ts>,1,1,450000000,-40.5\n<ts>,1,2,500000000,-89.75\n",
+			wantCalls: []string{"GetTraceData(1)", "Close()"},
+		},
+		{
+			name: "duration",
+			args: []string{"-d", "1ms", "-i", "1h"},
+			wantFile: "timestamp,trace,point,frequency,value\n" +
+				"<ts>,1,0,400000000,-90.25\n<ts>,1,1,450000000,-40.5\n<ts>,1,2,500000000,-89.75\n",
+			wantCalls: []string{"GetTraceData(1)", "Close()"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			file := filepath.Join(t.TempDir(), "monitor.csv")
+			if tt.existing != "" {
+				if err := os.WriteFile(file, []byte(tt.existing), 0o600); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			d := newFakeDevice()
+			out, err := runCli(t, d, append([]string{"monitor", "-o", file}, tt.args...)...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !strings.HasSuffix(out, "logged to "+file+"\n") {
+				t.Errorf("output = %q", out)
+			}
+
+			data, err := os.ReadFile(file)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := timestampRegex.ReplaceAllString(string(data), "<ts>"); got != tt.wantFile {
+				t.Errorf("file:\n%s\nwant:\n%s", got, tt.wantFile)
+			}
+			if got := strings.Join(d.calls, "\n"); got != strings.Join(tt.wantCalls, "\n") {
+				t.Errorf("calls:\n%s\nwant:\n%s", got, strings.Join(tt.wantCalls, "\n"))
+			}
+		})
+	}
+}
+
+func TestMonitorCmdError(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "monitor.csv")
+	d := newFakeDevice()
+	d.errs = map[string]error{"GetTraceData": errors.New("timeout")}
+
+	if _, err := runCli(t, d, "monitor", "-o", file, "-n", "3"); err == nil {
+		t.Fatal("expected error")
+	}
+	if _, err := os.Stat(file); !os.IsNotExist(err) {
+		t.Errorf("file should not be created, got %v", err)
+	}
+}
+
+func TestMonitorLogNDJSON(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "monitor.ndjson")
+	ts := time.Date(2025, 4, 7, 20, 28, 15, 0, time.UTC)
+
+	log := newMonitorLog(file, 0, 0)
+	data := [][]tinysa.TraceData{newFakeDevice().traceData[2]}
+	if err := log.write(ts, data); err != nil {
+		t.Fatal(err)
+	}
+	if err := log.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"timestamp":"2025-04-07T20:28:15.000Z","trace":2,"frequencies":[400000000,450000000,500000000],"values":[-84.78,-35,-85.75]}` + "\n"
+	if string(got) != want {
+		t.Errorf("file = %s, want %s", got, want)
+	}
+}
+
+func TestMonitorLogRotation(t *testing.T) {
+	dir := t.TempDir()
+	ts := time.Date(2025, 4, 7, 20, 28, 15, 0, time.UTC)
+	data := [][]tinysa.TraceData{newFakeDevice().traceData[1]}
+
+	// by age, the file name gets a timestamp and a counter within the same second
+	log := newMonitorLog(filepath.Join(dir, "survey.csv"), time.Minute, 0)
+	for _, offset := range []time.Duration{0, 30 * time.Second, time.Minute, 2 * time.Minute} {
+		if err := log.write(ts.Add(offset), data); err != nil {
+			t.Fatal(err)
+		}
+	}
+	_ = log.Close()
+
+	if len(log.files) != 3 {
+		t.Fatalf("files = %v, want 3 files", log.files)
+	}
+	pattern := regexp.MustCompile(`survey_\d{6}_\d{6}(_\d)?\.csv$`)
+	for _, f := range log.files {
+		if !pattern.MatchString(f) {
+			t.Errorf("file name %s doesn't match %s", f, pattern)
+		}
+		content, err := os.ReadFile(f)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.HasPrefix(content, []byte("timestamp,")) {
+			t.Errorf("file %s has no header", f)
+		}
+	}
+
+	// by size, every write exceeds the limit
+	log = newMonitorLog(filepath.Join(dir, "size_<date>_<time>.csv"), 0, 10)
+	for range 3 {
+		if err := log.write(ts, data); err != nil {
+			t.Fatal(err)
+		}
+	}
+	_ = log.Close()
+
+	if len(log.files) != 3 {
+		t.Errorf("files = %v, want 3 files", log.files)
+	}
+}
@@ -0,0 +1,211 @@
+package main
+
+import (
+	"cmp"
+	"fmt"
+	"math"
+	"slices"
+	"text/tabwriter"
+
+	"github.com/kkettinger/go-tinysa"
+	"github.com/kkettinger/tsactl/internal/util"
+)
+
+// maxMarkers is the number of markers of the device.
+const maxMarkers = 4
+
+type PeaksCmd struct {
+	Trace         uint      `help:"Trace to search" short:"t" default:"1" group:"Peaks flags:"`
+	Threshold     *float64  `help:"Minimum level of a peak, in dBm for the linear units W, V and Vpp" short:"l" group:"Peaks flags:" placeholder:"LEVEL"`
+	Max           int       `help:"Maximum number of peaks" short:"n" default:"10" group:"Peaks flags:"`
+	MinSeparation Frequency `help:"Minimum frequency distance between peaks" name:"min-separation" short:"s" group:"Peaks flags:" placeholder:"FREQ"`
+	Excursion     float64   `help:"Minimum rise of a peak above the trace between it and higher peaks, in dB" short:"e" default:"3" group:"Peaks flags:"`
+	Markers       bool      `help:"Place markers 1-4 on the highest peaks" short:"m" group:"Peaks flags:"`
+}
+
+type peakInfo struct {
+	Rank      int     `json:"rank" yaml:"rank"`
+	Frequency uint64  `json:"frequency" yaml:"frequency"`
+	Value     float64 `json:"value" yaml:"value"`
+	Width     uint64  `json:"width" yaml:"width"`
+	Index     uint    `json:"index" yaml:"index"`
+}
+
+func (c *PeaksCmd) Run(globals *Globals) error {
+	if c.Max < 1 {
+		return fmt.Errorf("max must be at least 1")
+	}
+	if c.Excursion < 0 {
+		return fmt.Errorf("excursion must not be negative")
+	}
+
+	d, err := initDevice(globals)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+
+	trace, err := d.GetTrace(c.Trace)
+	if err != nil {
+		return fmt.Errorf("failed to get trace: %w", err)
+	}
+	data, err := d.GetTraceData(c.Trace)
+	if err != nil {
+		return fmt.Errorf("failed to get trace data: %w", err)
+	}
+
+	// levels are compared in dB, so linear units are searched and reported in dBm
+	unit := trace.Unit.String()
+	if isLinearUnit(trace.Unit) {
+		data = slices.Clone(data)
+		for i, dp := range data {
+			if data[i].Value, err = toDBm(dp.Value, trace.Unit); err != nil {
+				return err
+			}
+		}
+		unit = tinysa.TraceUnitDBm.String()
+	}
+
+	peaks := findPeaks(data, c.Threshold, c.Excursion, c.MinSeparation.Value)
+	peaks = peaks[:min(len(peaks), c.Max)]
+
+	if err := c.printPeaks(globals.Format, unit, peaks); err != nil {
+		return err
+	}
+
+	if c.Markers {
+		for i, p := range peaks[:min(len(peaks), maxMarkers)] {
+			if err := c.placeMarker(d, uint(i+1), p, globals.Format == formatText); err != nil { // #nosec G115
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func (c *PeaksCmd) printPeaks(format, unit string, peaks []peakInfo) error {
+	if format != formatText {
+		return printStructured(format, peaks)
+	}
+
+	if len(peaks) == 0 {
+		_, _ = fmt.Fprintf(stdout, "no peaks found in trace %d\n", c.Trace)
+		return nil
+	}
+	w := tabwriter.NewWriter(stdout, 0, 0, 3, ' ', 0)
+	_, _ = fmt.Fprintln(w, "Rank\tFrequency\tLevel\tWidth")
+	for _, p := range peaks {
+		_, _ = fmt.Fprintf(w, "%d\t%s\t%g %s\t%s\n", p.Rank, util.FormatFrequency(p.Frequency), p.Value, unit, util.FormatFrequency(p.Width))
+	}
+	return w.Flush()
+}
+
+func (c *PeaksCmd) placeMarker(d Device, marker uint, p peakInfo, verbose bool) error {
+	if verbose {
+		_, _ = fmt.Fprintf(stdout, "set marker #%d to peak %d at %s\n", marker, p.Rank, util.FormatFrequency(p.Frequency))
+	}
+	if err := d.EnableMarker(marker); err != nil {
+		return fmt.Errorf("failed to enable marker #%d: %w", marker, err)
+	}
+	if err := d.SetMarkerTrace(marker, c.Trace); err != nil {
+		return fmt.Errorf("failed to assign marker #%d to trace #%d: %w", marker, c.Trace, err)
+	}
+	// a tracking marker would jump back to the highest peak
+	if err := d.DisableMarkerTracking(marker); err != nil {
+		return fmt.Errorf("failed to disable tracking for marker #%d: %w", marker, err)
+	}
+	if err := d.SetMarkerFreq(marker, p.Frequency); err != nil {
+		return fmt.Errorf("failed to set marker #%d to frequency %d: %w", marker, p.Frequency, err)
+	}
+	return nil
+}
+
+// findPeaks returns the local maxima of the trace ranked by level. A peak must be at least threshold high,
+// rise at least excursion dB above the lowest point between it and the next higher point on both sides
+// and be at least separation Hz away from higher peaks. Points at the sweep edges are not considered,
+// as the actual maximum could be outside of the sweep.
+func findPeaks(data []tinysa.TraceData, threshold *float64, excursion float64, separation uint64) []peakInfo {
+	var candidates []int
+	for i := 1; i < len(data)-1; i++ {
+		v := data[i].Value
+		// the first point of a plateau is the peak
+		if v <= data[i-1].Value || v < data[i+1].Value {
+			continue
+		}
+		if threshold != nil && v < *threshold {
+			continue
+		}
+		if prominence(data, i) < excursion {
+			continue
+		}
+		candidates = append(candidates, i)
+	}
+
+	slices.SortStableFunc(candidates, func(a, b int) int {
+		return cmp.Compare(data[b].Value, data[a].Value)
+	})
+
+	peaks := []peakInfo{}
+	for _, i := range candidates {
+		tooClose := slices.ContainsFunc(peaks, func(p peakInfo) bool {
+			return absDiff(p.Frequency, data[i].Frequency) < separation
+		})
+		if tooClose {
+			continue
+		}
+		peaks = append(peaks, peakInfo{
+			Rank:      len(peaks) + 1,
+			Frequency: data[i].Frequency,
+			Value:     data[i].Value,
+			Width:     peakWidth(data, i),
+			Index:     data[i].Point,
+		})
+	}
+	return peaks
+}
+
+// prominence returns how far the point i rises above the higher of the two minimums between it and
+// the next higher point (or the sweep edge) on each side.
+func prominence(data []tinysa.TraceData, i int) float64 {
+	v := data[i].Value
+	left, right := v, v
+	for j := i - 1; j >= 0 && data[j].Value <= v; j-- {
+		left = min(left, data[j].Value)
+	}
+	for j := i + 1; j < len(data) && data[j].Value <= v; j++ {
+		right = min(right, data[j].Value)
+	}
+	return v - max(left, right)
+}
+
+// peakWidth returns the -3 dB width of the peak at point i, interpolated between the points.
+// The width is limited by the sweep edges.
+func peakWidth(data []tinysa.TraceData, i int) uint64 {
+	level := data[i].Value - 3
+
+	lo := float64(data[0].Frequency)
+	for j := i; j > 0; j-- {
+		if data[j-1].Value <= level {
+			lo = interpolateFrequency(data[j-1], data[j], level)
+			break
+		}
+	}
+	hi := float64(data[len(data)-1].Frequency)
+	for j := i; j < len(data)-1; j++ {
+		if data[j+1].Value <= level {
+			hi = interpolateFrequency(data[j], data[j+1], level)
+			break
+		}
+	}
+	return uint64(math.Round(hi - lo))
+}
+
+// interpolateFrequency returns the frequency between the points a and b, where the trace crosses level.
+func interpolateFrequency(a, b tinysa.TraceData, level float64) float64 {
+	if a.Value == b.Value {
+		return float64(a.Frequency)
+	}
+	t := (level - a.Value) / (b.Value - a.Value)
+	return float64(a.Frequency) + t*(float64(b.Frequency)-float64(a.Frequency))
+}
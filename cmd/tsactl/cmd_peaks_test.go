@@ -0,0 +1,129 @@
+package main
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/kkettinger/go-tinysa"
+)
+
+func TestPeaksCmd(t *testing.T) {
+	runCliTests(t, []cliTest{
+		{
+			name: "text",
+			args: []string{"peaks"},
+			wantOut: "Rank   Frequency   Level       Width\n" +
+				"1      450 MHz     -40.5 dBm   6.060761 MHz\n",
+			wantCalls: []string{"GetTrace(1)", "GetTraceData(1)", "Close()"},
+		},
+		{
+			name:      "below threshold",
+			args:      []string{"peaks", "--trace", "2", "--threshold", "-30"},
+			wantOut:   "no peaks found in trace 2\n",
+			wantCalls: []string{"GetTrace(2)", "GetTraceData(2)", "Close()"},
+		},
+		{
+			name:      "csv",
+			args:      []string{"-F", "csv", "peaks", "-t", "2"},
+			wantOut:   "rank,frequency,value,width,index\n1,450000000,-35,5968923,1\n",
+			wantCalls: []string{"GetTrace(2)", "GetTraceData(2)", "Close()"},
+		},
+		{
+			name:      "json without peaks",
+			args:      []string{"-F", "json", "peaks", "-l", "0"},
+			wantOut:   "[]\n",
+			wantCalls: []string{"GetTrace(1)", "GetTraceData(1)", "Close()"},
+		},
+		{
+			name: "markers",
+			args: []string{"peaks", "--markers"},
+			wantOut: "Rank   Frequency   Level       Width\n" +
+				"1      450 MHz     -40.5 dBm   6.060761 MHz\n" +
+				"set marker #1 to peak 1 at 450 MHz\n",
+			wantCalls: []string{
+				"GetTrace(1)", "GetTraceData(1)",
+				"EnableMarker(1)", "SetMarkerTrace(1, 1)", "DisableMarkerTracking(1)", "SetMarkerFreq(1, 450000000)",
+				"Close()",
+			},
+		},
+		{
+			name: "linear unit in dBm",
+			args: []string{"peaks", "--threshold", "-45", "--excursion", "40"},
+			setup: func(d *fakeDevice) {
+				d.traces[0].Unit = tinysa.TraceUnitW
+				d.traceData[1] = []tinysa.TraceData{
+					{Trace: 1, Point: 0, Frequency: 400_000_000, Value: 1e-12},
+					{Trace: 1, Point: 1, Frequency: 450_000_000, Value: 1e-7},
+					{Trace: 1, Point: 2, Frequency: 500_000_000, Value: 1e-12},
+				}
+			},
+			wantOut: "Rank   Frequency   Level     Width\n" +
+				"1      450 MHz     -40 dBm   6 MHz\n",
+			wantCalls: []string{"GetTrace(1)", "GetTraceData(1)", "Close()"},
+		},
+		{
+			name:    "invalid max",
+			args:    []string{"peaks", "-n", "0"},
+			wantErr: true,
+		},
+	})
+}
+
+func TestFindPeaks(t *testing.T) {
+	trace := func(values ...float64) []tinysa.TraceData {
+		data := make([]tinysa.TraceData, len(values))
+		for i, v := range values {
+			data[i] = tinysa.TraceData{Point: uint(i), Frequency: uint64(i) * 100_000, Value: v} // #nosec G115
+		}
+		return data
+	}
+	level := func(v float64) *float64 { return &v }
+
+	tests := []struct {
+		name       string
+		data       []tinysa.TraceData
+		threshold  *float64
+		excursion  float64
+		separation uint64
+		want       []uint64
+	}{
+		{"ranked by level", trace(-90, -50, -90, -30, -90, -70, -90), nil, 3, 0, []uint64{300_000, 100_000, 500_000}},
+		{"threshold", trace(-90, -50, -90, -30, -90, -70, -90), level(-60), 3, 0, []uint64{300_000, 100_000}},
+		{"separation", trace(-90, -50, -90, -30, -90, -70, -90), nil, 3, 250_000, []uint64{300_000}},
+		{"excursion", trace(-90, -50, -52, -30, -90), nil, 3, 0, []uint64{300_000}},
+		{"plateau", trace(-90, -40, -40, -40, -90), nil, 3, 0, []uint64{100_000}},
+		{"edges", trace(-30, -90, -60, -90, -30), nil, 3, 0, []uint64{200_000}},
+		{"flat", trace(-90, -90, -90), nil, 0, 0, []uint64{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			peaks := findPeaks(tt.data, tt.threshold, tt.excursion, tt.separation)
+			got := make([]uint64, len(peaks))
+			for i, p := range peaks {
+				got[i] = p.Frequency
+				if p.Rank != i+1 {
+					t.Errorf("rank = %d, want %d", p.Rank, i+1)
+				}
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("peaks = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPeakWidth(t *testing.T) {
+	data := []tinysa.TraceData{
+		{Frequency: 100, Value: -90}, {Frequency: 200, Value: -43}, {Frequency: 300, Value: -40},
+		{Frequency: 400, Value: -42}, {Frequency: 500, Value: -46},
+	}
+	// -43 is crossed at 200, -43 between 400 and 500 at 425
+	if got := peakWidth(data, 2); got != 225 {
+		t.Errorf("width = %d, want 225", got)
+	}
+	// limited by the sweep edge
+	if got := peakWidth(data[1:4], 1); got != 200 {
+		t.Errorf("width = %d, want 200", got)
+	}
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"fmt"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/kkettinger/tsactl/internal/util"
+)
+
+type PlotCmd struct {
+	Files  []string `arg:"" help:"Trace CSV file(s) written by the save command" type:"existingfile"`
+	Output string   `help:"Output filepath, the format is chosen by the extension (.svg or .png)" short:"o" required:"" type:"path" group:"Plot flags:" placeholder:"PATH"`
+	Title  string   `help:"Title of the chart" group:"Plot flags:"`
+	Unit   string   `help:"Unit of the values in the CSV files" short:"u" default:"dBm" group:"Plot flags:"`
+	Marker []string `help:"Annotate the level of each trace at this frequency, or at its peak with 'peak'" short:"m" group:"Plot flags:" placeholder:"FREQ"`
+	Min    *float64 `help:"Lower end of the level axis, otherwise fitted to the data" group:"Plot flags:"`
+	Max    *float64 `help:"Upper end of the level axis, otherwise fitted to the data" group:"Plot flags:"`
+	Width  int      `help:"Width of the chart in pixels" default:"800" group:"Plot flags:"`
+	Height int      `help:"Height of the chart in pixels" default:"450" group:"Plot flags:"`
+}
+
+func (c *PlotCmd) Run() error {
+	format := strings.ToLower(filepath.Ext(c.Output))
+	if format != ".svg" && format != ".png" {
+		return fmt.Errorf("unsupported output format '%s', must be .svg or .png", format)
+	}
+	if c.Min != nil && c.Max != nil && *c.Min >= *c.Max {
+		return fmt.Errorf("min must be lower than max")
+	}
+	if c.Width < 320 || c.Height < 200 {
+		return fmt.Errorf("chart size must be at least 320x200")
+	}
+
+	ch := &chart{title: c.Title, unit: c.Unit, width: c.Width, height: c.Height, min: c.Min, max: c.Max}
+	for _, path := range c.Files {
+		series, err := readTraceCSV(path)
+		if err != nil {
+			return err
+		}
+		for _, s := range series {
+			name := fmt.Sprintf("trace %d", s.trace)
+			if len(c.Files) > 1 {
+				name = filepath.Base(path) + " " + name
+			}
+			ch.series = append(ch.series, chartSeries{name: name, frequencies: s.frequencies, values: s.values})
+		}
+	}
+
+	for _, marker := range c.Marker {
+		annotations, err := annotateChart(ch.series, marker)
+		if err != nil {
+			return err
+		}
+		ch.annotations = append(ch.annotations, annotations...)
+	}
+
+	file, err := os.Create(c.Output)
+	if err != nil {
+		return fmt.Errorf("failed to open file '%s': %w", c.Output, err)
+	}
+	defer file.Close()
+
+	if format == ".png" {
+		cv := newPngCanvas(c.Width, c.Height)
+		ch.draw(cv)
+		if err := png.Encode(file, cv.img); err != nil {
+			return fmt.Errorf("failed to encode file '%s': %w", c.Output, err)
+		}
+	} else {
+		cv := newSvgCanvas(c.Width, c.Height)
+		ch.draw(cv)
+		if err := cv.writeTo(file); err != nil {
+			return fmt.Errorf("failed to write file '%s': %w", c.Output, err)
+		}
+	}
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("failed to write file '%s': %w", c.Output, err)
+	}
+
+	_, _ = fmt.Fprintf(stdout, "plot of %d traces saved to %s\n", len(ch.series), c.Output)
+
+	return nil
+}
+
+// annotateChart returns the annotations of a marker, which is either 'peak' for the highest level of
+// each series or a frequency for the nearest point of each series covering it.
+func annotateChart(series []chartSeries, marker string) ([]chartAnnotation, error) {
+	var annotations []chartAnnotation
+
+	if marker == "peak" {
+		for i, s := range series {
+			peak := 0
+			for j, v := range s.values {
+				if v > s.values[peak] {
+					peak = j
+				}
+			}
+			annotations = append(annotations, chartAnnotation{series: i, point: peak})
+		}
+		return annotations, nil
+	}
+
+	freq, err := util.ParseFrequency(marker)
+	if err != nil {
+		return nil, fmt.Errorf("invalid marker '%s': %w", marker, err)
+	}
+	for i, s := range series {
+		if freq < s.frequencies[0] || freq > s.frequencies[len(s.frequencies)-1] {
+			continue
+		}
+		nearest := 0
+		for j, f := range s.frequencies {
+			if absDiff(f, freq) < absDiff(s.frequencies[nearest], freq) {
+				nearest = j
+			}
+		}
+		annotations = append(annotations, chartAnnotation{series: i, point: nearest})
+	}
+	if len(annotations) == 0 {
+		return nil, fmt.Errorf("marker frequency %s is outside of all traces", util.FormatFrequency(freq))
+	}
+	return annotations, nil
+}
+
+func absDiff(a, b uint64) uint64 {
+	if a > b {
+		return a - b
+	}
+	return b - a
+}
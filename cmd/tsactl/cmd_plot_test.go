@@ -0,0 +1,194 @@
+package main
+
+import (
+	"image/png"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+const (
+	plotSingleCSV = "trace,point,frequency,value\n1,0,400000000,-90.25\n1,1,450000000,-40.5\n1,2,500000000,-89.75\n"
+	plotMultiCSV  = "point,frequency,value_t1,value_t3\n0,400000000,-90.25,-84.78\n1,450000000,-40.5,-35\n2,500000000,-89.75,-85.75\n"
+)
+
+func writePlotFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestReadTraceCSV(t *testing.T) {
+	freqs := []uint64{400_000_000, 450_000_000, 500_000_000}
+
+	tests := []struct {
+		name    string
+		content string
+		want    []traceSeries
+		wantErr string
+	}{
+		{"single trace", plotSingleCSV, []traceSeries{
+			{trace: 1, frequencies: freqs, values: []float64{-90.25, -40.5, -89.75}},
+		}, ""},
+		{"multiple traces", plotMultiCSV, []traceSeries{
+			{trace: 1, frequencies: freqs, values: []float64{-90.25, -40.5, -89.75}},
+			{trace: 3, frequencies: freqs, values: []float64{-84.78, -35, -85.75}},
+		}, ""},
+		{"stddev columns", "point,frequency,value_t1,stddev_t1\n0,400000000,-90.25,0.5\n", []traceSeries{
+			{trace: 1, frequencies: freqs[:1], values: []float64{-90.25}},
+		}, ""},
+		{"header only", "trace,point,frequency,value\n", nil, "contains no trace data"},
+		{"unknown layout", "timestamp,value\n0,1\n", nil, "has an unknown layout"},
+		{"invalid column", "point,frequency,level\n0,400000000,-90\n", nil, "invalid column 'level'"},
+		{"invalid value", "trace,point,frequency,value\n1,0,400000000,high\n", nil, "line 2: invalid value 'high'"},
+		{"invalid frequency", "point,frequency,value_t1\n0,-1,-90\n", nil, "line 2: invalid frequency '-1'"},
+		{"wrong number of fields", "point,frequency,value_t1\n0,400000000\n", nil, "failed to read file"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := readTraceCSV(writePlotFile(t, "trace.csv", tt.content))
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("series = %d, want %d", len(got), len(tt.want))
+			}
+			for i := range got {
+				if got[i].trace != tt.want[i].trace || !slices.Equal(got[i].frequencies, tt.want[i].frequencies) ||
+					!slices.Equal(got[i].values, tt.want[i].values) {
+					t.Errorf("series %d = %+v, want %+v", i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestPlotCmdSVG(t *testing.T) {
+	single := writePlotFile(t, "single.csv", plotSingleCSV)
+	multi := writePlotFile(t, "multi.csv", plotMultiCSV)
+	output := filepath.Join(t.TempDir(), "chart.svg")
+
+	d := newFakeDevice()
+	out, err := runCli(t, d, "plot", single, multi, "-o", output, "--title", "Filter <A&B>", "-m", "peak", "-m", "410M")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "plot of 3 traces saved to " + output + "\n"; out != want {
+		t.Errorf("output = %q, want %q", out, want)
+	}
+	if len(d.calls) != 0 {
+		t.Errorf("plot must not access the device, got calls %v", d.calls)
+	}
+
+	data, err := os.ReadFile(output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	svg := string(data)
+	for _, want := range []string{
+		`<svg xmlns="http://www.w3.org/2000/svg" width="800" height="450"`,
+		">Filter &lt;A&amp;B&gt;</text>",
+		">Level (dBm)</text>",
+		">Frequency</text>",
+		">400 MHz</text>", ">500 MHz</text>",
+		">single.csv trace 1</text>", ">multi.csv trace 1</text>", ">multi.csv trace 3</text>",
+		// peak of each trace, the frequency marker annotates the nearest point
+		">450 MHz -40.5 dBm</text>", ">450 MHz -35 dBm</text>",
+		">400 MHz -90.25 dBm</text>", ">400 MHz -84.78 dBm</text>",
+		"</svg>\n",
+	} {
+		if !strings.Contains(svg, want) {
+			t.Errorf("svg does not contain %q", want)
+		}
+	}
+	if n := strings.Count(svg, "<circle"); n != 6 {
+		t.Errorf("annotations = %d, want 6", n)
+	}
+}
+
+func TestPlotCmdPNG(t *testing.T) {
+	input := writePlotFile(t, "trace.csv", plotSingleCSV)
+	output := filepath.Join(t.TempDir(), "chart.PNG")
+
+	if _, err := runCli(t, newFakeDevice(), "plot", input, "-o", output, "--width", "640", "--height", "360", "--unit", "dBuV"); err != nil {
+		t.Fatal(err)
+	}
+
+	file, err := os.Open(output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	img, err := png.Decode(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if img.Bounds().Dx() != 640 || img.Bounds().Dy() != 360 {
+		t.Errorf("image size = %v", img.Bounds())
+	}
+}
+
+func TestPlotCmdError(t *testing.T) {
+	input := writePlotFile(t, "trace.csv", plotSingleCSV)
+	dir := t.TempDir()
+
+	tests := []struct {
+		name    string
+		args    []string
+		wantErr string
+	}{
+		{"format", []string{"-o", filepath.Join(dir, "chart.jpg")}, "unsupported output format '.jpg', must be .svg or .png"},
+		{"range", []string{"-o", filepath.Join(dir, "chart.svg"), "--min", "-20", "--max", "-80"}, "min must be lower than max"},
+		{"size", []string{"-o", filepath.Join(dir, "chart.svg"), "--width", "100"}, "chart size must be at least 320x200"},
+		{"marker", []string{"-o", filepath.Join(dir, "chart.svg"), "-m", "1G"}, "marker frequency 1 GHz is outside of all traces"},
+		{"invalid marker", []string{"-o", filepath.Join(dir, "chart.svg"), "-m", "top"}, "invalid marker 'top'"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := runCli(t, newFakeDevice(), append([]string{"plot", input}, tt.args...)...)
+			if err == nil || !strings.HasPrefix(err.Error(), tt.wantErr) {
+				t.Errorf("error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 0 {
+		t.Errorf("no chart should be written, got %v", entries)
+	}
+}
+
+func TestChartLevelRange(t *testing.T) {
+	lo, hi := -100.0, -20.0
+	series := []chartSeries{{values: []float64{-87.5, -42}}}
+
+	tests := []struct {
+		name             string
+		chart            chart
+		wantMin, wantMax float64
+	}{
+		{"fitted", chart{series: series}, -90, -40},
+		{"fixed", chart{series: series, min: &lo, max: &hi}, -100, -20},
+		{"fixed min above data", chart{series: series, min: &hi}, -20, -10},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotMin, gotMax := tt.chart.levelRange()
+			if gotMin != tt.wantMin || gotMax != tt.wantMax {
+				t.Errorf("range = %v..%v, want %v..%v", gotMin, gotMax, tt.wantMin, tt.wantMax)
+			}
+		})
+	}
+}
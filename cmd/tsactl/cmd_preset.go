@@ -3,7 +3,6 @@ package main
 import (
 	"fmt"
 	"github.com/alecthomas/kong"
-	"github.com/kkettinger/go-tinysa"
 )
 
 type PresetCmd struct {
@@ -21,7 +20,7 @@ func (c *PresetCmd) Validate() error {
 
 func (c *PresetCmd) Run(globals *Globals, ctx *kong.Context) error {
 
-	var ops []func(d *tinysa.Device) error
+	var ops []func(d Device) error
 
 	if c.Load != nil {
 		ops = append(ops, c.LoadPreset)
@@ -36,6 +35,7 @@ func (c *PresetCmd) Run(globals *Globals, ctx *kong.Context) error {
 		if err != nil {
 			return err
 		}
+		defer d.Close()
 
 		for _, op := range ops {
 			if err := op(d); err != nil {
@@ -50,16 +50,16 @@ func (c *PresetCmd) Run(globals *Globals, ctx *kong.Context) error {
 	return nil
 }
 
-func (c *PresetCmd) LoadPreset(d *tinysa.Device) error {
-	fmt.Printf("load preset %d\n", *c.Load)
+func (c *PresetCmd) LoadPreset(d Device) error {
+	_, _ = fmt.Fprintf(stdout, "load preset %d\n", *c.Load)
 	if err := d.LoadPreset(*c.Load); err != nil {
 		return fmt.Errorf("failed to load preset %d: %w", *c.Load, err)
 	}
 	return nil
 }
 
-func (c *PresetCmd) SavePreset(d *tinysa.Device) error {
-	fmt.Printf("save preset %d\n", *c.Save)
+func (c *PresetCmd) SavePreset(d Device) error {
+	_, _ = fmt.Fprintf(stdout, "save preset %d\n", *c.Save)
 	if err := d.SavePreset(*c.Save); err != nil {
 		return fmt.Errorf("failed to save preset %d: %w", *c.Save, err)
 	}
@@ -0,0 +1,27 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestPresetCmd(t *testing.T) {
+	runCliTests(t, []cliTest{
+		{
+			name:      "load",
+			args:      []string{"preset", "--load", "1"},
+			wantOut:   "load preset 1\n",
+			wantCalls: []string{"LoadPreset(1)", "Close()"},
+		},
+		{
+			name:      "save startup",
+			args:      []string{"preset", "-s", "0"},
+			wantOut:   "save preset 0\n",
+			wantCalls: []string{"SavePreset(0)", "Close()"},
+		},
+		{
+			name:    "load and save",
+			args:    []string{"preset", "-l", "1", "-s", "2"},
+			wantErr: true,
+		},
+	})
+}
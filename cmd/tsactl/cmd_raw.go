@@ -19,6 +19,7 @@ func (c *RawCmd) Run(globals *Globals, ctx *kong.Context) error {
 	if err != nil {
 		return err
 	}
+	defer d.Close()
 
 	result, err := d.SendCommand(strings.Join(c.Command, " "))
 	if err != nil {
@@ -26,9 +27,9 @@ func (c *RawCmd) Run(globals *Globals, ctx *kong.Context) error {
 	}
 
 	if containsBinary(result) || len(result) == 0 {
-		fmt.Print(result)
+		_, _ = fmt.Fprint(stdout, result)
 	} else {
-		fmt.Println(result)
+		_, _ = fmt.Fprintln(stdout, result)
 	}
 
 	return nil
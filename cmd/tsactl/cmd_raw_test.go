@@ -0,0 +1,30 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestRawCmd(t *testing.T) {
+	runCliTests(t, []cliTest{
+		{
+			name:      "text",
+			args:      []string{"raw", "sd_list"},
+			setup:     func(d *fakeDevice) { d.responses["sd_list"] = "DECT.prs 1584\r\nWIFI.prs 1584" },
+			wantOut:   "DECT.prs 1584\r\nWIFI.prs 1584\n",
+			wantCalls: []string{"SendCommand(sd_list)", "Close()"},
+		},
+		{
+			name:      "arguments",
+			args:      []string{"raw", "sweep", "120M", "150M"},
+			wantOut:   "",
+			wantCalls: []string{"SendCommand(sweep 120M 150M)", "Close()"},
+		},
+		{
+			name:      "binary",
+			args:      []string{"raw", "capture"},
+			setup:     func(d *fakeDevice) { d.responses["capture"] = "\x00\x01\x02" },
+			wantOut:   "\x00\x01\x02",
+			wantCalls: []string{"SendCommand(capture)", "Close()"},
+		},
+	})
+}
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/kkettinger/tsactl/internal/util"
+)
+
+type RunCmd struct {
+	File            string            `help:"Script file with one command per line" arg:"" type:"existingfile"`
+	ContinueOnError bool              `help:"Continue with the next line when a command fails" short:"k" group:"Run flags:"`
+	Set             map[string]string `help:"Set script variable, e.g. --set board=2" short:"s" group:"Run flags:" placeholder:"NAME=VALUE"`
+}
+
+var varNameRegex = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// scriptError is the error of a single script line.
+type scriptError struct {
+	line int
+	err  error
+}
+
+func (c *RunCmd) Run(globals *Globals) error {
+	data, err := os.ReadFile(c.File)
+	if err != nil {
+		return fmt.Errorf("failed to read script: %w", err)
+	}
+
+	d, err := initDevice(globals)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+
+	globals.device = sessionDevice{d}
+	globals.script = true
+	// inside a shell, the wait directive reads the input of the shell, which is buffered already
+	if globals.readLine == nil {
+		globals.readLine = newLineReader(stdin)
+	}
+
+	vars := map[string]string{}
+	for name, value := range c.Set {
+		vars[name] = value
+	}
+
+	var failed []scriptError
+	executed := 0
+
+	for i, line := range strings.Split(string(data), "\n") {
+		lineNo := i + 1
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		line, err = expandVars(line, vars)
+		if err == nil {
+			_, _ = fmt.Fprintf(stdout, "[%d] %s\n", lineNo, line)
+			executed++
+			err = c.execScriptLine(globals, line, vars)
+		}
+
+		if err != nil {
+			_, _ = fmt.Fprintf(stdout, "[%d] error: %v\n", lineNo, err)
+			failed = append(failed, scriptError{line: lineNo, err: err})
+			if !c.ContinueOnError {
+				break
+			}
+		}
+	}
+
+	_, _ = fmt.Fprintf(stdout, "%d lines executed, %d failed\n", executed, len(failed))
+
+	switch len(failed) {
+	case 0:
+		return nil
+	case 1:
+		return fmt.Errorf("script failed at line %d: %w", failed[0].line, failed[0].err)
+	default:
+		lines := make([]string, len(failed))
+		for i, f := range failed {
+			lines[i] = fmt.Sprint(f.line)
+		}
+		return fmt.Errorf("script failed at lines %s", strings.Join(lines, ", "))
+	}
+}
+
+// execScriptLine runs a directive or tsactl command of the script.
+func (c *RunCmd) execScriptLine(globals *Globals, line string, vars map[string]string) error {
+	args, err := splitArgs(line)
+	if err != nil {
+		return err
+	}
+
+	switch args[0] {
+	case "set":
+		// set NAME VALUE or set NAME=VALUE
+		if len(args) == 2 {
+			args = append([]string{"set"}, strings.SplitN(args[1], "=", 2)...)
+		}
+		if len(args) != 3 || !varNameRegex.MatchString(args[1]) {
+			return fmt.Errorf("invalid set directive, expected 'set NAME VALUE'")
+		}
+		// variables given with --set take precedence over the script defaults
+		if _, ok := c.Set[args[1]]; !ok {
+			vars[args[1]] = args[2]
+		}
+		return nil
+
+	case "sleep":
+		if len(args) != 2 {
+			return fmt.Errorf("invalid sleep directive, expected 'sleep DURATION'")
+		}
+		us, err := util.ParseTimeDuration(args[1])
+		if err != nil {
+			return err
+		}
+		time.Sleep(time.Duration(us) * time.Microsecond) //nolint:gosec
+		return nil
+
+	case "wait":
+		msg := "Press enter to continue"
+		if len(args) > 1 {
+			msg = strings.Join(args[1:], " ")
+		}
+		_, _ = fmt.Fprintln(stdout, msg)
+		if _, err := globals.readLine(); err != nil {
+			return fmt.Errorf("failed to wait for input: %w", err)
+		}
+		return nil
+
+	default:
+		return execArgs(globals, args)
+	}
+}
+
+// expandVars replaces $NAME and ${NAME} in line with the script variables or environment variables.
+func expandVars(line string, vars map[string]string) (string, error) {
+	var missing []string
+	expanded := os.Expand(line, func(name string) string {
+		if value, ok := vars[name]; ok {
+			return value
+		}
+		if value, ok := os.LookupEnv(name); ok {
+			return value
+		}
+		missing = append(missing, name)
+		return ""
+	})
+	if len(missing) > 0 {
+		return "", fmt.Errorf("undefined variable '%s'", missing[0])
+	}
+	return expanded, nil
+}
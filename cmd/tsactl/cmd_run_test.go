@@ -0,0 +1,201 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRunCmd(t *testing.T) {
+	tests := []struct {
+		name      string
+		script    string
+		args      []string
+		input     string
+		setup     func(d *fakeDevice)
+		wantOut   string
+		wantCalls []string
+		wantErr   bool
+	}{
+		{
+			name: "commands, comments and variables",
+			script: "# board test\n" +
+				"set center 433.92mhz\n" +
+				"\n" +
+				"sweep --center ${center} --span $span\n" +
+				"sleep 1ms\n" +
+				"marker 1 --peak\n",
+			args: []string{"--set", "span=2mhz"},
+			wantOut: "[2] set center 433.92mhz\n" +
+				"[4] sweep --center 433.92mhz --span 2mhz\n" +
+				"set sweep span frequency to 2 MHz\n" +
+				"set sweep center frequency to 433.92 MHz\n" +
+				"[5] sleep 1ms\n" +
+				"[6] marker 1 --peak\n" +
+				"set marker #1 to peak\n" +
+				"4 lines executed, 0 failed\n",
+			wantCalls: []string{
+				"SetSweepSpan(2000000)",
+				"SetSweepCenter(433920000)",
+				"MoveMarkerPeak(1)",
+				"Close()",
+			},
+		},
+		{
+			name:   "set flag overrides script",
+			script: "set span=5mhz\nsweep --span ${span}\n",
+			args:   []string{"-s", "span=1mhz"},
+			wantOut: "[1] set span=5mhz\n" +
+				"[2] sweep --span 1mhz\n" +
+				"set sweep span frequency to 1 MHz\n" +
+				"2 lines executed, 0 failed\n",
+			wantCalls: []string{"SetSweepSpan(1000000)", "Close()"},
+		},
+		{
+			name:   "stop on error",
+			script: "sweep -p\nmarker 1 --peak\nsweep -r\n",
+			setup: func(d *fakeDevice) {
+				d.errs = map[string]error{"MoveMarkerPeak": errors.New("timeout")}
+			},
+			wantOut: "[1] sweep -p\n" +
+				"pause sweep\n" +
+				"[2] marker 1 --peak\n" +
+				"set marker #1 to peak\n" +
+				"[2] error: failed to set marker #1 to peak: timeout\n" +
+				"2 lines executed, 1 failed\n",
+			wantCalls: []string{"PauseSweep()", "MoveMarkerPeak(1)", "Close()"},
+			wantErr:   true,
+		},
+		{
+			name:   "continue on error",
+			script: "bogus\nsweep --span $undefined\nsweep -r\n",
+			args:   []string{"--continue-on-error"},
+			wantOut: "[1] bogus\n" +
+				"[1] error: unexpected argument bogus\n" +
+				"[2] error: undefined variable 'undefined'\n" +
+				"[3] sweep -r\n" +
+				"resume sweep\n" +
+				"2 lines executed, 2 failed\n",
+			wantCalls: []string{"ResumeSweep()", "Close()"},
+			wantErr:   true,
+		},
+		{
+			name:   "wait",
+			script: "wait Connect board 2\nsweep -r\n",
+			input:  "\n",
+			wantOut: "[1] wait Connect board 2\n" +
+				"Connect board 2\n" +
+				"[2] sweep -r\n" +
+				"resume sweep\n" +
+				"2 lines executed, 0 failed\n",
+			wantCalls: []string{"ResumeSweep()", "Close()"},
+		},
+		{
+			name:   "invalid directives",
+			script: "sleep forever\nset 1x 2\n",
+			args:   []string{"-k"},
+			wantOut: "[1] sleep forever\n" +
+				"[1] error: invalid time format: forever\n" +
+				"[2] set 1x 2\n" +
+				"[2] error: invalid set directive, expected 'set NAME VALUE'\n" +
+				"2 lines executed, 2 failed\n",
+			wantCalls: []string{"Close()"},
+			wantErr:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			file := filepath.Join(t.TempDir(), "plan.tsa")
+			if err := os.WriteFile(file, []byte(tt.script), 0o600); err != nil {
+				t.Fatal(err)
+			}
+
+			stdin = strings.NewReader(tt.input)
+			t.Cleanup(func() { stdin = nil })
+
+			d := newFakeDevice()
+			if tt.setup != nil {
+				tt.setup(d)
+			}
+
+			out, err := runCli(t, d, append([]string{"run", file}, tt.args...)...)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if out != tt.wantOut {
+				t.Errorf("output:\n%s\nwant:\n%s", out, tt.wantOut)
+			}
+			if got := strings.Join(d.calls, "\n"); got != strings.Join(tt.wantCalls, "\n") {
+				t.Errorf("calls:\n%s\nwant:\n%s", got, strings.Join(tt.wantCalls, "\n"))
+			}
+		})
+	}
+}
+
+func TestRunCmdNested(t *testing.T) {
+	dir := t.TempDir()
+	first, second := filepath.Join(dir, "first.tsa"), filepath.Join(dir, "second.tsa")
+	if err := os.WriteFile(first, []byte("run "+second+"\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(second, []byte("run "+first+"\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	stdin = strings.NewReader("")
+	t.Cleanup(func() { stdin = nil })
+
+	d := newFakeDevice()
+	out, err := runCli(t, d, "run", first)
+	if err == nil {
+		t.Fatal("expected error for script running another script")
+	}
+	want := "[1] run " + second + "\n" +
+		"[1] error: command 'run' is not available inside a script\n" +
+		"1 lines executed, 1 failed\n"
+	if out != want {
+		t.Errorf("output:\n%s\nwant:\n%s", out, want)
+	}
+
+	// a shell may run scripts
+	stdin = strings.NewReader("run " + second + "\n")
+	out, err = runCli(t, d, "shell")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out, "[1] run "+first+"\n[1] error: command 'run' is not available inside a script\n") {
+		t.Errorf("shell output:\n%s", out)
+	}
+}
+
+func TestRunCmdWaitInShell(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "plan.tsa")
+	if err := os.WriteFile(file, []byte("wait\nsweep -p\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	// the wait directive takes the empty line of the shell input, the shell continues with the next line
+	stdin = strings.NewReader("run " + file + "\n\nsweep -r\n")
+	t.Cleanup(func() { stdin = nil })
+
+	d := newFakeDevice()
+	out, err := runCli(t, d, "shell")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "[1] wait\n" +
+		"Press enter to continue\n" +
+		"[2] sweep -p\n" +
+		"pause sweep\n" +
+		"2 lines executed, 0 failed\n" +
+		"resume sweep\n"
+	if out != want {
+		t.Errorf("output:\n%s\nwant:\n%s", out, want)
+	}
+	if got := strings.Join(d.calls, ","); got != "PauseSweep(),ResumeSweep(),Close()" {
+		t.Errorf("calls = %s", got)
+	}
+}
@@ -6,7 +6,10 @@ import (
 	"github.com/alecthomas/kong"
 	"github.com/kkettinger/go-tinysa"
 	"image/png"
+	"math"
 	"os"
+	"path/filepath"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
@@ -18,17 +21,53 @@ const (
 	filenameTraceMultiDefault = "SA_<date>_<time>.csv"
 )
 
+// reductions of multiple sweeps per point
+const (
+	reduceAvg    = "avg"
+	reduceMax    = "max"
+	reduceMin    = "min"
+	reduceMedian = "median"
+)
+
 type SaveCmd struct {
 	Capture bool   `help:"Save screen as PNG to file" short:"c" group:"Save flags:" `
 	Trace   []uint `help:"Save trace(s) as CSV to file" short:"t" group:"Save flags:" `
 	Output  string `help:"Output filepath for capture or trace" short:"o" type:"path" group:"Save flags:" placeholder:"PATH"`
+
+	Unit      HostUnit `help:"Convert the trace values on the host to this unit (${host_unit_opts}), the unit of the device is not changed" short:"u" group:"Unit conversion flags:" placeholder:"UNIT"`
+	Impedance uint     `help:"Impedance in ohm of the voltage units (${enum})" default:"50" enum:"50,75" group:"Unit conversion flags:"`
+
+	Sweeps   uint          `help:"Number of sweeps to collect and reduce per point" short:"n" default:"1" group:"Sweep reduction flags:"`
+	Reduce   string        `help:"Reduction of the sweeps (${enum}), avg averages in linear power" default:"avg" enum:"avg,max,min,median" group:"Sweep reduction flags:"`
+	Interval time.Duration `help:"Delay between sweeps, should be at least one sweep time to not read a sweep twice" default:"500ms" group:"Sweep reduction flags:"`
+	StdDev   bool          `help:"Add the standard deviation of the sweeps per point as extra column" name:"stddev" group:"Sweep reduction flags:"`
+
+	// settings contains the output directory and filename templates of the config file
+	settings Profile
 }
 
 func (c *SaveCmd) Run(globals *Globals, ctx *kong.Context) error {
+	settings, err := globals.settings()
+	if err != nil {
+		return err
+	}
+	c.settings = settings
+
+	if c.Capture && c.Unit.Valid {
+		return fmt.Errorf("unit conversion is only supported for traces, not for captures")
+	}
+	if len(c.Trace) > 0 && c.Sweeps == 0 {
+		return fmt.Errorf("sweeps must be at least 1")
+	}
+	if c.Sweeps > 1 && c.Interval <= 0 {
+		return fmt.Errorf("interval must be greater than zero, otherwise the same sweep is read several times")
+	}
+
 	d, err := initDevice(globals)
 	if err != nil {
 		return err
 	}
+	defer d.Close()
 
 	if len(c.Trace) == 1 {
 		return c.SaveSingleTrace(d)
@@ -47,18 +86,31 @@ func (c *SaveCmd) Run(globals *Globals, ctx *kong.Context) error {
 	return nil
 }
 
-func (c *SaveCmd) SaveCapture(d *tinysa.Device) error {
+func (c *SaveCmd) SaveCapture(d Device) error {
 	if c.Output == "" {
-		c.Output = filenameCaptureDefault
+		if err := c.setDefaultOutput(c.settings.Filenames.Capture); err != nil {
+			return err
+		}
 	}
 
 	// replace filename placeholders with actual values
 	c.Output = replaceFilenamePlaceholdersDateTime(c.Output)
 
+	if err := saveCapture(d, c.Output); err != nil {
+		return err
+	}
+
+	_, _ = fmt.Fprintf(stdout, "capture saved to %s\n", c.Output)
+
+	return nil
+}
+
+// saveCapture saves the screen of the device as PNG to path.
+func saveCapture(d Device, path string) error {
 	// open file
-	file, err := os.Create(c.Output)
+	file, err := os.Create(path)
 	if err != nil {
-		return fmt.Errorf("failed to open file '%s': %w", c.Output, err)
+		return fmt.Errorf("failed to open file '%s': %w", path, err)
 	}
 	defer file.Close()
 
@@ -70,17 +122,17 @@ func (c *SaveCmd) SaveCapture(d *tinysa.Device) error {
 
 	// save as PNG
 	if err = png.Encode(file, img); err != nil {
-		return fmt.Errorf("failed to encode file '%s': %w", c.Output, err)
+		return fmt.Errorf("failed to encode file '%s': %w", path, err)
 	}
 
-	fmt.Printf("capture saved to %s\n", c.Output)
-
 	return nil
 }
 
-func (c *SaveCmd) SaveSingleTrace(d *tinysa.Device) error {
+func (c *SaveCmd) SaveSingleTrace(d Device) error {
 	if c.Output == "" {
-		c.Output = filenameTraceDefault
+		if err := c.setDefaultOutput(c.settings.Filenames.Trace); err != nil {
+			return err
+		}
 	}
 
 	// replace filename placeholders with actual values
@@ -88,10 +140,11 @@ func (c *SaveCmd) SaveSingleTrace(d *tinysa.Device) error {
 	c.Output = strings.ReplaceAll(c.Output, "<trace>", fmt.Sprintf("%d", c.Trace[0]))
 
 	// get data from device
-	data, err := d.GetTraceData(c.Trace[0])
+	traces, stddev, err := c.readTraces(d)
 	if err != nil {
-		return fmt.Errorf("failed to get trace data: %w", err)
+		return err
 	}
+	data := traces[0]
 
 	// open file
 	file, err := os.Create(c.Output)
@@ -104,42 +157,47 @@ func (c *SaveCmd) SaveSingleTrace(d *tinysa.Device) error {
 	writer := csv.NewWriter(file)
 	defer writer.Flush()
 
-	if err := writer.Write([]string{"trace", "point", "frequency", "value"}); err != nil {
+	header := []string{"trace", "point", "frequency", "value"}
+	if stddev != nil {
+		header = append(header, "stddev")
+	}
+	if err := writer.Write(header); err != nil {
 		return err
 	}
 
-	for _, dp := range data {
+	for i, dp := range data {
 		row := []string{
 			strconv.FormatUint(uint64(dp.Trace), 10),
 			strconv.FormatUint(uint64(dp.Point), 10),
 			strconv.FormatUint(dp.Frequency, 10),
 			strconv.FormatFloat(dp.Value, 'f', -1, 64),
 		}
+		if stddev != nil {
+			row = append(row, strconv.FormatFloat(stddev[0][i], 'f', -1, 64))
+		}
 		if err := writer.Write(row); err != nil {
 			return err
 		}
 	}
 
-	fmt.Printf("trace %d data saved to %s\n", c.Trace[0], c.Output)
+	_, _ = fmt.Fprintf(stdout, "trace %d data saved to %s%s\n", c.Trace[0], c.Output, c.reductionInfo())
 
 	return nil
 }
 
-func (c *SaveCmd) SaveMultipleTraces(d *tinysa.Device) error {
+func (c *SaveCmd) SaveMultipleTraces(d Device) error {
 	if c.Output == "" {
-		c.Output = filenameTraceMultiDefault
+		if err := c.setDefaultOutput(c.settings.Filenames.TraceMulti); err != nil {
+			return err
+		}
 	}
 
 	// replace filename placeholders with actual values
 	c.Output = replaceFilenamePlaceholdersDateTime(c.Output)
 
-	data := make([][]tinysa.TraceData, len(c.Trace))
-	for i, traceId := range c.Trace {
-		d, err := d.GetTraceData(traceId)
-		if err != nil {
-			return fmt.Errorf("failed to get trace data: %w", err)
-		}
-		data[i] = d
+	data, stddev, err := c.readTraces(d)
+	if err != nil {
+		return err
 	}
 
 	// open file
@@ -158,6 +216,11 @@ func (c *SaveCmd) SaveMultipleTraces(d *tinysa.Device) error {
 	for _, t := range c.Trace {
 		header = append(header, fmt.Sprintf("value_t%d", t))
 	}
+	if stddev != nil {
+		for _, t := range c.Trace {
+			header = append(header, fmt.Sprintf("stddev_t%d", t))
+		}
+	}
 	if err := writer.Write(header); err != nil {
 		return err
 	}
@@ -170,13 +233,195 @@ func (c *SaveCmd) SaveMultipleTraces(d *tinysa.Device) error {
 		for j := range c.Trace {
 			row = append(row, strconv.FormatFloat(data[j][i].Value, 'f', -1, 64))
 		}
+		for j := range stddev {
+			row = append(row, strconv.FormatFloat(stddev[j][i], 'f', -1, 64))
+		}
 		if err := writer.Write(row); err != nil {
 			return err
 		}
 	}
 
-	fmt.Printf("traces %v saved to %s\n", c.Trace, c.Output)
+	_, _ = fmt.Fprintf(stdout, "traces %v saved to %s%s\n", c.Trace, c.Output, c.reductionInfo())
+
+	return nil
+}
+
+// readTraces reads the data of the traces. With more than one sweep, the sweeps are collected and reduced
+// per point. The standard deviation per trace and point is nil unless requested.
+func (c *SaveCmd) readTraces(d Device) ([][]tinysa.TraceData, [][]float64, error) {
+	// sweeps of every trace
+	sweeps := make([][][]tinysa.TraceData, len(c.Trace))
+	for n := range c.Sweeps {
+		if n > 0 {
+			time.Sleep(c.Interval)
+		}
+		for i, traceId := range c.Trace {
+			data, err := d.GetTraceData(traceId)
+			if err != nil {
+				return nil, nil, fmt.Errorf("failed to get trace data: %w", err)
+			}
+			if n > 0 && !sameFrequencies(data, sweeps[i][0]) {
+				return nil, nil, fmt.Errorf("sweep settings changed during the sweeps")
+			}
+			sweeps[i] = append(sweeps[i], data)
+		}
+	}
+
+	data := make([][]tinysa.TraceData, len(c.Trace))
+	var stddev [][]float64
+	if c.StdDev {
+		stddev = make([][]float64, len(c.Trace))
+	}
+	for i, traceId := range c.Trace {
+		// averaging and the unit conversion need the unit of the device
+		unit := tinysa.TraceUnitDBm
+		if (c.Sweeps > 1 && c.Reduce == reduceAvg) || c.Unit.Valid {
+			trace, err := d.GetTrace(traceId)
+			if err != nil {
+				return nil, nil, fmt.Errorf("failed to get trace: %w", err)
+			}
+			unit = trace.Unit
+		}
+
+		reduced, err := reduceSweeps(sweeps[i], c.Reduce, unit)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to reduce sweeps of trace %d: %w", traceId, err)
+		}
+		data[i] = reduced
+
+		if c.Unit.Valid {
+			// the standard deviation is taken in the converted unit, so the sweeps are converted as well
+			converted := [][]tinysa.TraceData{reduced}
+			if c.StdDev {
+				converted = append(converted, sweeps[i]...)
+			}
+			for _, sweep := range converted {
+				if err := convertTraceData(sweep, unit, c.Unit.Unit, float64(c.Impedance)); err != nil {
+					return nil, nil, fmt.Errorf("failed to convert trace %d: %w", traceId, err)
+				}
+			}
+		}
+		if c.StdDev {
+			stddev[i] = sweepStdDev(sweeps[i])
+		}
+	}
+
+	return data, stddev, nil
+}
+
+// reductionInfo returns the suffix of the saved message, describing the unit conversion and the reduction of the sweeps.
+func (c *SaveCmd) reductionInfo() string {
+	var info string
+	if c.Unit.Valid {
+		info = " in " + hostUnitLabels[c.Unit.Unit]
+		if isVoltageUnit(c.Unit.Unit) {
+			info += fmt.Sprintf(" at %d ohm", c.Impedance)
+		}
+	}
+	if c.Sweeps > 1 {
+		info += fmt.Sprintf(" (%s of %d sweeps)", c.Reduce, c.Sweeps)
+	}
+	return info
+}
+
+func sameFrequencies(a, b []tinysa.TraceData) bool {
+	return slices.EqualFunc(a, b, func(x, y tinysa.TraceData) bool { return x.Frequency == y.Frequency })
+}
+
+// reduceSweeps reduces the sweeps of a trace to a single sweep by the value per point. The average
+// is calculated in linear power, so a single strong sweep is weighted like on a power meter.
+func reduceSweeps(sweeps [][]tinysa.TraceData, mode string, unit tinysa.TraceUnit) ([]tinysa.TraceData, error) {
+	reduced := slices.Clone(sweeps[0])
+	if len(sweeps) == 1 {
+		return reduced, nil
+	}
+
+	values := make([]float64, len(sweeps))
+	for p := range reduced {
+		for s, sweep := range sweeps {
+			values[s] = sweep[p].Value
+		}
 
+		switch mode {
+		case reduceMax:
+			reduced[p].Value = slices.Max(values)
+		case reduceMin:
+			reduced[p].Value = slices.Min(values)
+		case reduceMedian:
+			reduced[p].Value = median(values)
+		default:
+			v, err := averagePower(values, unit)
+			if err != nil {
+				return nil, err
+			}
+			reduced[p].Value = v
+		}
+	}
+	return reduced, nil
+}
+
+// averagePower averages the values in linear power and converts the result back to unit.
+func averagePower(values []float64, unit tinysa.TraceUnit) (float64, error) {
+	var mW float64
+	for _, v := range values {
+		dBm, err := toDBm(v, unit)
+		if err != nil {
+			return 0, err
+		}
+		mW += dBmToMilliwatt(dBm)
+	}
+
+	avg, err := fromDBm(milliwattToDBm(mW/float64(len(values))), unit)
+	if err != nil {
+		return 0, err
+	}
+	if strings.HasPrefix(unit.String(), "dB") {
+		// remove the float noise of the conversion, the device reports two decimals
+		avg = math.Round(avg*1e6) / 1e6
+	}
+	return avg, nil
+}
+
+func median(values []float64) float64 {
+	sorted := slices.Sorted(slices.Values(values))
+	n := len(sorted)
+	if n%2 == 1 {
+		return sorted[n/2]
+	}
+	return (sorted[n/2-1] + sorted[n/2]) / 2
+}
+
+// sweepStdDev returns the sample standard deviation per point in the unit of the trace.
+func sweepStdDev(sweeps [][]tinysa.TraceData) []float64 {
+	stddev := make([]float64, len(sweeps[0]))
+	n := float64(len(sweeps))
+	if n < 2 {
+		return stddev
+	}
+
+	for p := range stddev {
+		// shifted by the first sweep, so equal values result in exactly zero
+		var sum, sumSquares float64
+		for _, sweep := range sweeps {
+			d := sweep[p].Value - sweeps[0][p].Value
+			sum += d
+			sumSquares += d * d
+		}
+		stddev[p] = math.Sqrt(max(sumSquares-sum*sum/n, 0) / (n - 1))
+	}
+	return stddev
+}
+
+// setDefaultOutput sets the output path to the filename template inside the configured output directory.
+func (c *SaveCmd) setDefaultOutput(template string) error {
+	if c.settings.OutputDir == "" {
+		c.Output = template
+		return nil
+	}
+	if err := os.MkdirAll(c.settings.OutputDir, 0o755); err != nil {
+		return fmt.Errorf("failed to create output directory '%s': %w", c.settings.OutputDir, err)
+	}
+	c.Output = filepath.Join(c.settings.OutputDir, template)
 	return nil
 }
 
@@ -0,0 +1,228 @@
+package main
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/kkettinger/go-tinysa"
+)
+
+func TestSaveCmd(t *testing.T) {
+	tests := []struct {
+		name      string
+		args      []string
+		wantOut   string
+		wantCalls []string
+		wantFile  string
+	}{
+		{
+			name:      "single trace",
+			args:      []string{"save", "--trace", "1", "-o", "trace_<trace>.csv"},
+			wantOut:   "trace 1 data saved to trace_1.csv\n",
+			wantCalls: []string{"GetTraceData(1)", "Close()"},
+			wantFile: "trace,point,frequency,value\n" +
+				"1,0,400000000,-90.25\n" +
+				"1,1,450000000,-40.5\n" +
+				"1,2,500000000,-89.75\n",
+		},
+		{
+			name:      "multiple traces",
+			args:      []string{"save", "--trace", "1,2", "-o", "traces.csv"},
+			wantOut:   "traces [1 2] saved to traces.csv\n",
+			wantCalls: []string{"GetTraceData(1)", "GetTraceData(2)", "Close()"},
+			wantFile: "point,frequency,value_t1,value_t2\n" +
+				"0,400000000,-90.25,-84.78\n" +
+				"1,450000000,-40.5,-35\n" +
+				"2,500000000,-89.75,-85.75\n",
+		},
+		{
+			name:      "reduced single trace with stddev",
+			args:      []string{"save", "--trace", "1", "--sweeps", "3", "--interval", "1ms", "--reduce", "avg", "--stddev", "-o", "avg.csv"},
+			wantOut:   "trace 1 data saved to avg.csv (avg of 3 sweeps)\n",
+			wantCalls: []string{"GetTraceData(1)", "GetTraceData(1)", "GetTraceData(1)", "GetTrace(1)", "Close()"},
+			wantFile: "trace,point,frequency,value,stddev\n" +
+				"1,0,400000000,-90.25,0\n" +
+				"1,1,450000000,-40.5,0\n" +
+				"1,2,500000000,-89.75,0\n",
+		},
+		{
+			name:      "reduced multiple traces",
+			args:      []string{"save", "--trace", "1,2", "-n", "2", "--interval", "1ms", "--reduce", "max", "-o", "max.csv"},
+			wantOut:   "traces [1 2] saved to max.csv (max of 2 sweeps)\n",
+			wantCalls: []string{"GetTraceData(1)", "GetTraceData(2)", "GetTraceData(1)", "GetTraceData(2)", "Close()"},
+			wantFile: "point,frequency,value_t1,value_t2\n" +
+				"0,400000000,-90.25,-84.78\n" +
+				"1,450000000,-40.5,-35\n" +
+				"2,500000000,-89.75,-85.75\n",
+		},
+		{
+			name:      "converted to dbuv at 75 ohm",
+			args:      []string{"save", "--trace", "1", "--unit", "dbuv", "--impedance", "75", "-o", "dbuv.csv"},
+			wantOut:   "trace 1 data saved to dbuv.csv in dBuV at 75 ohm\n",
+			wantCalls: []string{"GetTraceData(1)", "GetTrace(1)", "Close()"},
+			wantFile: "trace,point,frequency,value\n" +
+				"1,0,400000000,18.500613\n" +
+				"1,1,450000000,68.250613\n" +
+				"1,2,500000000,19.000613\n",
+		},
+		{
+			name:      "reduced and converted to mw",
+			args:      []string{"save", "--trace", "1,2", "-n", "2", "--interval", "1ms", "--reduce", "max", "--stddev", "-u", "mw", "-o", "mw.csv"},
+			wantOut:   "traces [1 2] saved to mw.csv in mW (max of 2 sweeps)\n",
+			wantCalls: []string{"GetTraceData(1)", "GetTraceData(2)", "GetTraceData(1)", "GetTraceData(2)", "GetTrace(1)", "GetTrace(2)", "Close()"},
+			wantFile: "point,frequency,value_t1,value_t2,stddev_t1,stddev_t2\n" +
+				"0,400000000,0.0000000009440608762859226,0.000000003326595532940047,0,0\n" +
+				"1,450000000,0.0000891250938133746,0.00031622776601683794,0,0\n" +
+				"2,500000000,0.0000000010592537251772898,0.000000002660725059798814,0,0\n",
+		},
+		{
+			name:      "capture",
+			args:      []string{"save", "--capture", "-o", "capture.png"},
+			wantOut:   "capture saved to capture.png\n",
+			wantCalls: []string{"Capture()", "Close()"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Chdir(t.TempDir())
+
+			d := newFakeDevice()
+			out, err := runCli(t, d, tt.args...)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// kong resolves the output path, so only compare the file name
+			if got := strings.ReplaceAll(out, mustGetwd(t)+string(filepath.Separator), ""); got != tt.wantOut {
+				t.Errorf("output = %q, want %q", got, tt.wantOut)
+			}
+			if got := strings.Join(d.calls, "\n"); got != strings.Join(tt.wantCalls, "\n") {
+				t.Errorf("calls = %q, want %q", d.calls, tt.wantCalls)
+			}
+
+			if tt.wantFile != "" {
+				name := strings.Fields(tt.wantOut[strings.Index(tt.wantOut, " to ")+4:])[0]
+				data, err := os.ReadFile(name)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if string(data) != tt.wantFile {
+					t.Errorf("file:\n%s\nwant:\n%s", data, tt.wantFile)
+				}
+			}
+		})
+	}
+}
+
+func TestSaveCmdConfig(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "bench1")
+	config := &Config{
+		Filenames: Filenames{Trace: "board_<trace>.csv"},
+		Profiles:  map[string]Profile{"bench1": {OutputDir: dir}},
+	}
+
+	out, err := runCliConfig(t, newFakeDevice(), config, "--profile", "bench1", "save", "-t", "2")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	file := filepath.Join(dir, "board_2.csv")
+	if want := "trace 2 data saved to " + file + "\n"; out != want {
+		t.Errorf("output = %q, want %q", out, want)
+	}
+	if _, err := os.Stat(file); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestSaveCmdInvalid(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    []string
+		wantErr string
+	}{
+		{"no sweeps", []string{"save", "-t", "1", "-n", "0"}, "sweeps must be at least 1"},
+		{"unit of capture", []string{"save", "--capture", "--unit", "dbuv"}, "unit conversion is only supported for traces, not for captures"},
+		{"no interval", []string{"save", "-t", "1", "-n", "2", "--interval", "0s"}, "interval must be greater than zero, otherwise the same sweep is read several times"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := newFakeDevice()
+			_, err := runCli(t, d, tt.args...)
+			if err == nil || err.Error() != tt.wantErr {
+				t.Fatalf("error = %v, want %s", err, tt.wantErr)
+			}
+			// rejected before the device is opened
+			if len(d.calls) > 0 {
+				t.Errorf("calls = %q", d.calls)
+			}
+		})
+	}
+}
+
+func mustGetwd(t *testing.T) string {
+	t.Helper()
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return wd
+}
+
+func TestReduceSweeps(t *testing.T) {
+	sweep := func(values ...float64) []tinysa.TraceData {
+		data := make([]tinysa.TraceData, len(values))
+		for i, v := range values {
+			data[i] = tinysa.TraceData{Trace: 1, Point: uint(i), Frequency: uint64(400_000_000 + i*1_000_000), Value: v} // #nosec G115
+		}
+		return data
+	}
+	sweeps := [][]tinysa.TraceData{sweep(-30, -80), sweep(-40, -80), sweep(-35, -80), sweep(-31, -80)}
+
+	tests := []struct {
+		mode string
+		unit tinysa.TraceUnit
+		want []float64
+	}{
+		// 1 µW, 0.1 µW, 0.316 µW and 0.794 µW average to 0.552 µW
+		{reduceAvg, tinysa.TraceUnitDBm, []float64{-32.575585, -80}},
+		{reduceAvg, tinysa.TraceUnitDBuV, []float64{-32.575585, -80}},
+		{reduceMax, tinysa.TraceUnitDBm, []float64{-30, -80}},
+		{reduceMin, tinysa.TraceUnitDBm, []float64{-40, -80}},
+		{reduceMedian, tinysa.TraceUnitDBm, []float64{-33, -80}},
+	}
+	for _, tt := range tests {
+		got, err := reduceSweeps(sweeps, tt.mode, tt.unit)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i, dp := range got {
+			if math.Abs(dp.Value-tt.want[i]) > 1e-6 || dp.Frequency != sweeps[0][i].Frequency {
+				t.Errorf("%s in %s: point %d = %v, want %v", tt.mode, tt.unit, i, dp.Value, tt.want[i])
+			}
+		}
+	}
+
+	if _, err := reduceSweeps(sweeps, reduceAvg, tinysa.TraceUnitRaw); err == nil {
+		t.Error("expected error for average of raw unit")
+	}
+
+	// averaging the voltage in linear power is the RMS of the voltages
+	got, err := reduceSweeps([][]tinysa.TraceData{sweep(1), sweep(7)}, reduceAvg, tinysa.TraceUnitV)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if math.Abs(got[0].Value-5) > 1e-9 {
+		t.Errorf("average of 1 V and 7 V = %v, want 5", got[0].Value)
+	}
+
+	stddev := sweepStdDev(sweeps)
+	if math.Abs(stddev[0]-4.546061) > 1e-6 || stddev[1] != 0 {
+		t.Errorf("stddev = %v, want [4.546061 0]", stddev)
+	}
+}
@@ -0,0 +1,190 @@
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/kkettinger/go-tinysa"
+	"github.com/kkettinger/tsactl/internal/util"
+)
+
+const filenameScanDefault = "SA_<date>_<time>_scan.csv"
+
+// minScanPoints is the minimum number of points per segment.
+const minScanPoints = 10
+
+type ScanCmd struct {
+	Trace   uint          `help:"Trace to read" short:"t" default:"1" group:"Scan flags:"`
+	Start   Frequency     `help:"Start frequency of the scan" required:"" group:"Scan flags:" placeholder:"FREQ"`
+	Stop    Frequency     `help:"Stop frequency of the scan" required:"" group:"Scan flags:" placeholder:"FREQ"`
+	RBWStep Frequency     `help:"Frequency step between the points, e.g. the resolution bandwidth" name:"rbw-step" required:"" group:"Scan flags:" placeholder:"FREQ"`
+	Points  uint          `help:"Points per segment, defaults to the maximum of the device model" short:"n" group:"Scan flags:"`
+	Dwell   time.Duration `help:"Time to wait after retuning before reading a segment, should be at least one sweep time" default:"500ms" group:"Scan flags:"`
+	Output  string        `help:"Output filepath of the stitched trace CSV" short:"o" type:"path" group:"Scan flags:" placeholder:"PATH"`
+}
+
+// scanSegment is a single sweep of a scan.
+type scanSegment struct {
+	start  uint64
+	stop   uint64
+	points uint
+}
+
+func (c *ScanCmd) Run(globals *Globals) (err error) {
+	if c.Start.Value >= c.Stop.Value {
+		return fmt.Errorf("start frequency must be lower than stop frequency")
+	}
+	if c.RBWStep.Value == 0 {
+		return fmt.Errorf("rbw step must be greater than zero")
+	}
+
+	settings, err := globals.settings()
+	if err != nil {
+		return err
+	}
+	output := c.Output
+	if output == "" {
+		output = filepath.Join(settings.OutputDir, settings.Filenames.Scan)
+		if settings.OutputDir != "" {
+			if err := os.MkdirAll(settings.OutputDir, 0o755); err != nil {
+				return fmt.Errorf("failed to create output directory '%s': %w", settings.OutputDir, err)
+			}
+		}
+	}
+	output = replaceFilenamePlaceholdersDateTime(output)
+
+	d, err := initDevice(globals)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+
+	limit := deviceLimit(d.Model())
+	if c.Stop.Value > limit.maxFrequency {
+		return fmt.Errorf("stop frequency exceeds the maximum of %s of the %s", util.FormatFrequency(limit.maxFrequency), d.Model())
+	}
+	points := c.Points
+	if points == 0 {
+		points = limit.maxPoints
+	}
+	if points < minScanPoints || points > limit.maxPoints {
+		return fmt.Errorf("points must be between %d and %d", minScanPoints, limit.maxPoints)
+	}
+
+	segments, err := planScanSegments(c.Start.Value, c.Stop.Value, c.RBWStep.Value, points)
+	if err != nil {
+		return err
+	}
+
+	// restore the sweep settings when done, also on errors and Ctrl-C
+	tuner, err := newSweepTuner(d)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if rerr := tuner.restore(); rerr != nil && err == nil {
+			err = rerr
+		}
+	}()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	last := segments[len(segments)-1]
+	_, _ = fmt.Fprintf(stdout, "scanning %s to %s in %d segments, step %s\n", util.FormatFrequency(c.Start.Value),
+		util.FormatFrequency(last.stop), len(segments), util.FormatFrequency(c.RBWStep.Value))
+
+	var data []tinysa.TraceData
+	for i, seg := range segments {
+		if err := tuner.tune(tinysa.Sweep{Start: seg.start, Stop: seg.stop, Points: seg.points}); err != nil {
+			return fmt.Errorf("failed to set sweep of segment %d: %w", i+1, err)
+		}
+
+		// wait for a complete sweep with the new settings
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("scan interrupted")
+		case <-time.After(c.Dwell):
+		}
+
+		segData, err := d.GetTraceData(c.Trace)
+		if err != nil {
+			return fmt.Errorf("failed to get trace data of segment %d: %w", i+1, err)
+		}
+		if uint(len(segData)) != seg.points {
+			return fmt.Errorf("segment %d returned %d points, expected %d", i+1, len(segData), seg.points)
+		}
+		data = append(data, segData...)
+
+		_, _ = fmt.Fprintf(stdout, "segment %d/%d: %s to %s\n", i+1, len(segments), util.FormatFrequency(seg.start), util.FormatFrequency(seg.stop))
+	}
+
+	if err := writeScanCSV(output, c.Trace, data); err != nil {
+		return err
+	}
+	_, _ = fmt.Fprintf(stdout, "scan of %d points saved to %s\n", len(data), output)
+
+	return nil
+}
+
+// planScanSegments splits the points from start to stop, spaced by step, evenly into sweeps of at most
+// maxPoints and at least minScanPoints. The last point is at stop, so the points of the last segment are
+// slightly closer if the range is not a multiple of step.
+func planScanSegments(start, stop, step uint64, maxPoints uint) ([]scanSegment, error) {
+	n := (stop-start+step-1)/step + 1
+	if n < minScanPoints {
+		return nil, fmt.Errorf("range covers only %d points at a step of %s, at least %d are needed", n,
+			util.FormatFrequency(step), minScanPoints)
+	}
+	count := (n + uint64(maxPoints) - 1) / uint64(maxPoints)
+
+	segments := make([]scanSegment, count)
+	for i := range count {
+		lo, hi := i*n/count, (i+1)*n/count
+		if hi-lo < minScanPoints {
+			return nil, fmt.Errorf("segments of %d points are below the minimum of %d, increase the points per segment",
+				hi-lo, minScanPoints)
+		}
+		segments[i] = scanSegment{start: start + lo*step, stop: start + (hi-1)*step, points: uint(hi - lo)}
+	}
+	// the rounded up last point could exceed the frequency limit of the device
+	segments[count-1].stop = stop
+	return segments, nil
+}
+
+// writeScanCSV writes the stitched scan in the single trace layout of the save command.
+func writeScanCSV(path string, trace uint, data []tinysa.TraceData) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to open file '%s': %w", path, err)
+	}
+	defer file.Close()
+
+	writer := csv.NewWriter(file)
+	if err := writer.Write([]string{"trace", "point", "frequency", "value"}); err != nil {
+		return fmt.Errorf("failed to write file '%s': %w", path, err)
+	}
+	for i, dp := range data {
+		row := []string{
+			strconv.FormatUint(uint64(trace), 10),
+			strconv.Itoa(i),
+			strconv.FormatUint(dp.Frequency, 10),
+			strconv.FormatFloat(dp.Value, 'f', -1, 64),
+		}
+		if err := writer.Write(row); err != nil {
+			return fmt.Errorf("failed to write file '%s': %w", path, err)
+		}
+	}
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return fmt.Errorf("failed to write file '%s': %w", path, err)
+	}
+	return nil
+}
@@ -0,0 +1,180 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/kkettinger/go-tinysa"
+)
+
+// sweepDevice returns trace data matching the sweep settings, with the frequency in MHz as value.
+type sweepDevice struct {
+	*fakeDevice
+}
+
+func (d *sweepDevice) SetSweepStart(freqHz uint64) error {
+	d.sweep.Start = freqHz
+	return d.fakeDevice.SetSweepStart(freqHz)
+}
+
+func (d *sweepDevice) SetSweepStop(freqHz uint64) error {
+	d.sweep.Stop = freqHz
+	return d.fakeDevice.SetSweepStop(freqHz)
+}
+
+func (d *sweepDevice) SetSweepPoints(points uint) error {
+	d.sweep.Points = points
+	return d.fakeDevice.SetSweepPoints(points)
+}
+
+func (d *sweepDevice) GetTraceData(traceID uint) ([]tinysa.TraceData, error) {
+	s := d.sweep
+	data := make([]tinysa.TraceData, s.Points)
+	for i := range data {
+		freq := s.Start + uint64(i)*(s.Stop-s.Start)/uint64(s.Points-1)
+		data[i] = tinysa.TraceData{Trace: traceID, Point: uint(i), Frequency: freq, Value: float64(freq) / 1e6} // #nosec G115
+	}
+	return data, d.record("GetTraceData", traceID)
+}
+
+func TestScanCmd(t *testing.T) {
+	t.Chdir(t.TempDir())
+
+	d := &sweepDevice{fakeDevice: newFakeDevice()}
+	out, err := runCli(t, d, "scan", "--start", "100M", "--stop", "101.85M", "--rbw-step", "100k", "--points", "10", "--dwell", "0", "-o", "scan.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wantOut := "scanning 100 MHz to 101.85 MHz in 2 segments, step 100 kHz\n" +
+		"segment 1/2: 100 MHz to 100.9 MHz\n" +
+		"segment 2/2: 101 MHz to 101.85 MHz\n" +
+		"scan of 20 points saved to scan.csv\n"
+	if got := strings.ReplaceAll(out, mustGetwd(t)+string(filepath.Separator), ""); got != wantOut {
+		t.Errorf("output = %q, want %q", got, wantOut)
+	}
+
+	// the stop frequency is set first when moving up, unchanged points are not set again and the original
+	// sweep is restored at the end
+	wantCalls := []string{
+		"GetSweep()",
+		"SetSweepStart(100000000)", "SetSweepStop(100900000)", "SetSweepPoints(10)", "GetTraceData(1)",
+		"SetSweepStop(101850000)", "SetSweepStart(101000000)", "GetTraceData(1)",
+		"SetSweepStop(500000000)", "SetSweepStart(400000000)", "SetSweepPoints(450)",
+		"Close()",
+	}
+	if got := strings.Join(d.calls, "\n"); got != strings.Join(wantCalls, "\n") {
+		t.Errorf("calls = %q, want %q", d.calls, wantCalls)
+	}
+
+	data, err := os.ReadFile("scan.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the last point is at the stop frequency instead of the next step
+	wantFile := "trace,point,frequency,value\n" +
+		"1,0,100000000,100\n1,1,100100000,100.1\n1,2,100200000,100.2\n1,3,100300000,100.3\n1,4,100400000,100.4\n" +
+		"1,5,100500000,100.5\n1,6,100600000,100.6\n1,7,100700000,100.7\n1,8,100800000,100.8\n1,9,100900000,100.9\n" +
+		"1,10,101000000,101\n1,11,101094444,101.094444\n1,12,101188888,101.188888\n1,13,101283333,101.283333\n" +
+		"1,14,101377777,101.377777\n1,15,101472222,101.472222\n1,16,101566666,101.566666\n1,17,101661111,101.661111\n" +
+		"1,18,101755555,101.755555\n1,19,101850000,101.85\n"
+	if string(data) != wantFile {
+		t.Errorf("file:\n%s\nwant:\n%s", data, wantFile)
+	}
+}
+
+func TestScanCmdRestoresSweepOnError(t *testing.T) {
+	t.Chdir(t.TempDir())
+
+	d := &sweepDevice{fakeDevice: newFakeDevice()}
+	d.errs = map[string]error{"GetTraceData": errors.New("timeout")}
+	if _, err := runCli(t, d, "scan", "--start", "100M", "--stop", "200M", "--rbw-step", "1M", "--dwell", "0"); err == nil {
+		t.Fatal("expected error")
+	}
+
+	wantCalls := []string{
+		"GetSweep()",
+		"SetSweepStart(100000000)", "SetSweepStop(200000000)", "SetSweepPoints(101)", "GetTraceData(1)",
+		"SetSweepStop(500000000)", "SetSweepStart(400000000)", "SetSweepPoints(450)",
+		"Close()",
+	}
+	if got := strings.Join(d.calls, "\n"); got != strings.Join(wantCalls, "\n") {
+		t.Errorf("calls = %q, want %q", d.calls, wantCalls)
+	}
+}
+
+func TestScanCmdErrors(t *testing.T) {
+	runCliTests(t, []cliTest{
+		{
+			name:    "start above stop",
+			args:    []string{"scan", "--start", "200M", "--stop", "100M", "--rbw-step", "100k"},
+			wantErr: true,
+		},
+		{
+			name:      "stop above model limit",
+			args:      []string{"scan", "--start", "100M", "--stop", "7G", "--rbw-step", "100k"},
+			wantCalls: []string{"Close()"},
+			wantErr:   true,
+		},
+		{
+			name:      "stop above limit of basic model",
+			args:      []string{"scan", "--start", "100M", "--stop", "1G", "--rbw-step", "100k"},
+			setup:     func(d *fakeDevice) { d.model = tinysa.ModelBasic },
+			wantCalls: []string{"Close()"},
+			wantErr:   true,
+		},
+		{
+			name:      "too many points",
+			args:      []string{"scan", "--start", "100M", "--stop", "1G", "--rbw-step", "100k", "--points", "451"},
+			wantCalls: []string{"Close()"},
+			wantErr:   true,
+		},
+	})
+}
+
+func TestPlanScanSegments(t *testing.T) {
+	segments, err := planScanSegments(100_000_000, 6_000_000_000, 100_000, 450)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 59001 points in 132 segments of 446 or 447 points
+	if len(segments) != 132 {
+		t.Fatalf("segments = %d, want 132", len(segments))
+	}
+	var points uint
+	next := uint64(100_000_000)
+	for i, s := range segments {
+		if s.points > 450 || s.points < 446 {
+			t.Errorf("segment %d has %d points", i, s.points)
+		}
+		if s.start != next || s.stop != s.start+uint64(s.points-1)*100_000 {
+			t.Errorf("segment %d = %+v, want start at %d", i, s, next)
+		}
+		next = s.stop + 100_000
+		points += s.points
+	}
+	if points != 59001 || segments[len(segments)-1].stop != 6_000_000_000 {
+		t.Errorf("points = %d, last segment = %+v", points, segments[len(segments)-1])
+	}
+
+	// the last point is at the stop frequency, also if the range is not a multiple of the step
+	segments, err = planScanSegments(100, 1050, 100, 450)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(segments) != 1 || segments[0] != (scanSegment{start: 100, stop: 1050, points: 11}) {
+		t.Errorf("segments = %+v", segments)
+	}
+
+	// too few points for the range or per segment
+	if _, err := planScanSegments(100, 500, 100, 450); err == nil {
+		t.Error("expected error for 5 points")
+	}
+	if _, err := planScanSegments(100, 1100, 100, 10); err == nil {
+		t.Error("expected error for segments of 5 and 6 points")
+	}
+}
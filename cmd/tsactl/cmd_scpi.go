@@ -0,0 +1,513 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+
+	"github.com/kkettinger/go-tinysa"
+	"github.com/kkettinger/tsactl/internal/scpi"
+)
+
+type ScpiCmd struct {
+	Listen string `help:"Address to listen on" short:"l" default:":5025" placeholder:"ADDR"`
+}
+
+func (c *ScpiCmd) Run(globals *Globals) error {
+	d, err := initDevice(globals)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	listener, err := net.Listen("tcp", c.Listen)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", c.Listen, err)
+	}
+
+	go func() {
+		<-ctx.Done()
+		_ = listener.Close()
+	}()
+
+	_, _ = fmt.Fprintf(stdout, "listening on %s, press Ctrl-C to stop\n", c.Listen)
+
+	srv := newScpiServer(d)
+	var wg sync.WaitGroup
+	defer wg.Wait()
+
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return fmt.Errorf("failed to accept connection: %w", err)
+		}
+
+		// close open connections on shutdown, so their sessions end
+		connCtx, cancel := context.WithCancel(ctx)
+		go func() {
+			<-connCtx.Done()
+			_ = conn.Close()
+		}()
+
+		wg.Go(func() {
+			defer cancel()
+			srv.serve(conn)
+		})
+	}
+}
+
+// scpiServer translates SCPI commands of its clients onto device calls.
+type scpiServer struct {
+	// mu serializes the access to the device, whose commands must not interleave
+	mu sync.Mutex
+	d  Device
+}
+
+func newScpiServer(d Device) *scpiServer {
+	return &scpiServer{d: d}
+}
+
+// serve handles the program messages of a single client, one per line.
+func (s *scpiServer) serve(conn net.Conn) {
+	defer conn.Close()
+
+	session := &scpiSession{server: s}
+	scanner := bufio.NewScanner(conn)
+	scanner.Buffer(make([]byte, 4096), 1<<20)
+
+	for scanner.Scan() {
+		if response, ok := session.exec(scanner.Text()); ok {
+			if _, err := fmt.Fprintf(conn, "%s\n", response); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// scpiError is an entry of the SCPI error queue.
+type scpiError struct {
+	code int
+	msg  string
+}
+
+func (e *scpiError) Error() string {
+	return fmt.Sprintf("%d,\"%s\"", e.code, e.msg)
+}
+
+func undefinedHeader(header string) error {
+	return &scpiError{code: -113, msg: "Undefined header;" + header}
+}
+
+func illegalParameter(format string, args ...any) error {
+	return &scpiError{code: -224, msg: "Illegal parameter value;" + fmt.Sprintf(format, args...)}
+}
+
+// maxErrors is the size of the error queue, further errors are dropped
+const maxErrors = 16
+
+// scpiSession is the state of a client connection.
+type scpiSession struct {
+	server *scpiServer
+	errs   []*scpiError
+}
+
+// exec executes a program message and returns the responses of its queries joined by semicolons.
+// Failed commands are added to the error queue and don't respond.
+func (s *scpiSession) exec(line string) (string, bool) {
+	commands, err := scpi.Parse(strings.TrimSpace(line))
+	if err != nil {
+		s.push(&scpiError{code: -100, msg: "Command error;" + err.Error()})
+		return "", false
+	}
+
+	var responses []string
+	for _, cmd := range commands {
+		response, err := s.execCommand(cmd)
+		if err != nil {
+			s.push(err)
+			continue
+		}
+		if cmd.Query {
+			responses = append(responses, response)
+		}
+	}
+
+	return strings.Join(responses, ";"), len(responses) > 0
+}
+
+func (s *scpiSession) execCommand(cmd scpi.Command) (string, error) {
+	for _, h := range scpiHandlers {
+		suffixes, ok := scpi.Match(h.pattern, cmd.Header)
+		if !ok || (cmd.Query && h.query == nil) || (!cmd.Query && h.set == nil) {
+			continue
+		}
+
+		req := scpiRequest{session: s, d: s.server.d, suffixes: suffixes, args: cmd.Args}
+
+		s.server.mu.Lock()
+		defer s.server.mu.Unlock()
+
+		if cmd.Query {
+			return h.query(req)
+		}
+		return "", h.set(req)
+	}
+	return "", undefinedHeader(cmd.Header)
+}
+
+func (s *scpiSession) push(err error) {
+	var scpiErr *scpiError
+	if !errors.As(err, &scpiErr) {
+		scpiErr = &scpiError{code: -240, msg: "Hardware error;" + err.Error()}
+	}
+	if len(s.errs) < maxErrors {
+		s.errs = append(s.errs, scpiErr)
+	}
+}
+
+// scpiRequest is a matched command with its numeric header suffixes and arguments.
+type scpiRequest struct {
+	session  *scpiSession
+	d        Device
+	suffixes []int
+	args     []string
+}
+
+// id returns the numeric suffix of the header, e.g. the marker of CALC:MARK2:Y?.
+func (r scpiRequest) id() (uint, error) {
+	if len(r.suffixes) == 0 || r.suffixes[0] < 1 {
+		return 0, &scpiError{code: -114, msg: "Header suffix out of range"}
+	}
+	return uint(r.suffixes[0]), nil //nolint:gosec
+}
+
+// arg returns the single argument of the command.
+func (r scpiRequest) arg() (string, error) {
+	switch len(r.args) {
+	case 0:
+		return "", &scpiError{code: -109, msg: "Missing parameter"}
+	case 1:
+		return r.args[0], nil
+	default:
+		return "", &scpiError{code: -108, msg: "Parameter not allowed"}
+	}
+}
+
+func (r scpiRequest) frequency() (uint64, error) {
+	arg, err := r.arg()
+	if err != nil {
+		return 0, err
+	}
+	freq, err := parseSCPIFrequency(arg)
+	if err != nil {
+		return 0, illegalParameter("%s", arg)
+	}
+	return freq, nil
+}
+
+// scpiFrequencyUnits are the unit suffixes of frequencies, longest first. A plain M is milli in SCPI,
+// so only the suffixes with HZ are accepted.
+var scpiFrequencyUnits = []struct {
+	suffix     string
+	multiplier float64
+}{
+	{"GHZ", 1e9},
+	{"MHZ", 1e6},
+	{"KHZ", 1e3},
+	{"HZ", 1},
+}
+
+// parseSCPIFrequency parses a SCPI numeric value in Hz with an optional unit, e.g. 1.000000E+08, 1.5E+09 or
+// 433.92 MHz, as sent by instrument drivers. The value is rounded to whole Hz.
+func parseSCPIFrequency(arg string) (uint64, error) {
+	// SCPI allows whitespace between value and unit, e.g. "100 MHz"
+	value := strings.ToUpper(strings.ReplaceAll(arg, " ", ""))
+	multiplier := 1.0
+	for _, u := range scpiFrequencyUnits {
+		if num, ok := strings.CutSuffix(value, u.suffix); ok {
+			value, multiplier = num, u.multiplier
+			break
+		}
+	}
+
+	v, err := strconv.ParseFloat(value, 64)
+	if err != nil || math.IsNaN(v) || math.IsInf(v, 0) {
+		return 0, fmt.Errorf("invalid frequency '%s'", arg)
+	}
+	freq := math.Round(v * multiplier)
+	if freq < 0 || freq >= math.MaxUint64 {
+		return 0, fmt.Errorf("frequency '%s' out of range", arg)
+	}
+	return uint64(freq), nil
+}
+
+func (r scpiRequest) uint() (uint, error) {
+	arg, err := r.arg()
+	if err != nil {
+		return 0, err
+	}
+	v, err := strconv.ParseUint(arg, 10, 0)
+	if err != nil {
+		return 0, illegalParameter("%s", arg)
+	}
+	return uint(v), nil
+}
+
+func (r scpiRequest) bool() (bool, error) {
+	arg, err := r.arg()
+	if err != nil {
+		return false, err
+	}
+	switch strings.ToUpper(arg) {
+	case "ON", "1":
+		return true, nil
+	case "OFF", "0":
+		return false, nil
+	}
+	return false, illegalParameter("%s", arg)
+}
+
+// trace returns the trace of TRAC:DATA? [TRACE1], defaulting to the header suffix.
+func (r scpiRequest) trace() (uint, error) {
+	if len(r.args) == 0 {
+		return r.id()
+	}
+	arg, err := r.arg()
+	if err != nil {
+		return 0, err
+	}
+	v, err := strconv.ParseUint(strings.TrimPrefix(strings.ToUpper(arg), "TRACE"), 10, 0)
+	if err != nil || v < 1 {
+		return 0, illegalParameter("%s", arg)
+	}
+	return uint(v), nil
+}
+
+type scpiHandler struct {
+	// pattern is the header in the notation of scpi.Match
+	pattern string
+	set     func(r scpiRequest) error
+	query   func(r scpiRequest) (string, error)
+}
+
+var scpiHandlers = []scpiHandler{
+	{
+		pattern: "*IDN",
+		query: func(r scpiRequest) (string, error) {
+			// the device id is only available on newer firmwares
+			id, _ := r.d.GetDeviceID()
+			return fmt.Sprintf("tinySA,%s,%d,%s", r.d.Model(), id, r.d.Version()), nil
+		},
+	},
+	{
+		pattern: "*OPC",
+		query:   func(scpiRequest) (string, error) { return "1", nil },
+	},
+	{
+		pattern: "*CLS",
+		set: func(r scpiRequest) error {
+			r.session.errs = nil
+			return nil
+		},
+	},
+	{
+		pattern: "SYSTem:ERRor[:NEXT]",
+		query: func(r scpiRequest) (string, error) {
+			if len(r.session.errs) == 0 {
+				return `0,"No error"`, nil
+			}
+			err := r.session.errs[0]
+			r.session.errs = r.session.errs[1:]
+			return err.Error(), nil
+		},
+	},
+	{
+		pattern: "[SENSe:]FREQuency:STARt",
+		set:     setSweepFreq(func(d Device, freq uint64) error { return d.SetSweepStart(freq) }),
+		query:   getSweepFreq(func(s tinysa.Sweep) uint64 { return s.Start }),
+	},
+	{
+		pattern: "[SENSe:]FREQuency:STOP",
+		set:     setSweepFreq(func(d Device, freq uint64) error { return d.SetSweepStop(freq) }),
+		query:   getSweepFreq(func(s tinysa.Sweep) uint64 { return s.Stop }),
+	},
+	{
+		pattern: "[SENSe:]FREQuency:CENTer",
+		set:     setSweepFreq(func(d Device, freq uint64) error { return d.SetSweepCenter(freq) }),
+		query:   getSweepFreq(func(s tinysa.Sweep) uint64 { return (s.Start + s.Stop) / 2 }),
+	},
+	{
+		pattern: "[SENSe:]FREQuency:SPAN",
+		set:     setSweepFreq(func(d Device, freq uint64) error { return d.SetSweepSpan(freq) }),
+		query:   getSweepFreq(func(s tinysa.Sweep) uint64 { return s.Stop - s.Start }),
+	},
+	{
+		pattern: "[SENSe:]SWEep:POINts",
+		set: func(r scpiRequest) error {
+			points, err := r.uint()
+			if err != nil {
+				return err
+			}
+			return r.d.SetSweepPoints(points)
+		},
+		query: func(r scpiRequest) (string, error) {
+			sweep, err := r.d.GetSweep()
+			if err != nil {
+				return "", err
+			}
+			return strconv.FormatUint(uint64(sweep.Points), 10), nil
+		},
+	},
+	{
+		pattern: "INITiate:CONTinuous",
+		set: func(r scpiRequest) error {
+			on, err := r.bool()
+			if err != nil {
+				return err
+			}
+			if on {
+				return r.d.ResumeSweep()
+			}
+			return r.d.PauseSweep()
+		},
+		query: func(r scpiRequest) (string, error) {
+			status, err := r.d.GetSweepStatus()
+			if err != nil {
+				return "", err
+			}
+			return formatScpiBool(status == tinysa.SweepStatusResumed), nil
+		},
+	},
+	{
+		pattern: "CALCulate:MARKer#[:STATe]",
+		set: func(r scpiRequest) error {
+			id, err := r.id()
+			if err != nil {
+				return err
+			}
+			on, err := r.bool()
+			if err != nil {
+				return err
+			}
+			if on {
+				return r.d.EnableMarker(id)
+			}
+			return r.d.DisableMarker(id)
+		},
+	},
+	{
+		pattern: "CALCulate:MARKer#:X",
+		set: func(r scpiRequest) error {
+			id, err := r.id()
+			if err != nil {
+				return err
+			}
+			freq, err := r.frequency()
+			if err != nil {
+				return err
+			}
+			return r.d.SetMarkerFreq(id, freq)
+		},
+		query: getMarker(func(m tinysa.Marker) string { return strconv.FormatUint(m.Frequency, 10) }),
+	},
+	{
+		pattern: "CALCulate:MARKer#:Y",
+		query:   getMarker(func(m tinysa.Marker) string { return formatScpiFloat(m.Value) }),
+	},
+	{
+		pattern: "CALCulate:MARKer#:MAXimum[:PEAK]",
+		set: func(r scpiRequest) error {
+			id, err := r.id()
+			if err != nil {
+				return err
+			}
+			return r.d.MoveMarkerPeak(id)
+		},
+	},
+	{
+		pattern: "TRACe#[:DATA]",
+		query:   getTraceData(func(dp tinysa.TraceData) string { return formatScpiFloat(dp.Value) }),
+	},
+	{
+		pattern: "TRACe#[:DATA]:X",
+		query:   getTraceData(func(dp tinysa.TraceData) string { return strconv.FormatUint(dp.Frequency, 10) }),
+	},
+}
+
+func setSweepFreq(set func(d Device, freq uint64) error) func(r scpiRequest) error {
+	return func(r scpiRequest) error {
+		freq, err := r.frequency()
+		if err != nil {
+			return err
+		}
+		return set(r.d, freq)
+	}
+}
+
+func getSweepFreq(get func(s tinysa.Sweep) uint64) func(r scpiRequest) (string, error) {
+	return func(r scpiRequest) (string, error) {
+		sweep, err := r.d.GetSweep()
+		if err != nil {
+			return "", err
+		}
+		return strconv.FormatUint(get(sweep), 10), nil
+	}
+}
+
+func getMarker(format func(m tinysa.Marker) string) func(r scpiRequest) (string, error) {
+	return func(r scpiRequest) (string, error) {
+		id, err := r.id()
+		if err != nil {
+			return "", err
+		}
+		m, err := r.d.GetMarker(id)
+		if err != nil {
+			return "", err
+		}
+		return format(m), nil
+	}
+}
+
+func getTraceData(format func(dp tinysa.TraceData) string) func(r scpiRequest) (string, error) {
+	return func(r scpiRequest) (string, error) {
+		id, err := r.trace()
+		if err != nil {
+			return "", err
+		}
+		data, err := r.d.GetTraceData(id)
+		if err != nil {
+			return "", err
+		}
+		values := make([]string, len(data))
+		for i, dp := range data {
+			values[i] = format(dp)
+		}
+		return strings.Join(values, ","), nil
+	}
+}
+
+func formatScpiFloat(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
+
+func formatScpiBool(v bool) string {
+	if v {
+		return "1"
+	}
+	return "0"
+}
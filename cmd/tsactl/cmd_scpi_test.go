@@ -0,0 +1,154 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"testing"
+)
+
+func TestScpiSession(t *testing.T) {
+	tests := []struct {
+		name      string
+		lines     []string
+		setup     func(d *fakeDevice)
+		want      []string
+		wantCalls []string
+	}{
+		{
+			name:      "idn",
+			lines:     []string{"*IDN?"},
+			setup:     func(d *fakeDevice) { d.deviceID = 7 },
+			want:      []string{"tinySA,tinySA4,7,1.4-197-gaa78ccc"},
+			wantCalls: []string{"GetDeviceID()"},
+		},
+		{
+			name:      "set frequencies",
+			lines:     []string{"SENS:FREQ:CENT 433.92 MHz;SPAN 2e6", "freq:star?"},
+			want:      []string{"400000000"},
+			wantCalls: []string{"SetSweepCenter(433920000)", "SetSweepSpan(2000000)", "GetSweep()"},
+		},
+		{
+			name:      "exponents and units",
+			lines:     []string{"FREQ:STAR 1.000000E+08;STOP 1.5E+09;CENT 4.3392e+8 hz;SPAN 2.5E-3 GHz", "FREQ:STAR 100 kHz;STOP 433.92MHZ"},
+			wantCalls: []string{"SetSweepStart(100000000)", "SetSweepStop(1500000000)", "SetSweepCenter(433920000)", "SetSweepSpan(2500000)", "SetSweepStart(100000)", "SetSweepStop(433920000)"},
+		},
+		{
+			name:  "invalid frequencies",
+			lines: []string{"FREQ:STAR 1E+", "FREQ:STAR -1E+06", "FREQ:STAR 10 M", "FREQ:STAR INF", "SYST:ERR?;ERR?;ERR?;ERR?"},
+			want: []string{
+				`-224,"Illegal parameter value;1E+";-224,"Illegal parameter value;-1E+06";-224,"Illegal parameter value;10 M";-224,"Illegal parameter value;INF"`,
+			},
+		},
+		{
+			name:      "query frequencies",
+			lines:     []string{"FREQ:STAR?;STOP?;CENT?;SPAN?"},
+			want:      []string{"400000000;500000000;450000000;100000000"},
+			wantCalls: []string{"GetSweep()", "GetSweep()", "GetSweep()", "GetSweep()"},
+		},
+		{
+			name:      "sweep points",
+			lines:     []string{"SWE:POIN 290", "SWEEP:POINTS?"},
+			want:      []string{"450"},
+			wantCalls: []string{"SetSweepPoints(290)", "GetSweep()"},
+		},
+		{
+			name:      "continuous",
+			lines:     []string{"INIT:CONT OFF", "INIT:CONT?"},
+			want:      []string{"1"},
+			wantCalls: []string{"PauseSweep()", "GetSweepStatus()"},
+		},
+		{
+			name:      "marker",
+			lines:     []string{"CALC:MARK2 ON;MARK2:MAX;:CALC:MARK2:X?;Y?", "CALC:MARK:Y?"},
+			want:      []string{"447700000;-67.9", "-89.4"},
+			wantCalls: []string{"EnableMarker(2)", "MoveMarkerPeak(2)", "GetMarker(2)", "GetMarker(2)", "GetMarker(1)"},
+		},
+		{
+			name:      "trace data",
+			lines:     []string{"TRAC:DATA? TRACE2", "TRAC2?", "TRAC:DATA:X? 1"},
+			want:      []string{"-84.78,-35,-85.75", "-84.78,-35,-85.75", "400000000,450000000,500000000"},
+			wantCalls: []string{"GetTraceData(2)", "GetTraceData(2)", "GetTraceData(1)"},
+		},
+		{
+			name:  "error queue",
+			lines: []string{"FREQ:STAR abc;:FOO 1;SWE:POIN?", "SYST:ERR?", "SYST:ERR?", "SYST:ERR?"},
+			want: []string{
+				"450",
+				`-224,"Illegal parameter value;abc"`,
+				`-113,"Undefined header;FOO"`,
+				`0,"No error"`,
+			},
+			wantCalls: []string{"GetSweep()"},
+		},
+		{
+			name:      "device error",
+			lines:     []string{"TRAC:DATA?", "SYST:ERR?"},
+			setup:     func(d *fakeDevice) { d.errs = map[string]error{"GetTraceData": errors.New("timeout")} },
+			want:      []string{`-240,"Hardware error;timeout"`},
+			wantCalls: []string{"GetTraceData(1)"},
+		},
+		{
+			name:  "query only",
+			lines: []string{"CALC:MARK:Y -10", "*CLS", "SYST:ERR?"},
+			want:  []string{`0,"No error"`},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := newFakeDevice()
+			if tt.setup != nil {
+				tt.setup(d)
+			}
+			session := &scpiSession{server: newScpiServer(d)}
+
+			var got []string
+			for _, line := range tt.lines {
+				if response, ok := session.exec(line); ok {
+					got = append(got, response)
+				}
+			}
+
+			if strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+				t.Errorf("responses:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(tt.want, "\n"))
+			}
+			if got := strings.Join(d.calls, "\n"); got != strings.Join(tt.wantCalls, "\n") {
+				t.Errorf("calls:\n%s\nwant:\n%s", got, strings.Join(tt.wantCalls, "\n"))
+			}
+		})
+	}
+}
+
+func TestScpiServerConn(t *testing.T) {
+	client, conn := net.Pipe()
+	defer client.Close()
+
+	d := newFakeDevice()
+	done := make(chan struct{})
+	go func() {
+		newScpiServer(d).serve(conn)
+		close(done)
+	}()
+
+	// commands without response must not block the connection
+	if _, err := fmt.Fprint(client, "FREQ:CENT 1e8\r\nSWE:POIN?\r\n"); err != nil {
+		t.Fatal(err)
+	}
+	line, err := bufio.NewReader(client).ReadString('\n')
+	if err != nil {
+		t.Fatal(err)
+	}
+	if line != "450\n" {
+		t.Errorf("response = %q, want %q", line, "450\n")
+	}
+
+	_ = client.Close()
+	<-done
+
+	if got := strings.Join(d.calls, ","); got != "SetSweepCenter(100000000),GetSweep()" {
+		t.Errorf("calls = %s", got)
+	}
+}
@@ -0,0 +1,461 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image"
+	"image/png"
+	"io"
+	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/kkettinger/go-tinysa"
+)
+
+type ServeCmd struct {
+	Listen string `help:"Address to listen on" short:"l" default:":8080" placeholder:"ADDR"`
+}
+
+func (c *ServeCmd) Run(globals *Globals) error {
+	d, err := initDevice(globals)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+
+	return listenAndServe(c.Listen, newAPIServer(d).handler())
+}
+
+// listenAndServe serves handler on addr until interrupted.
+func listenAndServe(addr string, handler http.Handler) error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	srv := &http.Server{Addr: addr, Handler: handler, ReadHeaderTimeout: 10 * time.Second}
+
+	errCh := make(chan error, 1)
+	go func() { errCh <- srv.ListenAndServe() }()
+
+	_, _ = fmt.Fprintf(stdout, "listening on %s, press Ctrl-C to stop\n", addr)
+
+	select {
+	case err := <-errCh:
+		return fmt.Errorf("failed to listen on %s: %w", addr, err)
+	case <-ctx.Done():
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	return srv.Shutdown(shutdownCtx)
+}
+
+// apiServer provides the device operations as JSON/HTTP endpoints.
+type apiServer struct {
+	// mu serializes the access to the device, whose commands must not interleave
+	mu sync.Mutex
+	d  Device
+}
+
+// httpError is an error with the HTTP status code of the response.
+type httpError struct {
+	status int
+	err    error
+}
+
+func (e *httpError) Error() string {
+	return e.err.Error()
+}
+
+func badRequest(format string, args ...any) error {
+	return &httpError{status: http.StatusBadRequest, err: fmt.Errorf(format, args...)}
+}
+
+type apiSweepRequest struct {
+	Start  *uint64 `json:"start"`
+	Stop   *uint64 `json:"stop"`
+	Center *uint64 `json:"center"`
+	Span   *uint64 `json:"span"`
+	Points *uint   `json:"points"`
+	Mode   *string `json:"mode"`
+	Paused *bool   `json:"paused"`
+}
+
+type apiMarkerRequest struct {
+	Enabled   *bool   `json:"enabled"`
+	Trace     *uint   `json:"trace"`
+	Frequency *uint64 `json:"frequency"`
+	Peak      bool    `json:"peak"`
+	Tracking  *bool   `json:"tracking"`
+}
+
+type apiLevelRequest struct {
+	Unit         *string  `json:"unit"`
+	RefLevel     *int     `json:"ref_level"`
+	RefLevelAuto bool     `json:"ref_level_auto"`
+	Scale        *float64 `json:"scale"`
+	LNA          *bool    `json:"lna"`
+}
+
+type apiRawRequest struct {
+	Command string `json:"command"`
+}
+
+type apiRawResponse struct {
+	Response string `json:"response"`
+}
+
+type apiTracePoint struct {
+	Point     uint    `json:"point"`
+	Frequency uint64  `json:"frequency"`
+	Value     float64 `json:"value"`
+}
+
+type apiErrorResponse struct {
+	Error string `json:"error"`
+}
+
+func newAPIServer(d Device) *apiServer {
+	return &apiServer{d: d}
+}
+
+func (s *apiServer) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/device", s.handle(s.getDevice))
+	mux.HandleFunc("GET /api/sweep", s.handle(s.getSweep))
+	mux.HandleFunc("PUT /api/sweep", s.handle(s.setSweep))
+	mux.HandleFunc("GET /api/markers", s.handle(s.getMarkers))
+	mux.HandleFunc("GET /api/markers/{id}", s.handle(s.getMarker))
+	mux.HandleFunc("PUT /api/markers/{id}", s.handle(s.setMarker))
+	mux.HandleFunc("GET /api/traces", s.handle(s.getTraces))
+	mux.HandleFunc("GET /api/traces/{id}/data", s.handle(s.getTraceData))
+	mux.HandleFunc("GET /api/capture", s.handle(s.getCapture))
+	mux.HandleFunc("PUT /api/level", s.handle(s.setLevel))
+	mux.HandleFunc("POST /api/presets/{id}/load", s.handle(s.loadPreset))
+	mux.HandleFunc("POST /api/presets/{id}/save", s.handle(s.savePreset))
+	mux.HandleFunc("POST /api/raw", s.handle(s.raw))
+	return mux
+}
+
+// handle serializes the device access of fn and writes its result as JSON, or PNG for images.
+func (s *apiServer) handle(fn func(r *http.Request) (any, error)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// read the request body first, so slow clients don't block the device
+		body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, 1<<20))
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, apiErrorResponse{Error: err.Error()})
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+
+		s.mu.Lock()
+		v, err := fn(r)
+		s.mu.Unlock()
+
+		if err != nil {
+			status := http.StatusInternalServerError
+			var httpErr *httpError
+			if errors.As(err, &httpErr) {
+				status = httpErr.status
+			}
+			writeJSON(w, status, apiErrorResponse{Error: err.Error()})
+			return
+		}
+
+		switch v := v.(type) {
+		case nil:
+			w.WriteHeader(http.StatusNoContent)
+		case image.Image:
+			w.Header().Set("Content-Type", "image/png")
+			_ = png.Encode(w, v)
+		default:
+			writeJSON(w, http.StatusOK, v)
+		}
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func decodeRequest(r *http.Request, v any) error {
+	dec := json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil {
+		return badRequest("invalid request body: %v", err)
+	}
+	return nil
+}
+
+func pathID(r *http.Request) (uint, error) {
+	id, err := strconv.ParseUint(r.PathValue("id"), 10, 0)
+	if err != nil {
+		return 0, badRequest("invalid id '%s'", r.PathValue("id"))
+	}
+	return uint(id), nil
+}
+
+func (s *apiServer) getDevice(*http.Request) (any, error) {
+	return deviceInfo{
+		Model:    string(s.d.Model()),
+		Firmware: s.d.Version(),
+		Hardware: s.d.HardwareVersion(),
+	}, nil
+}
+
+func (s *apiServer) getSweep(*http.Request) (any, error) {
+	state, err := s.d.GetSweepStatus()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get sweep status: %w", err)
+	}
+	sweep, err := s.d.GetSweep()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get sweep: %w", err)
+	}
+	return newSweepInfo(state, sweep), nil
+}
+
+func (s *apiServer) setSweep(r *http.Request) (any, error) {
+	var req apiSweepRequest
+	if err := decodeRequest(r, &req); err != nil {
+		return nil, err
+	}
+
+	var ops []func() error
+	if req.Mode != nil {
+		mode, ok := tinysa.SweepModeFromString(*req.Mode)
+		if !ok {
+			return nil, badRequest("invalid sweep mode '%s', must be one of: %s",
+				*req.Mode, strings.Join(tinysa.SweepModeOptions(), ", "))
+		}
+		ops = append(ops, func() error { return s.d.SetSweepMode(mode) })
+	}
+	if req.Start != nil {
+		ops = append(ops, func() error { return s.d.SetSweepStart(*req.Start) })
+	}
+	if req.Stop != nil {
+		ops = append(ops, func() error { return s.d.SetSweepStop(*req.Stop) })
+	}
+	if req.Span != nil {
+		ops = append(ops, func() error { return s.d.SetSweepSpan(*req.Span) })
+	}
+	if req.Center != nil {
+		ops = append(ops, func() error { return s.d.SetSweepCenter(*req.Center) })
+	}
+	if req.Points != nil {
+		ops = append(ops, func() error { return s.d.SetSweepPoints(*req.Points) })
+	}
+	if req.Paused != nil {
+		if *req.Paused {
+			ops = append(ops, s.d.PauseSweep)
+		} else {
+			ops = append(ops, s.d.ResumeSweep)
+		}
+	}
+
+	for _, op := range ops {
+		if err := op(); err != nil {
+			return nil, fmt.Errorf("failed to set sweep: %w", err)
+		}
+	}
+
+	return s.getSweep(r)
+}
+
+func (s *apiServer) getMarkers(*http.Request) (any, error) {
+	markers, err := s.d.GetMarkerAll()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get markers: %w", err)
+	}
+	infos := make([]markerInfo, len(markers))
+	for i, m := range markers {
+		infos[i] = newMarkerInfo(m)
+	}
+	return infos, nil
+}
+
+func (s *apiServer) getMarker(r *http.Request) (any, error) {
+	id, err := pathID(r)
+	if err != nil {
+		return nil, err
+	}
+	m, err := s.d.GetMarker(id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get marker %d: %w", id, err)
+	}
+	return newMarkerInfo(m), nil
+}
+
+func (s *apiServer) setMarker(r *http.Request) (any, error) {
+	id, err := pathID(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var req apiMarkerRequest
+	if err := decodeRequest(r, &req); err != nil {
+		return nil, err
+	}
+
+	if req.Enabled != nil && !*req.Enabled {
+		if err := s.d.DisableMarker(id); err != nil {
+			return nil, fmt.Errorf("failed to disable marker %d: %w", id, err)
+		}
+		return nil, nil
+	}
+
+	var ops []func() error
+	if req.Enabled != nil {
+		ops = append(ops, func() error { return s.d.EnableMarker(id) })
+	}
+	if req.Trace != nil {
+		ops = append(ops, func() error { return s.d.SetMarkerTrace(id, *req.Trace) })
+	}
+	if req.Frequency != nil {
+		ops = append(ops, func() error { return s.d.SetMarkerFreq(id, *req.Frequency) })
+	}
+	if req.Peak {
+		ops = append(ops, func() error { return s.d.MoveMarkerPeak(id) })
+	}
+	if req.Tracking != nil {
+		if *req.Tracking {
+			ops = append(ops, func() error { return s.d.EnableMarkerTracking(id) })
+		} else {
+			ops = append(ops, func() error { return s.d.DisableMarkerTracking(id) })
+		}
+	}
+
+	for _, op := range ops {
+		if err := op(); err != nil {
+			return nil, fmt.Errorf("failed to set marker %d: %w", id, err)
+		}
+	}
+
+	return s.getMarker(r)
+}
+
+func (s *apiServer) getTraces(*http.Request) (any, error) {
+	traces, err := s.d.GetTraceAll()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get traces: %w", err)
+	}
+	infos := make([]traceInfo, len(traces))
+	for i, t := range traces {
+		infos[i] = newTraceInfo(t)
+	}
+	return infos, nil
+}
+
+func (s *apiServer) getTraceData(r *http.Request) (any, error) {
+	id, err := pathID(r)
+	if err != nil {
+		return nil, err
+	}
+	data, err := s.d.GetTraceData(id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get trace data: %w", err)
+	}
+	points := make([]apiTracePoint, len(data))
+	for i, dp := range data {
+		points[i] = apiTracePoint{Point: dp.Point, Frequency: dp.Frequency, Value: dp.Value}
+	}
+	return points, nil
+}
+
+func (s *apiServer) getCapture(*http.Request) (any, error) {
+	img, err := s.d.Capture()
+	if err != nil {
+		return nil, fmt.Errorf("failed to capture screen: %w", err)
+	}
+	return img, nil
+}
+
+func (s *apiServer) setLevel(r *http.Request) (any, error) {
+	var req apiLevelRequest
+	if err := decodeRequest(r, &req); err != nil {
+		return nil, err
+	}
+	if req.RefLevel != nil && req.RefLevelAuto {
+		return nil, badRequest("only one of ref_level or ref_level_auto can be set")
+	}
+
+	var ops []func() error
+	if req.Unit != nil {
+		unit, ok := tinysa.TraceUnitFromString(*req.Unit)
+		if !ok {
+			return nil, badRequest("invalid trace unit '%s', must be one of: %s",
+				*req.Unit, strings.Join(tinysa.TraceUnitOptions(), ", "))
+		}
+		ops = append(ops, func() error { return s.d.SetTraceUnit(unit) })
+	}
+	if req.RefLevel != nil {
+		ops = append(ops, func() error { return s.d.SetTraceRefLevel(*req.RefLevel) })
+	}
+	if req.RefLevelAuto {
+		ops = append(ops, s.d.SetTraceRefLevelAuto)
+	}
+	if req.Scale != nil {
+		ops = append(ops, func() error { return s.d.SetTraceScale(*req.Scale) })
+	}
+	if req.LNA != nil {
+		if *req.LNA {
+			ops = append(ops, s.d.EnableLNA)
+		} else {
+			ops = append(ops, s.d.DisableLNA)
+		}
+	}
+
+	for _, op := range ops {
+		if err := op(); err != nil {
+			return nil, fmt.Errorf("failed to set level: %w", err)
+		}
+	}
+	return nil, nil
+}
+
+func (s *apiServer) loadPreset(r *http.Request) (any, error) {
+	id, err := pathID(r)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.d.LoadPreset(id); err != nil {
+		return nil, fmt.Errorf("failed to load preset %d: %w", id, err)
+	}
+	return nil, nil
+}
+
+func (s *apiServer) savePreset(r *http.Request) (any, error) {
+	id, err := pathID(r)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.d.SavePreset(id); err != nil {
+		return nil, fmt.Errorf("failed to save preset %d: %w", id, err)
+	}
+	return nil, nil
+}
+
+func (s *apiServer) raw(r *http.Request) (any, error) {
+	var req apiRawRequest
+	if err := decodeRequest(r, &req); err != nil {
+		return nil, err
+	}
+	if strings.TrimSpace(req.Command) == "" {
+		return nil, badRequest("command must not be empty")
+	}
+	response, err := s.d.SendCommand(req.Command)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send raw command: %w", err)
+	}
+	return apiRawResponse{Response: response}, nil
+}
@@ -0,0 +1,276 @@
+package main
+
+import (
+	"errors"
+	"image/png"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/kkettinger/go-tinysa"
+)
+
+func TestAPIServer(t *testing.T) {
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		body       string
+		setup      func(d *fakeDevice)
+		wantStatus int
+		wantBody   string
+		wantCalls  []string
+	}{
+		{
+			name:       "device",
+			method:     "GET",
+			path:       "/api/device",
+			wantStatus: http.StatusOK,
+			wantBody:   `{"model":"tinySA4","firmware":"1.4-197-gaa78ccc","hardware":"0.4.5.1"}`,
+		},
+		{
+			name:       "get sweep",
+			method:     "GET",
+			path:       "/api/sweep",
+			wantStatus: http.StatusOK,
+			wantBody:   `{"status":"resumed","start":400000000,"stop":500000000,"center":450000000,"span":100000000,"points":450}`,
+			wantCalls:  []string{"GetSweepStatus()", "GetSweep()"},
+		},
+		{
+			name:       "set sweep",
+			method:     "PUT",
+			path:       "/api/sweep",
+			body:       `{"center":433920000,"span":2000000,"paused":true}`,
+			wantStatus: http.StatusOK,
+			wantBody:   `{"status":"resumed","start":400000000,"stop":500000000,"center":450000000,"span":100000000,"points":450}`,
+			wantCalls: []string{
+				"SetSweepSpan(2000000)", "SetSweepCenter(433920000)", "PauseSweep()",
+				"GetSweepStatus()", "GetSweep()",
+			},
+		},
+		{
+			name:       "set sweep invalid mode",
+			method:     "PUT",
+			path:       "/api/sweep",
+			body:       `{"mode":"turbo"}`,
+			wantStatus: http.StatusBadRequest,
+			wantBody:   `{"error":"invalid sweep mode 'turbo', must be one of: ` + strings.Join(tinysa.SweepModeOptions(), ", ") + `"}`,
+		},
+		{
+			name:       "set sweep unknown field",
+			method:     "PUT",
+			path:       "/api/sweep",
+			body:       `{"centre":1}`,
+			wantStatus: http.StatusBadRequest,
+			wantBody:   `{"error":"invalid request body: json: unknown field \"centre\""}`,
+		},
+		{
+			name:       "markers",
+			method:     "GET",
+			path:       "/api/markers",
+			wantStatus: http.StatusOK,
+			wantBody: `[{"marker":1,"frequency":410000000,"value":-89.4,"index":45},` +
+				`{"marker":2,"frequency":447700000,"value":-67.9,"index":214}]`,
+			wantCalls: []string{"GetMarkerAll()"},
+		},
+		{
+			name:       "set marker",
+			method:     "PUT",
+			path:       "/api/markers/2",
+			body:       `{"enabled":true,"trace":1,"peak":true}`,
+			wantStatus: http.StatusOK,
+			wantBody:   `{"marker":2,"frequency":447700000,"value":-67.9,"index":214}`,
+			wantCalls:  []string{"EnableMarker(2)", "SetMarkerTrace(2, 1)", "MoveMarkerPeak(2)", "GetMarker(2)"},
+		},
+		{
+			name:       "disable marker",
+			method:     "PUT",
+			path:       "/api/markers/3",
+			body:       `{"enabled":false}`,
+			wantStatus: http.StatusNoContent,
+			wantCalls:  []string{"DisableMarker(3)"},
+		},
+		{
+			name:       "invalid marker id",
+			method:     "GET",
+			path:       "/api/markers/x",
+			wantStatus: http.StatusBadRequest,
+			wantBody:   `{"error":"invalid id 'x'"}`,
+		},
+		{
+			name:       "traces",
+			method:     "GET",
+			path:       "/api/traces",
+			wantStatus: http.StatusOK,
+			wantBody:   `[{"trace":1,"unit":"dBm","scale":10,"refpos":-10}]`,
+			wantCalls:  []string{"GetTraceAll()"},
+		},
+		{
+			name:       "trace data",
+			method:     "GET",
+			path:       "/api/traces/2/data",
+			wantStatus: http.StatusOK,
+			wantBody: `[{"point":0,"frequency":400000000,"value":-84.78},` +
+				`{"point":1,"frequency":450000000,"value":-35},` +
+				`{"point":2,"frequency":500000000,"value":-85.75}]`,
+			wantCalls: []string{"GetTraceData(2)"},
+		},
+		{
+			name:       "device error",
+			method:     "GET",
+			path:       "/api/traces/1/data",
+			setup:      func(d *fakeDevice) { d.errs = map[string]error{"GetTraceData": errors.New("timeout")} },
+			wantStatus: http.StatusInternalServerError,
+			wantBody:   `{"error":"failed to get trace data: timeout"}`,
+			wantCalls:  []string{"GetTraceData(1)"},
+		},
+		{
+			name:       "level",
+			method:     "PUT",
+			path:       "/api/level",
+			body:       `{"unit":"dBuV","ref_level_auto":true,"scale":5,"lna":false}`,
+			wantStatus: http.StatusNoContent,
+			wantCalls:  []string{"SetTraceUnit(dBuV)", "SetTraceRefLevelAuto()", "SetTraceScale(5)", "DisableLNA()"},
+		},
+		{
+			name:       "level conflict",
+			method:     "PUT",
+			path:       "/api/level",
+			body:       `{"ref_level":-20,"ref_level_auto":true}`,
+			wantStatus: http.StatusBadRequest,
+			wantBody:   `{"error":"only one of ref_level or ref_level_auto can be set"}`,
+		},
+		{
+			name:       "load preset",
+			method:     "POST",
+			path:       "/api/presets/3/load",
+			wantStatus: http.StatusNoContent,
+			wantCalls:  []string{"LoadPreset(3)"},
+		},
+		{
+			name:       "save preset",
+			method:     "POST",
+			path:       "/api/presets/0/save",
+			wantStatus: http.StatusNoContent,
+			wantCalls:  []string{"SavePreset(0)"},
+		},
+		{
+			name:       "raw",
+			method:     "POST",
+			path:       "/api/raw",
+			body:       `{"command":"sd_list"}`,
+			setup:      func(d *fakeDevice) { d.responses["sd_list"] = "DECT.prs 1584" },
+			wantStatus: http.StatusOK,
+			wantBody:   `{"response":"DECT.prs 1584"}`,
+			wantCalls:  []string{"SendCommand(sd_list)"},
+		},
+		{
+			name:       "method not allowed",
+			method:     "DELETE",
+			path:       "/api/sweep",
+			wantStatus: http.StatusMethodNotAllowed,
+			wantBody:   "Method Not Allowed",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := newFakeDevice()
+			if tt.setup != nil {
+				tt.setup(d)
+			}
+			srv := httptest.NewServer(newAPIServer(d).handler())
+			defer srv.Close()
+
+			req, err := http.NewRequest(tt.method, srv.URL+tt.path, strings.NewReader(tt.body))
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer resp.Body.Close()
+			body, _ := io.ReadAll(resp.Body)
+
+			if resp.StatusCode != tt.wantStatus {
+				t.Errorf("status = %d, want %d", resp.StatusCode, tt.wantStatus)
+			}
+			if got := strings.TrimSpace(string(body)); got != tt.wantBody {
+				t.Errorf("body = %s, want %s", got, tt.wantBody)
+			}
+			if got := strings.Join(d.calls, "\n"); got != strings.Join(tt.wantCalls, "\n") {
+				t.Errorf("calls:\n%s\nwant:\n%s", got, strings.Join(tt.wantCalls, "\n"))
+			}
+		})
+	}
+}
+
+func TestAPIServerCapture(t *testing.T) {
+	srv := httptest.NewServer(newAPIServer(newFakeDevice()).handler())
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL + "/api/capture")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	if ct := resp.Header.Get("Content-Type"); ct != "image/png" {
+		t.Errorf("content type = %s", ct)
+	}
+	img, err := png.Decode(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if img.Bounds().Dx() != 480 || img.Bounds().Dy() != 320 {
+		t.Errorf("image size = %v", img.Bounds())
+	}
+}
+
+// overlapDevice records the maximum number of concurrent trace data requests.
+type overlapDevice struct {
+	*fakeDevice
+	active, max atomic.Int32
+}
+
+func (d *overlapDevice) GetTraceData(traceID uint) ([]tinysa.TraceData, error) {
+	n := d.active.Add(1)
+	defer d.active.Add(-1)
+	if n > d.max.Load() {
+		d.max.Store(n)
+	}
+	time.Sleep(time.Millisecond)
+	return d.fakeDevice.GetTraceData(traceID)
+}
+
+func TestAPIServerSerialized(t *testing.T) {
+	d := &overlapDevice{fakeDevice: newFakeDevice()}
+	srv := httptest.NewServer(newAPIServer(d).handler())
+	defer srv.Close()
+
+	var wg sync.WaitGroup
+	for range 20 {
+		wg.Go(func() {
+			resp, err := http.Get(srv.URL + "/api/traces/1/data")
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			_ = resp.Body.Close()
+		})
+	}
+	wg.Wait()
+
+	if got := d.max.Load(); got != 1 {
+		t.Errorf("max concurrent device calls = %d, want 1", got)
+	}
+	if got := len(d.calls); got != 20 {
+		t.Errorf("calls = %d, want 20", got)
+	}
+}
@@ -0,0 +1,309 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/alecthomas/kong"
+	"github.com/kkettinger/tsactl/internal/util"
+	"golang.org/x/term"
+)
+
+type ShellCmd struct {
+	History string `help:"History file" type:"path" default:"~/.tsactl_history" placeholder:"PATH"`
+}
+
+// errSessionExit is raised by kong when a command line inside a session requests to exit, e.g. on --help.
+var errSessionExit = errors.New("session exit")
+
+// sessionDevice shares the connection of a session between commands, which would close it otherwise.
+type sessionDevice struct {
+	Device
+}
+
+func (d sessionDevice) Close() error {
+	return nil
+}
+
+func (c *ShellCmd) Run(globals *Globals) error {
+	d, err := initDevice(globals)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+
+	globals.device = sessionDevice{d}
+
+	// read plain lines without prompt, e.g. when commands are piped into the shell
+	f, ok := stdin.(*os.File)
+	if !ok || !term.IsTerminal(int(f.Fd())) {
+		return c.runLines(globals, stdin)
+	}
+
+	state, err := term.MakeRaw(int(f.Fd()))
+	if err != nil {
+		return fmt.Errorf("failed to set terminal raw mode: %w", err)
+	}
+	defer func() { _ = term.Restore(int(f.Fd()), state) }()
+
+	t := term.NewTerminal(struct {
+		io.Reader
+		io.Writer
+	}{f, stdout}, "")
+	t.AutoCompleteCallback = newCompleter().complete
+	c.loadHistory(t)
+
+	// commands print to the terminal, which translates newlines in raw mode
+	out := stdout
+	stdout = t
+	defer func() { stdout = out }()
+
+	// the wait directive of scripts reads from the terminal as well, without the prompt
+	globals.readLine = func() (string, error) {
+		t.SetPrompt("")
+		return t.ReadLine()
+	}
+
+	for {
+		t.SetPrompt(shellPrompt(d))
+
+		line, err := t.ReadLine()
+		if errors.Is(err, io.EOF) {
+			_, _ = fmt.Fprintln(stdout)
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		c.appendHistory(line)
+		if !execLine(globals, line) {
+			return nil
+		}
+	}
+}
+
+func (c *ShellCmd) runLines(globals *Globals, r io.Reader) error {
+	globals.readLine = newLineReader(r)
+	for {
+		line, err := globals.readLine()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if !execLine(globals, line) {
+			return nil
+		}
+	}
+}
+
+// newLineReader returns a function reading the lines of r without line endings. The last line
+// doesn't need a line ending.
+func newLineReader(r io.Reader) func() (string, error) {
+	br := bufio.NewReader(r)
+	return func() (string, error) {
+		line, err := br.ReadString('\n')
+		if errors.Is(err, io.EOF) && line != "" {
+			err = nil
+		}
+		return strings.TrimRight(line, "\r\n"), err
+	}
+}
+
+// execLine runs a single line of the shell and reports whether the shell should continue.
+func execLine(globals *Globals, line string) bool {
+	args, err := splitArgs(line)
+	if err != nil {
+		_, _ = fmt.Fprintln(stdout, "error:", err)
+		return true
+	}
+
+	if len(args) == 0 {
+		return true
+	}
+
+	switch args[0] {
+	case "exit", "quit":
+		return false
+	case "help":
+		args = append(args[1:], "--help")
+	}
+
+	if err := execArgs(globals, args); err != nil {
+		_, _ = fmt.Fprintln(stdout, "error:", err)
+	}
+	return true
+}
+
+// execArgs parses and runs a tsactl command line on the device of the current session.
+func execArgs(globals *Globals, args []string) (err error) {
+	var c Cli
+	parser, err := newParser(&c, kong.Exit(func(int) { panic(errSessionExit) }))
+	if err != nil {
+		return err
+	}
+
+	// kong exits after printing help or version, which must not end the session
+	defer func() {
+		if r := recover(); r != nil {
+			if r != errSessionExit {
+				panic(r)
+			}
+			err = nil
+		}
+	}()
+
+	// the session's output format and profile apply, unless overridden in args
+	c.config = globals.config
+	sessionArgs := []string{"--format", globals.Format}
+	if globals.Profile != "" {
+		sessionArgs = append(sessionArgs, "--profile", globals.Profile)
+	}
+	ctx, err := parser.Parse(append(sessionArgs, args...))
+	if err != nil {
+		return err
+	}
+
+	switch name := strings.Fields(ctx.Command())[0]; {
+	case name == "shell":
+		return fmt.Errorf("command '%s' is not available inside a session", name)
+	case name == "run" && globals.script:
+		// scripts calling themselves or each other would recurse endlessly
+		return fmt.Errorf("command '%s' is not available inside a script", name)
+	}
+
+	c.device = globals.device
+	c.script = globals.script
+	c.readLine = globals.readLine
+	return ctx.Run(&c.Globals)
+}
+
+// shellPrompt returns the prompt showing the current center frequency and span.
+func shellPrompt(d Device) string {
+	sweep, err := d.GetSweep()
+	if err != nil {
+		return "tsactl> "
+	}
+	center := (sweep.Start + sweep.Stop) / 2
+	span := sweep.Stop - sweep.Start
+	return fmt.Sprintf("tsactl [%s, span %s]> ", util.FormatFrequency(center), util.FormatFrequency(span))
+}
+
+func (c *ShellCmd) loadHistory(t *term.Terminal) {
+	data, err := os.ReadFile(c.History)
+	if err != nil {
+		return
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		if strings.TrimSpace(line) != "" {
+			t.History.Add(line)
+		}
+	}
+}
+
+func (c *ShellCmd) appendHistory(line string) {
+	if strings.TrimSpace(line) == "" {
+		return
+	}
+	file, err := os.OpenFile(c.History, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return
+	}
+	defer file.Close()
+	_, _ = fmt.Fprintln(file, line)
+}
+
+// completer completes command names and flags from the kong model.
+type completer struct {
+	app *kong.Application
+}
+
+func newCompleter() *completer {
+	var c Cli
+	parser, err := newParser(&c)
+	if err != nil {
+		panic(err)
+	}
+	return &completer{app: parser.Model}
+}
+
+// complete implements term.Terminal.AutoCompleteCallback for the tab key.
+func (c *completer) complete(line string, pos int, key rune) (string, int, bool) {
+	if key != '\t' {
+		return "", 0, false
+	}
+
+	head := line[:pos]
+	start := strings.LastIndexByte(head, ' ') + 1
+	word := head[start:]
+
+	var matches []string
+	for _, candidate := range c.candidates(strings.Fields(head[:start]), word) {
+		if strings.HasPrefix(candidate, word) {
+			matches = append(matches, candidate)
+		}
+	}
+	if len(matches) == 0 {
+		return "", 0, false
+	}
+
+	completion := commonPrefix(matches)
+	if len(matches) == 1 {
+		completion += " "
+	}
+	if completion == word {
+		return "", 0, false
+	}
+
+	return head[:start] + completion + line[pos:], start + len(completion), true
+}
+
+// candidates returns the possible completions of word, following the given preceding words.
+func (c *completer) candidates(words []string, word string) []string {
+	node := c.app.Node
+	for _, w := range words {
+		for _, child := range node.Children {
+			if child.Type == kong.CommandNode && (child.Name == w || slices.Contains(child.Aliases, w)) {
+				node = child
+				break
+			}
+		}
+	}
+
+	var candidates []string
+	if strings.HasPrefix(word, "-") {
+		for _, group := range node.AllFlags(true) {
+			for _, flag := range group {
+				candidates = append(candidates, "--"+flag.Name)
+			}
+		}
+		return candidates
+	}
+
+	for _, child := range node.Children {
+		if child.Type == kong.CommandNode && !child.Hidden && child.Name != "shell" {
+			candidates = append(candidates, child.Name)
+		}
+	}
+	if node == c.app.Node {
+		candidates = append(candidates, "help", "exit", "quit")
+	}
+	return candidates
+}
+
+func commonPrefix(words []string) string {
+	prefix := words[0]
+	for _, w := range words[1:] {
+		for !strings.HasPrefix(w, prefix) {
+			prefix = prefix[:len(prefix)-1]
+		}
+	}
+	return prefix
+}
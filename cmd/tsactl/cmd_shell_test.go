@@ -0,0 +1,162 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestShellCmd(t *testing.T) {
+	tests := []struct {
+		name      string
+		args      []string
+		input     string
+		wantOut   string
+		wantCalls []string
+	}{
+		{
+			name:    "commands share one connection",
+			input:   "sweep --center 450mhz\n\nmarker 1 --peak\n",
+			wantOut: "set sweep center frequency to 450 MHz\nset marker #1 to peak\n",
+			wantCalls: []string{
+				"SetSweepCenter(450000000)",
+				"MoveMarkerPeak(1)",
+				"Close()",
+			},
+		},
+		{
+			name:      "errors do not end the session",
+			input:     "bogus\nsweep -p\n",
+			wantOut:   "error: unexpected argument bogus\npause sweep\n",
+			wantCalls: []string{"PauseSweep()", "Close()"},
+		},
+		{
+			name:      "exit",
+			input:     "exit\nsweep -p\n",
+			wantCalls: []string{"Close()"},
+		},
+		{
+			name:      "nested shell",
+			input:     "shell\n",
+			wantOut:   "error: command 'shell' is not available inside a session\n",
+			wantCalls: []string{"Close()"},
+		},
+		{
+			name:  "session format",
+			args:  []string{"--format", "json", "shell"},
+			input: "marker 1\nmarker 2 -F text\n",
+			wantOut: "{\n  \"marker\": 1,\n  \"frequency\": 410000000,\n  \"value\": -89.4,\n  \"index\": 45\n}\n" +
+				"  Marker 2:   447.7 MHz   -67.9   (Index 214)\n",
+			wantCalls: []string{"GetMarker(1)", "GetMarker(2)", "Close()"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stdin = strings.NewReader(tt.input)
+			t.Cleanup(func() { stdin = nil })
+
+			d := newFakeDevice()
+			args := tt.args
+			if args == nil {
+				args = []string{"shell"}
+			}
+
+			out, err := runCli(t, d, args...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if out != tt.wantOut {
+				t.Errorf("output:\n%s\nwant:\n%s", out, tt.wantOut)
+			}
+			if got := strings.Join(d.calls, "\n"); got != strings.Join(tt.wantCalls, "\n") {
+				t.Errorf("calls:\n%s\nwant:\n%s", got, strings.Join(tt.wantCalls, "\n"))
+			}
+		})
+	}
+}
+
+func TestShellHelp(t *testing.T) {
+	stdin = strings.NewReader("help\nsweep --help\nsweep -r\n")
+	t.Cleanup(func() { stdin = nil })
+
+	d := newFakeDevice()
+	out, err := runCli(t, d, "shell")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out, "Usage: tsactl <command>") || !strings.Contains(out, "Usage: tsactl sweep") {
+		t.Errorf("help output missing:\n%s", out)
+	}
+	if got := strings.Join(d.calls, ","); got != "ResumeSweep(),Close()" {
+		t.Errorf("calls = %s", got)
+	}
+}
+
+func TestShellPrompt(t *testing.T) {
+	if got, want := shellPrompt(newFakeDevice()), "tsactl [450 MHz, span 100 MHz]> "; got != want {
+		t.Errorf("shellPrompt() = %q, want %q", got, want)
+	}
+}
+
+func TestCompleterComplete(t *testing.T) {
+	tests := []struct {
+		line   string
+		want   string
+		wantOk bool
+	}{
+		{line: "sw", want: "sweep ", wantOk: true},
+		{line: "s", wantOk: false},
+		{line: "sa", want: "save ", wantOk: true},
+		{line: "sweep --cen", want: "sweep --center", wantOk: true},
+		{line: "sweep --center-m", want: "sweep --center-marker ", wantOk: true},
+		{line: "mk 1 --pe", want: "mk 1 --peak ", wantOk: true},
+		{line: "sweep --form", want: "sweep --format ", wantOk: true},
+		{line: "exi", want: "exit ", wantOk: true},
+		{line: "sweep --xyz", wantOk: false},
+	}
+
+	c := newCompleter()
+	for _, tt := range tests {
+		t.Run(tt.line, func(t *testing.T) {
+			got, pos, ok := c.complete(tt.line, len(tt.line), '\t')
+			if ok != tt.wantOk {
+				t.Fatalf("ok = %v, want %v", ok, tt.wantOk)
+			}
+			if got != tt.want {
+				t.Errorf("line = %q, want %q", got, tt.want)
+			}
+			if ok && pos != len(tt.want) {
+				t.Errorf("pos = %d, want %d", pos, len(tt.want))
+			}
+		})
+	}
+}
+
+func TestSplitArgs(t *testing.T) {
+	tests := []struct {
+		line    string
+		want    []string
+		wantErr bool
+	}{
+		{line: "", want: nil},
+		{line: "  sweep   --center +2mhz ", want: []string{"sweep", "--center", "+2mhz"}},
+		{line: `save -t 1 -o "my trace.csv"`, want: []string{"save", "-t", "1", "-o", "my trace.csv"}},
+		{line: `raw 'a "b"' c\ d`, want: []string{"raw", `a "b"`, "c d"}},
+		{line: `raw ""`, want: []string{"raw", ""}},
+		{line: `raw "open`, wantErr: true},
+		{line: `raw \`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.line, func(t *testing.T) {
+			got, err := splitArgs(tt.line)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("splitArgs() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -3,7 +3,6 @@ package main
 import (
 	"fmt"
 	"github.com/alecthomas/kong"
-	"github.com/kkettinger/go-tinysa"
 )
 
 type SignalCmd struct {
@@ -11,7 +10,7 @@ type SignalCmd struct {
 }
 
 func (c *SignalCmd) Run(globals *Globals, ctx *kong.Context) error {
-	var ops []func(*tinysa.Device) error
+	var ops []func(Device) error
 
 	if c.Spur.Valid {
 		switch {
@@ -29,6 +28,7 @@ func (c *SignalCmd) Run(globals *Globals, ctx *kong.Context) error {
 		if err != nil {
 			return err
 		}
+		defer d.Close()
 
 		for _, op := range ops {
 			if err := op(d); err != nil {
@@ -43,24 +43,24 @@ func (c *SignalCmd) Run(globals *Globals, ctx *kong.Context) error {
 	return nil
 }
 
-func (c *SignalCmd) EnableSpur(d *tinysa.Device) error {
-	fmt.Println("enable spur removal")
+func (c *SignalCmd) EnableSpur(d Device) error {
+	_, _ = fmt.Fprintln(stdout, "enable spur removal")
 	if err := d.EnableSpurRemoval(); err != nil {
 		return fmt.Errorf("failed to enable spur removal: %w", err)
 	}
 	return nil
 }
 
-func (c *SignalCmd) DisableSpur(d *tinysa.Device) error {
-	fmt.Println("disable spur removal")
+func (c *SignalCmd) DisableSpur(d Device) error {
+	_, _ = fmt.Fprintln(stdout, "disable spur removal")
 	if err := d.DisableSpurRemoval(); err != nil {
 		return fmt.Errorf("failed to disable spur removal: %w", err)
 	}
 	return nil
 }
 
-func (c *SignalCmd) EnableAutoSpur(d *tinysa.Device) error {
-	fmt.Println("enable auto spur removal")
+func (c *SignalCmd) EnableAutoSpur(d Device) error {
+	_, _ = fmt.Fprintln(stdout, "enable auto spur removal")
 	if err := d.EnableAutoSpurRemoval(); err != nil {
 		return fmt.Errorf("failed to enable auto spur removal: %w", err)
 	}
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestSignalCmd(t *testing.T) {
+	runCliTests(t, []cliTest{
+		{
+			name:      "spur on",
+			args:      []string{"signal", "--spur", "on"},
+			wantOut:   "enable spur removal\n",
+			wantCalls: []string{"EnableSpurRemoval()", "Close()"},
+		},
+		{
+			name:      "spur off",
+			args:      []string{"signal", "--spur", "OFF"},
+			wantOut:   "disable spur removal\n",
+			wantCalls: []string{"DisableSpurRemoval()", "Close()"},
+		},
+		{
+			name:      "spur auto",
+			args:      []string{"signal", "--spur", "auto"},
+			wantOut:   "enable auto spur removal\n",
+			wantCalls: []string{"EnableAutoSpurRemoval()", "Close()"},
+		},
+		{
+			name:    "invalid spur",
+			args:    []string{"signal", "--spur", "maybe"},
+			wantErr: true,
+		},
+	})
+}
@@ -18,10 +18,12 @@ type SweepCmd struct {
 	Points       *uint        `help:"Number of sweep points" short:"n" group:"Sweep flags:"`
 	Time         Time         `help:"Sweep time" short:"t" group:"Sweep flags:"`
 	CW           Frequency    `help:"Set continuous wave frequency" group:"Sweep flags:" placeholder:"FREQ"`
+
+	OutputFlag `embed:""`
 }
 
 func (c *SweepCmd) Run(globals *Globals) error {
-	var ops []func(*tinysa.Device) error
+	var ops []func(Device) error
 
 	if c.Pause {
 		ops = append(ops, c.PauseSweep)
@@ -71,6 +73,7 @@ func (c *SweepCmd) Run(globals *Globals) error {
 	if err != nil {
 		return err
 	}
+	defer d.Close()
 
 	if len(ops) > 0 {
 		for _, op := range ops {
@@ -81,10 +84,10 @@ func (c *SweepCmd) Run(globals *Globals) error {
 		return nil
 	}
 
-	return c.Status(d)
+	return c.Status(d, c.outputFormat(globals))
 }
 
-func (c *SweepCmd) Status(d *tinysa.Device) error {
+func (c *SweepCmd) Status(d Device, format string) error {
 	state, err := d.GetSweepStatus()
 	if err != nil {
 		return err
@@ -95,47 +98,62 @@ func (c *SweepCmd) Status(d *tinysa.Device) error {
 		return err
 	}
 
-	fmt.Printf("Status: %s\n", state)
+	if format != formatText {
+		return printStructured(format, newSweepInfo(state, sweep))
+	}
+
+	_, _ = fmt.Fprintf(stdout, "Status: %s\n", state)
 	if sweep.Start == sweep.Stop {
-		fmt.Printf("Frequency: %s (CW)\n",
+		_, _ = fmt.Fprintf(stdout, "Frequency: %s (CW)\n",
 			util.FormatFrequency(sweep.Start))
 	} else {
 		span := sweep.Stop - sweep.Start
 		center := (span / 2) + sweep.Start
-		fmt.Printf("Frequency: %s to %s (%d points)\n",
+		_, _ = fmt.Fprintf(stdout, "Frequency: %s to %s (%d points)\n",
 			util.FormatFrequency(sweep.Start), util.FormatFrequency(sweep.Stop), sweep.Points)
-		fmt.Printf("Center: %s\n", util.FormatFrequency(center))
-		fmt.Printf("Span: %s\n", util.FormatFrequency(span))
+		_, _ = fmt.Fprintf(stdout, "Center: %s\n", util.FormatFrequency(center))
+		_, _ = fmt.Fprintf(stdout, "Span: %s\n", util.FormatFrequency(span))
 	}
 
 	return nil
 }
 
-func (c *SweepCmd) PauseSweep(d *tinysa.Device) error {
-	fmt.Println("pause sweep")
+func newSweepInfo(state tinysa.SweepStatus, sweep tinysa.Sweep) sweepInfo {
+	return sweepInfo{
+		Status: string(state),
+		Start:  sweep.Start,
+		Stop:   sweep.Stop,
+		Center: sweep.Start + (sweep.Stop-sweep.Start)/2,
+		Span:   sweep.Stop - sweep.Start,
+		Points: sweep.Points,
+	}
+}
+
+func (c *SweepCmd) PauseSweep(d Device) error {
+	_, _ = fmt.Fprintln(stdout, "pause sweep")
 	if err := d.PauseSweep(); err != nil {
 		return fmt.Errorf("failed to pause sweep: %w", err)
 	}
 	return nil
 }
 
-func (c *SweepCmd) ResumeSweep(d *tinysa.Device) error {
-	fmt.Println("resume sweep")
+func (c *SweepCmd) ResumeSweep(d Device) error {
+	_, _ = fmt.Fprintln(stdout, "resume sweep")
 	if err := d.ResumeSweep(); err != nil {
 		return fmt.Errorf("failed to resume sweep: %w", err)
 	}
 	return nil
 }
 
-func (c *SweepCmd) SetSweepMode(d *tinysa.Device) error {
-	fmt.Printf("set sweep mode to %s\n", c.Mode.Mode.String())
+func (c *SweepCmd) SetSweepMode(d Device) error {
+	_, _ = fmt.Fprintf(stdout, "set sweep mode to %s\n", c.Mode.Mode.String())
 	if err := d.SetSweepMode(c.Mode.Mode); err != nil {
 		return fmt.Errorf("failed to set sweep mode to %s: %w", c.Mode.Mode.String(), err)
 	}
 	return nil
 }
 
-func (c *SweepCmd) SetSweepStart(d *tinysa.Device) error {
+func (c *SweepCmd) SetSweepStart(d Device) error {
 	freq := c.Start.Value
 
 	if c.Start.Relative {
@@ -152,14 +170,14 @@ func (c *SweepCmd) SetSweepStart(d *tinysa.Device) error {
 
 	uFreq := uint64(freq)
 
-	fmt.Printf("set sweep start frequency to %s\n", util.FormatFrequency(uFreq))
+	_, _ = fmt.Fprintf(stdout, "set sweep start frequency to %s\n", util.FormatFrequency(uFreq))
 	if err := d.SetSweepStart(uFreq); err != nil {
 		return fmt.Errorf("failed to set sweep start frequency to %s: %w", util.FormatFrequency(uFreq), err)
 	}
 	return nil
 }
 
-func (c *SweepCmd) SetSweepStop(d *tinysa.Device) error {
+func (c *SweepCmd) SetSweepStop(d Device) error {
 	freq := c.Stop.Value
 
 	if c.Stop.Relative {
@@ -176,14 +194,14 @@ func (c *SweepCmd) SetSweepStop(d *tinysa.Device) error {
 
 	uFreq := uint64(freq)
 
-	fmt.Printf("set sweep stop frequency to %s\n", util.FormatFrequency(uFreq))
+	_, _ = fmt.Fprintf(stdout, "set sweep stop frequency to %s\n", util.FormatFrequency(uFreq))
 	if err := d.SetSweepStop(uFreq); err != nil {
 		return fmt.Errorf("failed to set sweep stop frequency to %s: %w", util.FormatFrequency(uFreq), err)
 	}
 	return nil
 }
 
-func (c *SweepCmd) SetSweepSpan(d *tinysa.Device) error {
+func (c *SweepCmd) SetSweepSpan(d Device) error {
 	freq := c.Span.Value
 
 	if c.Span.Relative {
@@ -200,14 +218,14 @@ func (c *SweepCmd) SetSweepSpan(d *tinysa.Device) error {
 
 	uFreq := uint64(freq)
 
-	fmt.Printf("set sweep span frequency to %s\n", util.FormatFrequency(uFreq))
+	_, _ = fmt.Fprintf(stdout, "set sweep span frequency to %s\n", util.FormatFrequency(uFreq))
 	if err := d.SetSweepSpan(uFreq); err != nil {
 		return fmt.Errorf("failed to set sweep span frequency to %s: %w", util.FormatFrequency(uFreq), err)
 	}
 	return nil
 }
 
-func (c *SweepCmd) SetSweepCenter(d *tinysa.Device) error {
+func (c *SweepCmd) SetSweepCenter(d Device) error {
 	freq := c.Center.Value
 
 	if c.Center.Relative {
@@ -224,43 +242,43 @@ func (c *SweepCmd) SetSweepCenter(d *tinysa.Device) error {
 
 	uFreq := uint64(freq)
 
-	fmt.Printf("set sweep center frequency to %s\n", util.FormatFrequency(uFreq))
+	_, _ = fmt.Fprintf(stdout, "set sweep center frequency to %s\n", util.FormatFrequency(uFreq))
 	if err := d.SetSweepCenter(uFreq); err != nil {
 		return fmt.Errorf("failed to set sweep center frequency to %s: %w", util.FormatFrequency(uFreq), err)
 	}
 	return nil
 }
 
-func (c *SweepCmd) SetSweepCenterFromMarker(d *tinysa.Device) error {
+func (c *SweepCmd) SetSweepCenterFromMarker(d Device) error {
 	marker, err := d.GetMarker(*c.CenterMarker)
 	if err != nil {
 		return fmt.Errorf("failed to get marker #%d: %w", *c.CenterMarker, err)
 	}
-	fmt.Printf("set sweep center frequency from marker #%d (%s)\n", *c.CenterMarker, util.FormatFrequency(marker.Frequency))
+	_, _ = fmt.Fprintf(stdout, "set sweep center frequency from marker #%d (%s)\n", *c.CenterMarker, util.FormatFrequency(marker.Frequency))
 	if err := d.SetSweepCenter(marker.Frequency); err != nil {
 		return fmt.Errorf("failed to set sweep center frequency to %s: %w", util.FormatFrequency(marker.Frequency), err)
 	}
 	return nil
 }
 
-func (c *SweepCmd) SetSweepPoints(d *tinysa.Device) error {
-	fmt.Printf("set sweep points to %d\n", *c.Points)
+func (c *SweepCmd) SetSweepPoints(d Device) error {
+	_, _ = fmt.Fprintf(stdout, "set sweep points to %d\n", *c.Points)
 	if err := d.SetSweepPoints(*c.Points); err != nil {
 		return fmt.Errorf("failed to set sweep points to %d: %w", *c.Points, err)
 	}
 	return nil
 }
 
-func (c *SweepCmd) SetSweepTime(d *tinysa.Device) error {
-	fmt.Printf("set sweep time to %s\n", util.FormatTimeDuration(c.Time.Value))
+func (c *SweepCmd) SetSweepTime(d Device) error {
+	_, _ = fmt.Fprintf(stdout, "set sweep time to %s\n", util.FormatTimeDuration(c.Time.Value))
 	if err := d.SetSweepTime(c.Time.Value); err != nil {
 		return fmt.Errorf("failed to set sweep time to %s: %w", util.FormatTimeDuration(c.Time.Value), err)
 	}
 	return nil
 }
 
-func (c *SweepCmd) SetSweepCW(d *tinysa.Device) error {
-	fmt.Printf("Setting sweep cw frequency to %s\n", util.FormatFrequency(c.CW.Value))
+func (c *SweepCmd) SetSweepCW(d Device) error {
+	_, _ = fmt.Fprintf(stdout, "Setting sweep cw frequency to %s\n", util.FormatFrequency(c.CW.Value))
 	if err := d.SetSweepContinuousWave(c.CW.Value); err != nil {
 		return fmt.Errorf("failed to set sweep cw frequency to %s: %w", util.FormatFrequency(c.CW.Value), err)
 	}
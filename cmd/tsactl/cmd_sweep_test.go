@@ -0,0 +1,134 @@
+package main
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/kkettinger/go-tinysa"
+)
+
+func TestSweepCmd(t *testing.T) {
+	runCliTests(t, []cliTest{
+		{
+			name: "status",
+			args: []string{"sweep"},
+			wantOut: "Status: resumed\n" +
+				"Frequency: 400 MHz to 500 MHz (450 points)\n" +
+				"Center: 450 MHz\n" +
+				"Span: 100 MHz\n",
+			wantCalls: []string{"GetSweepStatus()", "GetSweep()", "Close()"},
+		},
+		{
+			name: "status cw",
+			args: []string{"sweep"},
+			setup: func(d *fakeDevice) {
+				d.sweep = tinysa.Sweep{Start: 433_920_000, Stop: 433_920_000, Points: 450}
+				d.status = tinysa.SweepStatusPaused
+			},
+			wantOut:   "Status: paused\nFrequency: 433.92 MHz (CW)\n",
+			wantCalls: []string{"GetSweepStatus()", "GetSweep()", "Close()"},
+		},
+		{
+			name: "status json",
+			args: []string{"--format", "json", "sweep"},
+			wantOut: `{
+  "status": "resumed",
+  "start": 400000000,
+  "stop": 500000000,
+  "center": 450000000,
+  "span": 100000000,
+  "points": 450
+}
+`,
+			wantCalls: []string{"GetSweepStatus()", "GetSweep()", "Close()"},
+		},
+		{
+			name: "status yaml",
+			args: []string{"-F", "yaml", "sweep"},
+			wantOut: "status: resumed\n" +
+				"start: 400000000\n" +
+				"stop: 500000000\n" +
+				"center: 450000000\n" +
+				"span: 100000000\n" +
+				"points: 450\n",
+			wantCalls: []string{"GetSweepStatus()", "GetSweep()", "Close()"},
+		},
+		{
+			name: "status output overrides format",
+			args: []string{"-F", "json", "sweep", "--output", "yaml"},
+			wantOut: "status: resumed\n" +
+				"start: 400000000\n" +
+				"stop: 500000000\n" +
+				"center: 450000000\n" +
+				"span: 100000000\n" +
+				"points: 450\n",
+			wantCalls: []string{"GetSweepStatus()", "GetSweep()", "Close()"},
+		},
+		{
+			name:    "invalid output",
+			args:    []string{"sweep", "--output", "xml"},
+			wantErr: true,
+		},
+		{
+			name:      "start and stop",
+			args:      []string{"sweep", "--start", "410.5mhz", "--stop", "600m"},
+			wantOut:   "set sweep start frequency to 410.5 MHz\nset sweep stop frequency to 600 MHz\n",
+			wantCalls: []string{"SetSweepStart(410500000)", "SetSweepStop(600000000)", "Close()"},
+		},
+		{
+			name:      "relative center",
+			args:      []string{"sweep", "--center", "+2mhz"},
+			wantOut:   "set sweep center frequency to 452 MHz\n",
+			wantCalls: []string{"GetSweep()", "SetSweepCenter(452000000)", "Close()"},
+		},
+		{
+			name:      "relative span",
+			args:      []string{"sweep", "--span", "-50m"},
+			wantOut:   "set sweep span frequency to 50 MHz\n",
+			wantCalls: []string{"GetSweep()", "SetSweepSpan(50000000)", "Close()"},
+		},
+		{
+			name:      "relative start below zero",
+			args:      []string{"sweep", "--start", "-500m"},
+			wantCalls: []string{"GetSweep()", "Close()"},
+			wantErr:   true,
+		},
+		{
+			name:      "center from marker",
+			args:      []string{"sweep", "-M", "2"},
+			wantOut:   "set sweep center frequency from marker #2 (447.7 MHz)\n",
+			wantCalls: []string{"GetMarker(2)", "SetSweepCenter(447700000)", "Close()"},
+		},
+		{
+			name:    "pause, mode, points and time",
+			args:    []string{"sweep", "--pause", "--mode", "precise", "--points", "290", "--time", "200ms"},
+			wantOut: "pause sweep\nset sweep mode to precise\nset sweep points to 290\nset sweep time to 200 ms\n",
+			wantCalls: []string{
+				"PauseSweep()",
+				"SetSweepMode(precise)",
+				"SetSweepPoints(290)",
+				"SetSweepTime(200000)",
+				"Close()",
+			},
+		},
+		{
+			name:      "cw",
+			args:      []string{"sweep", "--cw", "433.92M"},
+			wantOut:   "Setting sweep cw frequency to 433.92 MHz\n",
+			wantCalls: []string{"SetSweepContinuousWave(433920000)", "Close()"},
+		},
+		{
+			name:      "device error",
+			args:      []string{"sweep", "--resume"},
+			setup:     func(d *fakeDevice) { d.errs = map[string]error{"ResumeSweep": errors.New("timeout")} },
+			wantOut:   "resume sweep\n",
+			wantCalls: []string{"ResumeSweep()", "Close()"},
+			wantErr:   true,
+		},
+		{
+			name:    "invalid mode",
+			args:    []string{"sweep", "--mode", "slow"},
+			wantErr: true,
+		},
+	})
+}
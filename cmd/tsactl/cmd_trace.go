@@ -3,7 +3,6 @@ package main
 import (
 	"fmt"
 	"github.com/kkettinger/go-tinysa"
-	"os"
 	"text/tabwriter"
 )
 
@@ -12,11 +11,13 @@ type TraceCmd struct {
 	Disable bool      `help:"Disable trace" short:"d" group:"Trace flags:"`
 	Calc    TraceCalc `help:"Enable trace calculation (${trace_calc_opts})" short:"c" placeholder:"MODE" group:"Trace flags:"`
 
+	OutputFlag `embed:""`
+
 	Trace uint `arg:"" name:"id" help:"Trace id" optional:""`
 }
 
 func (c *TraceCmd) Run(globals *Globals) error {
-	var ops []func(*tinysa.Device) error
+	var ops []func(Device) error
 
 	hasTrace := c.Trace != 0
 
@@ -44,6 +45,9 @@ func (c *TraceCmd) Run(globals *Globals) error {
 	if err != nil {
 		return err
 	}
+	defer d.Close()
+
+	format := c.outputFormat(globals)
 
 	// apply operations on trace
 	if hasTrace && len(ops) > 0 {
@@ -55,25 +59,37 @@ func (c *TraceCmd) Run(globals *Globals) error {
 		return nil
 	}
 
-	w := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
-
 	// show details about specific trace
 	if hasTrace {
 		t, err := d.GetTrace(c.Trace)
 		if err != nil {
 			return err
 		}
+		if format != formatText {
+			return printStructured(format, newTraceInfo(t))
+		}
+		w := tabwriter.NewWriter(stdout, 0, 0, 3, ' ', 0)
 		printTraceInfo(w, t)
 		_ = w.Flush()
 		return nil
 	}
 
 	// default: list details about all active traces
-	fmt.Println("Active traces:")
 	tAll, err := d.GetTraceAll()
 	if err != nil {
 		return err
 	}
+
+	if format != formatText {
+		infos := make([]traceInfo, len(tAll))
+		for i, t := range tAll {
+			infos[i] = newTraceInfo(t)
+		}
+		return printStructured(format, infos)
+	}
+
+	_, _ = fmt.Fprintln(stdout, "Active traces:")
+	w := tabwriter.NewWriter(stdout, 0, 0, 3, ' ', 0)
 	for _, t := range tAll {
 		printTraceInfo(w, t)
 	}
@@ -86,8 +102,12 @@ func printTraceInfo(w *tabwriter.Writer, t tinysa.Trace) {
 	_, _ = fmt.Fprintf(w, "  Trace %d:\t%s\t%f\t%f\n", t.Trace, t.Unit, t.Scale, t.RefPos)
 }
 
-func (c *TraceCmd) EnableTrace(d *tinysa.Device) error {
-	fmt.Printf("enable trace #%d\n", c.Trace)
+func newTraceInfo(t tinysa.Trace) traceInfo {
+	return traceInfo{Trace: t.Trace, Unit: t.Unit.String(), Scale: t.Scale, RefPos: t.RefPos}
+}
+
+func (c *TraceCmd) EnableTrace(d Device) error {
+	_, _ = fmt.Fprintf(stdout, "enable trace #%d\n", c.Trace)
 
 	if err := d.EnableTrace(c.Trace); err != nil {
 		return fmt.Errorf("failed to enable trace #%d: %w", c.Trace, err)
@@ -96,8 +116,8 @@ func (c *TraceCmd) EnableTrace(d *tinysa.Device) error {
 	return nil
 }
 
-func (c *TraceCmd) DisableTrace(d *tinysa.Device) error {
-	fmt.Printf("disable trace #%d\n", c.Trace)
+func (c *TraceCmd) DisableTrace(d Device) error {
+	_, _ = fmt.Fprintf(stdout, "disable trace #%d\n", c.Trace)
 
 	if err := d.DisableTrace(c.Trace); err != nil {
 		return fmt.Errorf("failed to disable trace #%d: %w", c.Trace, err)
@@ -106,8 +126,8 @@ func (c *TraceCmd) DisableTrace(d *tinysa.Device) error {
 	return nil
 }
 
-func (c *TraceCmd) DisableTraceCalc(d *tinysa.Device) error {
-	fmt.Printf("disable calculations on trace #%d\n", c.Trace)
+func (c *TraceCmd) DisableTraceCalc(d Device) error {
+	_, _ = fmt.Fprintf(stdout, "disable calculations on trace #%d\n", c.Trace)
 
 	if err := d.DisableTraceCalc(c.Trace); err != nil {
 		return fmt.Errorf("failed to disable calculation for trace %d: %w", c.Trace, err)
@@ -116,8 +136,8 @@ func (c *TraceCmd) DisableTraceCalc(d *tinysa.Device) error {
 	return nil
 }
 
-func (c *TraceCmd) EnableTraceCalc(d *tinysa.Device) error {
-	fmt.Printf("enable trace calculations %s for trace #%d\n", c.Calc.Mode.String(), c.Trace)
+func (c *TraceCmd) EnableTraceCalc(d Device) error {
+	_, _ = fmt.Fprintf(stdout, "enable trace calculations %s for trace #%d\n", c.Calc.Mode.String(), c.Trace)
 
 	if err := d.EnableTrace(c.Trace); err != nil {
 		return fmt.Errorf("failed to enable trace #%d: %w", c.Trace, err)
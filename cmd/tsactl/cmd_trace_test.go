@@ -0,0 +1,72 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/kkettinger/go-tinysa"
+)
+
+func TestTraceCmd(t *testing.T) {
+	runCliTests(t, []cliTest{
+		{
+			name: "list",
+			args: []string{"trace"},
+			setup: func(d *fakeDevice) {
+				d.traces = append(d.traces, tinysa.Trace{Trace: 2, Unit: tinysa.TraceUnitDBmV, RefPos: -20, Scale: 5})
+			},
+			wantOut: "Active traces:\n" +
+				"  Trace 1:   dBm    10.000000   -10.000000\n" +
+				"  Trace 2:   dBmV   5.000000    -20.000000\n",
+			wantCalls: []string{"GetTraceAll()", "Close()"},
+		},
+		{
+			name:      "single",
+			args:      []string{"trace", "1"},
+			wantOut:   "  Trace 1:   dBm   10.000000   -10.000000\n",
+			wantCalls: []string{"GetTrace(1)", "Close()"},
+		},
+		{
+			name: "list json",
+			args: []string{"--format", "json", "trace"},
+			wantOut: `[
+  {
+    "trace": 1,
+    "unit": "dBm",
+    "scale": 10,
+    "refpos": -10
+  }
+]
+`,
+			wantCalls: []string{"GetTraceAll()", "Close()"},
+		},
+		{
+			name:      "single yaml",
+			args:      []string{"--format", "yaml", "trace", "1"},
+			wantOut:   "trace: 1\nunit: dBm\nscale: 10\nrefpos: -10\n",
+			wantCalls: []string{"GetTrace(1)", "Close()"},
+		},
+		{
+			name:      "calc",
+			args:      []string{"trace", "2", "--calc", "maxh"},
+			wantOut:   "enable trace calculations maxh for trace #2\n",
+			wantCalls: []string{"EnableTrace(2)", "EnableTraceCalc(2, maxh)", "Close()"},
+		},
+		{
+			name:      "calc off",
+			args:      []string{"trace", "2", "-c", "off"},
+			wantOut:   "disable calculations on trace #2\n",
+			wantCalls: []string{"DisableTraceCalc(2)", "Close()"},
+		},
+		{
+			name:      "enable and disable",
+			args:      []string{"trace", "3", "-e", "-d"},
+			wantOut:   "enable trace #3\ndisable trace #3\n",
+			wantCalls: []string{"EnableTrace(3)", "DisableTrace(3)", "Close()"},
+		},
+		{
+			name:    "flags without id",
+			args:    []string{"trace", "-e"},
+			wantErr: true,
+		},
+	})
+}
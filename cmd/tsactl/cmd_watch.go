@@ -0,0 +1,317 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"os"
+	"os/signal"
+	"slices"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/kkettinger/go-tinysa"
+	"github.com/kkettinger/tsactl/internal/braille"
+	"github.com/kkettinger/tsactl/internal/util"
+	"golang.org/x/term"
+)
+
+type WatchCmd struct {
+	Trace    []uint        `help:"Trace(s) to plot" short:"t" default:"1" group:"Watch flags:"`
+	Interval time.Duration `help:"Refresh interval" short:"i" default:"500ms" group:"Watch flags:"`
+	Count    uint          `help:"Stop after this number of refreshes, otherwise run until interrupted" short:"n" group:"Watch flags:"`
+	Min      *float64      `help:"Lower end of the level axis, otherwise fitted to the data" group:"Watch flags:"`
+	Max      *float64      `help:"Upper end of the level axis, otherwise fitted to the data" group:"Watch flags:"`
+}
+
+// traceColors are the ANSI colors of the traces 1-4, similar to the device display
+var traceColors = []string{"33", "36", "35", "32"}
+
+func (c *WatchCmd) Run(globals *Globals) error {
+	if c.Interval <= 0 {
+		return fmt.Errorf("interval must be greater than zero")
+	}
+	if c.Min != nil && c.Max != nil && *c.Min >= *c.Max {
+		return fmt.Errorf("min must be lower than max")
+	}
+
+	d, err := initDevice(globals)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+
+	traces, err := d.GetTraceAll()
+	if err != nil {
+		return fmt.Errorf("failed to get traces: %w", err)
+	}
+	unit := ""
+	for _, t := range traces {
+		if slices.Contains(c.Trace, t.Trace) {
+			unit = t.Unit.String()
+			break
+		}
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	ticker := time.NewTicker(c.Interval)
+	defer ticker.Stop()
+
+	_, _, tty := terminalSize()
+	if tty {
+		// hide the cursor while drawing
+		_, _ = fmt.Fprint(stdout, "\x1b[?25l")
+		defer fmt.Fprint(stdout, "\x1b[?25h\n")
+	}
+
+	for n := uint(1); ; n++ {
+		data := make([][]tinysa.TraceData, len(c.Trace))
+		for i, traceId := range c.Trace {
+			traceData, err := d.GetTraceData(traceId)
+			if err != nil {
+				return fmt.Errorf("failed to get trace data: %w", err)
+			}
+			data[i] = traceData
+		}
+
+		markers, err := d.GetMarkerAll()
+		if err != nil {
+			return fmt.Errorf("failed to get markers: %w", err)
+		}
+
+		// the size is read on every refresh to follow resizes of the terminal
+		width, height, tty := terminalSize()
+		p := watchPlot{width: width, height: height, unit: unit, min: c.Min, max: c.Max, color: tty}
+		lines := p.render(data, markers, time.Now())
+
+		if tty {
+			// redraw in place, clearing the rest of each line and of the screen
+			_, _ = fmt.Fprint(stdout, "\x1b[H"+strings.Join(lines, "\x1b[K\n")+"\x1b[K\x1b[J")
+		} else {
+			_, _ = fmt.Fprintln(stdout, strings.Join(lines, "\n"))
+		}
+
+		if c.Count > 0 && n >= c.Count {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// terminalSize returns the size of the terminal, or 80x24 if stdout is no terminal.
+func terminalSize() (int, int, bool) {
+	if f, ok := stdout.(*os.File); ok && term.IsTerminal(int(f.Fd())) {
+		if width, height, err := term.GetSize(int(f.Fd())); err == nil {
+			return width, height, true
+		}
+	}
+	return 80, 24, false
+}
+
+// watchPlot renders trace data as braille line plot of the given size in characters.
+type watchPlot struct {
+	width, height int
+	unit          string
+	min, max      *float64
+	color         bool
+}
+
+func (p watchPlot) render(data [][]tinysa.TraceData, markers []tinysa.Marker, ts time.Time) []string {
+	var first []tinysa.TraceData
+	for _, traceData := range data {
+		if len(traceData) > 0 {
+			first = traceData
+			break
+		}
+	}
+	if len(first) == 0 {
+		return []string{"no trace data"}
+	}
+
+	start, stop := first[0].Frequency, first[len(first)-1].Frequency
+	yMin, yMax := p.levelRange(data)
+
+	// header, axis, frequency labels and markers take a line each
+	rows := max(p.height-4, 2)
+
+	// levels are labelled in steps of 1, 2 or 5, about every fourth row
+	labels := make([]string, rows)
+	step := niceStep(yMax-yMin, max(rows/4, 1))
+	precision := max(0, -int(math.Floor(math.Log10(step))))
+	for v := math.Ceil(yMin/step) * step; v <= yMax+step/1e6; v += step {
+		r := int(math.Round((yMax-v)/(yMax-yMin)*float64(rows*4-1))) / 4
+		if labels[r] == "" {
+			labels[r] = strconv.FormatFloat(v, 'f', precision, 64)
+		}
+	}
+	for r := range labels {
+		if labels[r] != "" {
+			labels[r] = strings.TrimSpace(labels[r] + " " + p.unit)
+			break
+		}
+	}
+	labelWidth := 0
+	for _, label := range labels {
+		labelWidth = max(labelWidth, len(label))
+	}
+
+	cols := max(p.width-labelWidth-3, 10)
+	canvas := braille.New(cols, rows)
+
+	for _, traceData := range data {
+		if len(traceData) == 0 {
+			continue
+		}
+		color := int(traceData[0].Trace) - 1
+
+		prevX, prevY := -1, -1
+		for j, dp := range traceData {
+			x := 0
+			if len(traceData) > 1 {
+				x = int(math.Round(float64(j) * float64(canvas.Width()-1) / float64(len(traceData)-1)))
+			}
+			y := int(math.Round((yMax - dp.Value) / (yMax - yMin) * float64(canvas.Height()-1)))
+			y = min(max(y, 0), canvas.Height()-1)
+
+			if prevX < 0 {
+				canvas.Set(x, y, color)
+			} else {
+				canvas.Line(prevX, prevY, x, y, color)
+			}
+			prevX, prevY = x, y
+		}
+	}
+
+	var legend []string
+	for _, traceData := range data {
+		if len(traceData) > 0 {
+			traceID := traceData[0].Trace
+			legend = append(legend, p.colorize(int(traceID)-1, "━")+fmt.Sprintf(" trace %d", traceID))
+		}
+	}
+	lines := []string{fmt.Sprintf("%s - %s, %d points, %s   %s",
+		util.FormatFrequency(start), util.FormatFrequency(stop), len(first), ts.Format("15:04:05"),
+		strings.Join(legend, "  "))}
+
+	for r := range rows {
+		var row strings.Builder
+		axis := "│"
+		if labels[r] != "" {
+			axis = "┤"
+		}
+		row.WriteString(fmt.Sprintf("%*s %s", labelWidth, labels[r], axis))
+
+		current := -1
+		for col := range cols {
+			char, color := canvas.Cell(col, r)
+			if p.color && char != ' ' && color != current {
+				row.WriteString("\x1b[" + traceColors[color%len(traceColors)] + "m")
+				current = color
+			}
+			row.WriteRune(char)
+		}
+		if current >= 0 {
+			row.WriteString("\x1b[0m")
+		}
+		lines = append(lines, row.String())
+	}
+
+	// markers are shown by their number on the frequency axis
+	axis := []rune(strings.Repeat("─", cols))
+	var markerInfos []string
+	for _, m := range markers {
+		if m.Frequency < start || m.Frequency > stop || stop == start {
+			continue
+		}
+		col := int(math.Round(float64(m.Frequency-start) / float64(stop-start) * float64(cols-1)))
+		axis[col] = rune(strconv.FormatUint(uint64(m.Marker%10), 10)[0])
+		value := strings.TrimSpace(strconv.FormatFloat(m.Value, 'f', -1, 64) + " " + p.unit)
+		markerInfos = append(markerInfos, fmt.Sprintf("%d: %s %s", m.Marker, util.FormatFrequency(m.Frequency), value))
+	}
+	lines = append(lines, strings.Repeat(" ", labelWidth+1)+"└"+string(axis))
+	lines = append(lines, strings.Repeat(" ", labelWidth+2)+frequencyLabels(start, stop, cols))
+
+	if len(markerInfos) > 0 {
+		lines = append(lines, "markers "+strings.Join(markerInfos, ", "))
+	} else {
+		lines = append(lines, "")
+	}
+
+	return lines
+}
+
+// levelRange returns the range of the level axis, fitted to the data in steps of 10.
+func (p watchPlot) levelRange(data [][]tinysa.TraceData) (float64, float64) {
+	lo, hi := math.Inf(1), math.Inf(-1)
+	for _, traceData := range data {
+		for _, dp := range traceData {
+			lo, hi = math.Min(lo, dp.Value), math.Max(hi, dp.Value)
+		}
+	}
+	yMin, yMax := math.Floor(lo/10)*10, math.Ceil(hi/10)*10
+	if p.min != nil {
+		yMin = *p.min
+	}
+	if p.max != nil {
+		yMax = *p.max
+	}
+	if yMax <= yMin {
+		yMax = yMin + 10
+	}
+	return yMin, yMax
+}
+
+func (p watchPlot) colorize(color int, s string) string {
+	if !p.color || color < 0 {
+		return s
+	}
+	return "\x1b[" + traceColors[color%len(traceColors)] + "m" + s + "\x1b[0m"
+}
+
+// frequencyLabels returns a line of frequency labels at round frequencies, spread over cols characters.
+func frequencyLabels(start, stop uint64, cols int) string {
+	line := []rune(strings.Repeat(" ", cols))
+	if stop <= start {
+		copy(line, []rune(util.FormatFrequency(start)))
+		return strings.TrimRight(string(line), " ")
+	}
+
+	step := uint64(niceStep(float64(stop-start), max(cols/12, 1)))
+	end := -1
+	for freq := (start + step - 1) / step * step; freq <= stop; freq += step {
+		label := []rune(util.FormatFrequency(freq))
+		col := int(math.Round(float64(freq-start) / float64(stop-start) * float64(cols-1)))
+		pos := min(max(col-len(label)/2, 0), cols-len(label))
+		if pos < 0 || (end >= 0 && pos <= end+1) {
+			continue
+		}
+		copy(line[pos:], label)
+		end = pos + len(label) - 1
+	}
+
+	return strings.TrimRight(string(line), " ")
+}
+
+// niceStep returns a step of 1, 2 or 5 times a power of ten, dividing span in about n parts.
+func niceStep(span float64, n int) float64 {
+	raw := span / float64(n)
+	if raw <= 0 {
+		return 1
+	}
+	magnitude := math.Pow(10, math.Floor(math.Log10(raw)))
+	for _, f := range []float64{1, 2, 5} {
+		if f*magnitude >= raw {
+			return f * magnitude
+		}
+	}
+	return 10 * magnitude
+}
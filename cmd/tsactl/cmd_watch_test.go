@@ -0,0 +1,130 @@
+package main
+
+import (
+	"slices"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/kkettinger/go-tinysa"
+)
+
+func TestWatchCmd(t *testing.T) {
+	d := newFakeDevice()
+	out, err := runCli(t, d, "watch", "-n", "2", "-i", "1ms", "-t", "1,2")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// two frames of the 80x24 fallback size
+	lines := strings.Split(strings.TrimSuffix(out, "\n"), "\n")
+	if len(lines) != 48 {
+		t.Fatalf("lines = %d, want 48:\n%s", len(lines), out)
+	}
+	for _, line := range lines {
+		if n := utf8.RuneCountInString(line); n > 80 {
+			t.Errorf("line exceeds 80 characters (%d): %s", n, line)
+		}
+	}
+
+	frame := lines[:24]
+	for i, want := range map[int]string{
+		0:  "400 MHz - 500 MHz, 3 points, ",
+		21: "└",
+		22: "400 MHz",
+		23: "markers 1: 410 MHz -89.4 dBm, 2: 447.7 MHz -67.9 dBm",
+	} {
+		if !strings.Contains(frame[i], want) {
+			t.Errorf("line %d = %q, want to contain %q", i, frame[i], want)
+		}
+	}
+	if !strings.HasSuffix(frame[0], "━ trace 1  ━ trace 2") {
+		t.Errorf("header = %q", frame[0])
+	}
+	for _, line := range frame[1:21] {
+		if strings.Contains(line, "┤") {
+			if !strings.Contains(line, "-40 dBm ┤") {
+				t.Errorf("top label = %q, want -40 dBm", line)
+			}
+			break
+		}
+	}
+	// marker 1 at 410 MHz is a tenth of the axis
+	axis := []rune(frame[21])
+	if i := slices.Index(axis, '└'); i < 0 || !slices.Contains(axis[i:i+10], '1') {
+		t.Errorf("axis = %q, want marker 1 near the start", frame[21])
+	}
+
+	wantCalls := []string{
+		"GetTraceAll()",
+		"GetTraceData(1)", "GetTraceData(2)", "GetMarkerAll()",
+		"GetTraceData(1)", "GetTraceData(2)", "GetMarkerAll()",
+		"Close()",
+	}
+	if got := strings.Join(d.calls, "\n"); got != strings.Join(wantCalls, "\n") {
+		t.Errorf("calls:\n%s\nwant:\n%s", got, strings.Join(wantCalls, "\n"))
+	}
+}
+
+func TestWatchPlotRange(t *testing.T) {
+	data := [][]tinysa.TraceData{{{Value: -87.5}, {Value: -42}}}
+	lo, hi := -100.0, -20.0
+
+	tests := []struct {
+		name             string
+		plot             watchPlot
+		wantMin, wantMax float64
+	}{
+		{"fitted", watchPlot{}, -90, -40},
+		{"fixed", watchPlot{min: &lo, max: &hi}, -100, -20},
+		{"fixed min above data", watchPlot{min: &hi}, -20, -10},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotMin, gotMax := tt.plot.levelRange(data)
+			if gotMin != tt.wantMin || gotMax != tt.wantMax {
+				t.Errorf("range = %v..%v, want %v..%v", gotMin, gotMax, tt.wantMin, tt.wantMax)
+			}
+		})
+	}
+}
+
+func TestFrequencyLabels(t *testing.T) {
+	tests := []struct {
+		start, stop uint64
+		cols        int
+		want        string
+	}{
+		{400_000_000, 500_000_000, 40, "400 MHz          450 MHz         500 MHz"},
+		{0, 800_000_000, 70, "0 Hz          200 MHz           400 MHz          600 MHz       800 MHz"},
+		{433_000_000, 433_000_000, 20, "433 MHz"},
+	}
+
+	for _, tt := range tests {
+		got := frequencyLabels(tt.start, tt.stop, tt.cols)
+		if got != tt.want {
+			t.Errorf("frequencyLabels(%d, %d, %d) = %q, want %q", tt.start, tt.stop, tt.cols, got, tt.want)
+		}
+	}
+}
+
+func TestNiceStep(t *testing.T) {
+	tests := []struct {
+		span float64
+		n    int
+		want float64
+	}{
+		{100, 4, 50},
+		{60, 5, 20},
+		{800e6, 5, 200e6},
+		{3, 3, 1},
+		{0.7, 4, 0.2},
+	}
+
+	for _, tt := range tests {
+		if got := niceStep(tt.span, tt.n); got != tt.want {
+			t.Errorf("niceStep(%v, %d) = %v, want %v", tt.span, tt.n, got, tt.want)
+		}
+	}
+}
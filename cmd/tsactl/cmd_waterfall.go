@@ -0,0 +1,348 @@
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"math"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/kkettinger/go-tinysa"
+	"github.com/kkettinger/tsactl/internal/colormap"
+	"github.com/kkettinger/tsactl/internal/util"
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/basicfont"
+	"golang.org/x/image/math/fixed"
+)
+
+const filenameWaterfallDefault = "SA_<date>_<time>_waterfall.png"
+
+type WaterfallCmd struct {
+	Trace    uint          `help:"Trace to record" short:"t" default:"1" group:"Waterfall flags:"`
+	Count    uint          `help:"Number of sweeps to record" short:"n" default:"100" group:"Waterfall flags:"`
+	Interval time.Duration `help:"Interval between sweeps" short:"i" default:"1s" group:"Waterfall flags:"`
+	Output   string        `help:"Output filepath of the PNG image" short:"o" type:"path" group:"Waterfall flags:" placeholder:"PATH"`
+	CSV      string        `help:"Output filepath of the CSV matrix, defaults to the image path with .csv extension" name:"csv" type:"path" group:"Waterfall flags:" placeholder:"PATH"`
+	Colormap string        `help:"Colormap of the levels (${enum})" short:"c" default:"viridis" enum:"gray,inferno,turbo,viridis" group:"Waterfall flags:"`
+	Min      *float64      `help:"Level shown with the lowest color, otherwise fitted to the data" group:"Waterfall flags:"`
+	Max      *float64      `help:"Level shown with the highest color, otherwise fitted to the data" group:"Waterfall flags:"`
+}
+
+func (c *WaterfallCmd) Run(globals *Globals) error {
+	if c.Interval <= 0 {
+		return fmt.Errorf("interval must be greater than zero")
+	}
+	if c.Count == 0 {
+		return fmt.Errorf("count must be greater than zero")
+	}
+	if c.Min != nil && c.Max != nil && *c.Min >= *c.Max {
+		return fmt.Errorf("min must be lower than max")
+	}
+
+	settings, err := globals.settings()
+	if err != nil {
+		return err
+	}
+
+	output := c.Output
+	if output == "" {
+		output = filepath.Join(settings.OutputDir, settings.Filenames.Waterfall)
+		if settings.OutputDir != "" {
+			if err := os.MkdirAll(settings.OutputDir, 0o755); err != nil {
+				return fmt.Errorf("failed to create output directory '%s': %w", settings.OutputDir, err)
+			}
+		}
+	}
+	output = replaceFilenamePlaceholdersDateTime(output)
+
+	csvOutput := c.CSV
+	if csvOutput == "" {
+		csvOutput = strings.TrimSuffix(output, filepath.Ext(output)) + ".csv"
+	}
+	csvOutput = replaceFilenamePlaceholdersDateTime(csvOutput)
+
+	d, err := initDevice(globals)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+
+	traces, err := d.GetTraceAll()
+	if err != nil {
+		return fmt.Errorf("failed to get traces: %w", err)
+	}
+	wf := &waterfall{trace: c.Trace}
+	for _, t := range traces {
+		if t.Trace == c.Trace {
+			wf.unit = t.Unit.String()
+		}
+	}
+
+	// stop recording on Ctrl-C, the sweeps so far are still saved
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	ticker := time.NewTicker(c.Interval)
+	defer ticker.Stop()
+
+	_, _ = fmt.Fprintf(stdout, "recording %d sweeps of trace %d every %s, press Ctrl-C to stop\n", c.Count, c.Trace, c.Interval)
+
+loop:
+	for {
+		data, err := d.GetTraceData(c.Trace)
+		if err != nil {
+			return fmt.Errorf("failed to get trace data: %w", err)
+		}
+		if err := wf.add(time.Now(), data); err != nil {
+			return err
+		}
+
+		if uint(len(wf.values)) >= c.Count {
+			break
+		}
+
+		select {
+		case <-ctx.Done():
+			break loop
+		case <-ticker.C:
+		}
+	}
+
+	cmap, _ := colormap.Get(c.Colormap)
+	lo, hi := wf.levelRange(c.Min, c.Max)
+	if err := wf.savePNG(output, cmap, lo, hi); err != nil {
+		return err
+	}
+	if err := wf.saveCSV(csvOutput); err != nil {
+		return err
+	}
+
+	_, _ = fmt.Fprintf(stdout, "%d sweeps saved to %s and %s\n", len(wf.values), output, csvOutput)
+
+	return nil
+}
+
+// waterfall is a matrix of successive sweeps of a trace.
+type waterfall struct {
+	trace       uint
+	unit        string
+	frequencies []uint64
+	times       []time.Time
+	values      [][]float64
+}
+
+// add appends a sweep, which must have the frequencies of the previous sweeps.
+func (w *waterfall) add(ts time.Time, data []tinysa.TraceData) error {
+	if len(data) == 0 {
+		return fmt.Errorf("trace %d has no data", w.trace)
+	}
+
+	if w.frequencies == nil {
+		w.frequencies = make([]uint64, len(data))
+		for i, dp := range data {
+			w.frequencies[i] = dp.Frequency
+		}
+	} else if len(data) != len(w.frequencies) ||
+		data[0].Frequency != w.frequencies[0] || data[len(data)-1].Frequency != w.frequencies[len(data)-1] {
+		return fmt.Errorf("sweep settings changed during recording")
+	}
+
+	values := make([]float64, len(data))
+	for i, dp := range data {
+		values[i] = dp.Value
+	}
+	w.times = append(w.times, ts)
+	w.values = append(w.values, values)
+
+	return nil
+}
+
+// levelRange returns the levels of the lowest and highest color, fitted to the data unless given.
+func (w *waterfall) levelRange(minLevel, maxLevel *float64) (float64, float64) {
+	lo, hi := math.Inf(1), math.Inf(-1)
+	for _, row := range w.values {
+		for _, v := range row {
+			lo, hi = math.Min(lo, v), math.Max(hi, v)
+		}
+	}
+	if minLevel != nil {
+		lo = *minLevel
+	}
+	if maxLevel != nil {
+		hi = *maxLevel
+	}
+	if hi <= lo {
+		hi = lo + 1
+	}
+	return lo, hi
+}
+
+func (w *waterfall) saveCSV(path string) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to open file '%s': %w", path, err)
+	}
+	defer file.Close()
+
+	writer := csv.NewWriter(file)
+
+	// the first row contains the frequencies, every other row a sweep
+	header := []string{"timestamp"}
+	for _, freq := range w.frequencies {
+		header = append(header, strconv.FormatUint(freq, 10))
+	}
+	if err := writer.Write(header); err != nil {
+		return err
+	}
+
+	for i, values := range w.values {
+		row := []string{w.times[i].Format(timestampFormat)}
+		for _, v := range values {
+			row = append(row, strconv.FormatFloat(v, 'f', -1, 64))
+		}
+		if err := writer.Write(row); err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return fmt.Errorf("failed to write file '%s': %w", path, err)
+	}
+	return file.Close()
+}
+
+func (w *waterfall) savePNG(path string, cmap colormap.Colormap, lo, hi float64) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to open file '%s': %w", path, err)
+	}
+	defer file.Close()
+
+	if err := png.Encode(file, w.render(cmap, lo, hi)); err != nil {
+		return fmt.Errorf("failed to encode file '%s': %w", path, err)
+	}
+	return file.Close()
+}
+
+const (
+	waterfallMarginLeft   = 72
+	waterfallMarginRight  = 88
+	waterfallMarginTop    = 28
+	waterfallMarginBottom = 36
+)
+
+// render draws the spectrogram with the first sweep on top, a frequency axis below,
+// the elapsed time on the left and a color bar on the right.
+func (w *waterfall) render(cmap colormap.Colormap, lo, hi float64) image.Image {
+	points, sweeps := len(w.frequencies), len(w.values)
+	plotW, plotH := max(points, 600), max(sweeps, 300)
+
+	img := image.NewRGBA(image.Rect(0, 0,
+		waterfallMarginLeft+plotW+waterfallMarginRight, waterfallMarginTop+plotH+waterfallMarginBottom))
+	draw.Draw(img, img.Bounds(), image.White, image.Point{}, draw.Src)
+
+	// spectrogram, scaled to the plot area
+	plot := image.Rect(waterfallMarginLeft, waterfallMarginTop, waterfallMarginLeft+plotW, waterfallMarginTop+plotH)
+	for y := range plotH {
+		row := w.values[y*sweeps/plotH]
+		for x := range plotW {
+			img.Set(plot.Min.X+x, plot.Min.Y+y, cmap.At((row[x*points/plotW]-lo)/(hi-lo)))
+		}
+	}
+
+	black := color.Black
+	title := fmt.Sprintf("trace %d, %d sweeps, %s", w.trace, sweeps, w.times[0].Format("2006-01-02 15:04:05"))
+	drawText(img, plot.Min.X, waterfallMarginTop-10, title, 0)
+
+	// frequency axis
+	start, stop := w.frequencies[0], w.frequencies[points-1]
+	if stop > start {
+		step := uint64(niceStep(float64(stop-start), max(plotW/120, 1)))
+		for freq := (start + step - 1) / step * step; freq <= stop; freq += step {
+			x := plot.Min.X + int(math.Round(float64(freq-start)/float64(stop-start)*float64(plotW-1)))
+			fillRect(img, x, plot.Max.Y, x, plot.Max.Y+4, black)
+			drawText(img, x, plot.Max.Y+18, util.FormatFrequency(freq), 0.5)
+		}
+	} else {
+		drawText(img, plot.Min.X+plotW/2, plot.Max.Y+18, util.FormatFrequency(start), 0.5)
+	}
+
+	// time axis, the rows are assumed to be evenly spaced
+	total := w.times[sweeps-1].Sub(w.times[0]).Seconds()
+	if total > 0 {
+		rowH := float64(plotH) / float64(sweeps)
+		step := niceStep(total, max(plotH/60, 1))
+		for i := 0.0; i*step <= total; i++ {
+			t := i * step
+			y := plot.Min.Y + int(math.Round(rowH/2+t/total*(float64(plotH)-rowH)))
+			fillRect(img, plot.Min.X-5, y, plot.Min.X-1, y, black)
+			drawText(img, plot.Min.X-8, y+4, formatElapsed(time.Duration(t*float64(time.Second))), 1)
+		}
+	}
+
+	// color bar with the levels
+	bar := image.Rect(plot.Max.X+12, plot.Min.Y, plot.Max.X+28, plot.Max.Y)
+	for y := bar.Min.Y; y < bar.Max.Y; y++ {
+		c := cmap.At(1 - float64(y-bar.Min.Y)/float64(bar.Dy()-1))
+		fillRect(img, bar.Min.X, y, bar.Max.X-1, y, c)
+	}
+	step := niceStep(hi-lo, max(plotH/60, 1))
+	precision := max(0, -int(math.Floor(math.Log10(step))))
+	for v := math.Ceil(lo/step) * step; v <= hi; v += step {
+		y := bar.Min.Y + int(math.Round((hi-v)/(hi-lo)*float64(bar.Dy()-1)))
+		fillRect(img, bar.Max.X, y, bar.Max.X+3, y, black)
+		drawText(img, bar.Max.X+6, y+4, strconv.FormatFloat(v, 'f', precision, 64), 0)
+	}
+	drawText(img, bar.Min.X, waterfallMarginTop-10, w.unit, 0)
+
+	return img
+}
+
+// drawText draws s with its baseline at y, aligned at x by align (0 left, 0.5 centered, 1 right).
+func drawText(img draw.Image, x, y int, s string, align float64) {
+	drawer := &font.Drawer{Dst: img, Src: image.Black, Face: basicfont.Face7x13}
+	width := drawer.MeasureString(s).Round()
+	drawer.Dot = fixed.P(x-int(float64(width)*align), y)
+	drawer.DrawString(s)
+}
+
+// fillRect fills the rectangle between the corners x0, y0 and x1, y1 inclusive.
+func fillRect(img draw.Image, x0, y0, x1, y1 int, c color.Color) {
+	for x := min(x0, x1); x <= max(x0, x1); x++ {
+		for y := min(y0, y1); y <= max(y0, y1); y++ {
+			img.Set(x, y, c)
+		}
+	}
+}
+
+// formatElapsed formats d like 1h30m, 45s or 0.5s.
+func formatElapsed(d time.Duration) string {
+	d = d.Round(time.Millisecond)
+	if d < time.Minute {
+		return strconv.FormatFloat(d.Seconds(), 'f', -1, 64) + "s"
+	}
+	d = d.Round(time.Second)
+
+	var s string
+	if h := d / time.Hour; h > 0 {
+		s += fmt.Sprintf("%dh", h)
+	}
+	if m := d % time.Hour / time.Minute; m > 0 {
+		s += fmt.Sprintf("%dm", m)
+	}
+	if sec := d % time.Minute / time.Second; sec > 0 {
+		s += fmt.Sprintf("%ds", sec)
+	}
+	return s
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"errors"
+	"image/png"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kkettinger/tsactl/internal/colormap"
+)
+
+func TestWaterfallCmd(t *testing.T) {
+	dir := t.TempDir()
+	output := filepath.Join(dir, "wf.png")
+
+	d := newFakeDevice()
+	out, err := runCli(t, d, "waterfall", "-n", "3", "-i", "1ms", "-o", output, "-c", "gray")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	csvOutput := filepath.Join(dir, "wf.csv")
+	want := "recording 3 sweeps of trace 1 every 1ms, press Ctrl-C to stop\n" +
+		"3 sweeps saved to " + output + " and " + csvOutput + "\n"
+	if out != want {
+		t.Errorf("output = %q, want %q", out, want)
+	}
+
+	data, err := os.ReadFile(csvOutput)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantCSV := "timestamp,400000000,450000000,500000000\n" +
+		"<ts>,-90.25,-40.5,-89.75\n<ts>,-90.25,-40.5,-89.75\n<ts>,-90.25,-40.5,-89.75\n"
+	if got := timestampRegex.ReplaceAllString(string(data), "<ts>"); got != wantCSV {
+		t.Errorf("csv:\n%s\nwant:\n%s", got, wantCSV)
+	}
+
+	file, err := os.Open(output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	img, err := png.Decode(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the plot area is scaled to at least 600x300 pixels
+	if img.Bounds().Dx() != 760 || img.Bounds().Dy() != 364 {
+		t.Errorf("image size = %v", img.Bounds())
+	}
+
+	wantCalls := []string{"GetTraceAll()", "GetTraceData(1)", "GetTraceData(1)", "GetTraceData(1)", "Close()"}
+	if got := strings.Join(d.calls, "\n"); got != strings.Join(wantCalls, "\n") {
+		t.Errorf("calls:\n%s\nwant:\n%s", got, strings.Join(wantCalls, "\n"))
+	}
+}
+
+func TestWaterfallCmdConfig(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "out")
+	config := &Config{OutputDir: dir, Filenames: Filenames{Waterfall: "survey.png"}}
+
+	out, err := runCliConfig(t, newFakeDevice(), config, "waterfall", "-n", "1", "--csv", filepath.Join(dir, "matrix.csv"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasSuffix(out, "1 sweeps saved to "+filepath.Join(dir, "survey.png")+" and "+filepath.Join(dir, "matrix.csv")+"\n") {
+		t.Errorf("output = %q", out)
+	}
+}
+
+func TestWaterfallAdd(t *testing.T) {
+	wf := &waterfall{trace: 1}
+	data := newFakeDevice().traceData[1]
+
+	if err := wf.add(time.Now(), data); err != nil {
+		t.Fatal(err)
+	}
+	if err := wf.add(time.Now(), data[:2]); err == nil || err.Error() != "sweep settings changed during recording" {
+		t.Errorf("error = %v", err)
+	}
+	if err := wf.add(time.Now(), nil); err == nil || err.Error() != "trace 1 has no data" {
+		t.Errorf("error = %v", err)
+	}
+	if len(wf.values) != 1 {
+		t.Errorf("sweeps = %d, want 1", len(wf.values))
+	}
+}
+
+func TestWaterfallCmdError(t *testing.T) {
+	output := filepath.Join(t.TempDir(), "wf.png")
+	d := newFakeDevice()
+	d.errs = map[string]error{"GetTraceData": errors.New("timeout")}
+
+	if _, err := runCli(t, d, "waterfall", "-o", output); err == nil || err.Error() != "failed to get trace data: timeout" {
+		t.Errorf("error = %v", err)
+	}
+	if _, err := os.Stat(output); !os.IsNotExist(err) {
+		t.Errorf("image should not be created, got %v", err)
+	}
+}
+
+func TestWaterfallColormapOptions(t *testing.T) {
+	// the enum of the colormap flag must list all colormaps
+	var c Cli
+	parser, err := newParser(&c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, node := range parser.Model.Children {
+		if node.Name != "waterfall" {
+			continue
+		}
+		for _, flag := range node.Flags {
+			if flag.Name == "colormap" {
+				if got := strings.Split(flag.Enum, ","); !slices.Equal(got, colormap.Names()) {
+					t.Errorf("enum = %v, want %v", got, colormap.Names())
+				}
+				return
+			}
+		}
+	}
+	t.Fatal("colormap flag not found")
+}
+
+func TestFormatElapsed(t *testing.T) {
+	tests := []struct {
+		d    time.Duration
+		want string
+	}{
+		{0, "0s"},
+		{400 * time.Millisecond, "0.4s"},
+		{45 * time.Second, "45s"},
+		{90 * time.Second, "1m30s"},
+		{time.Hour, "1h"},
+		{2*time.Hour + 5*time.Minute, "2h5m"},
+	}
+
+	for _, tt := range tests {
+		if got := formatElapsed(tt.d); got != tt.want {
+			t.Errorf("formatElapsed(%s) = %s, want %s", tt.d, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,220 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/alecthomas/kong"
+	"gopkg.in/yaml.v3"
+)
+
+// Config is the content of the config file, see configPath.
+type Config struct {
+	DefaultProfile string             `yaml:"default_profile"`
+	OutputDir      string             `yaml:"output_dir"`
+	Filenames      Filenames          `yaml:"filenames"`
+	Profiles       map[string]Profile `yaml:"profiles"`
+}
+
+// Profile contains the settings of a named device, which override the defaults of the config file.
+type Profile struct {
+	Device    string    `yaml:"device"`
+	Baudrate  int       `yaml:"baudrate"`
+	DeviceID  *uint     `yaml:"device_id"`
+	OutputDir string    `yaml:"output_dir"`
+	Filenames Filenames `yaml:"filenames"`
+}
+
+// Filenames contains the default filename templates of the save, monitor, waterfall and scan commands.
+type Filenames struct {
+	Capture    string `yaml:"capture"`
+	Trace      string `yaml:"trace"`
+	TraceMulti string `yaml:"trace_multi"`
+	Monitor    string `yaml:"monitor"`
+	Waterfall  string `yaml:"waterfall"`
+	Scan       string `yaml:"scan"`
+}
+
+// configPath returns the path of the config file, which can be changed with TSACTL_CONFIG.
+func configPath() string {
+	if path, ok := os.LookupEnv("TSACTL_CONFIG"); ok {
+		return path
+	}
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "tsactl", "config.yaml")
+}
+
+// loadConfig reads the config file at path. A missing file results in an empty config.
+func loadConfig(path string) (*Config, error) {
+	config := &Config{}
+	if path == "" {
+		return config, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return config, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	if err := yaml.Unmarshal(data, config); err != nil {
+		return nil, fmt.Errorf("failed to parse config file '%s': %w", path, err)
+	}
+
+	return config, nil
+}
+
+// Profile returns the settings of the named profile merged with the defaults of the config file.
+// The default profile is used when name is empty.
+func (c *Config) Profile(name string) (Profile, error) {
+	if c == nil {
+		c = &Config{}
+	}
+
+	settings := Profile{
+		OutputDir: c.OutputDir,
+		Filenames: Filenames{
+			Capture:    filenameCaptureDefault,
+			Trace:      filenameTraceDefault,
+			TraceMulti: filenameTraceMultiDefault,
+			Monitor:    filenameMonitorDefault,
+			Waterfall:  filenameWaterfallDefault,
+			Scan:       filenameScanDefault,
+		},
+	}
+	settings.Filenames.merge(c.Filenames)
+
+	if name == "" {
+		name = c.DefaultProfile
+	}
+	if name == "" {
+		settings.OutputDir = expandHome(settings.OutputDir)
+		return settings, nil
+	}
+
+	profile, ok := c.Profiles[name]
+	if !ok {
+		return Profile{}, fmt.Errorf("unknown profile '%s'", name)
+	}
+
+	settings.Device = profile.Device
+	settings.Baudrate = profile.Baudrate
+	settings.DeviceID = profile.DeviceID
+	if profile.OutputDir != "" {
+		settings.OutputDir = profile.OutputDir
+	}
+	settings.Filenames.merge(profile.Filenames)
+	settings.OutputDir = expandHome(settings.OutputDir)
+
+	return settings, nil
+}
+
+// merge overrides the filename templates with those set in other.
+func (f *Filenames) merge(other Filenames) {
+	if other.Capture != "" {
+		f.Capture = other.Capture
+	}
+	if other.Trace != "" {
+		f.Trace = other.Trace
+	}
+	if other.TraceMulti != "" {
+		f.TraceMulti = other.TraceMulti
+	}
+	if other.Monitor != "" {
+		f.Monitor = other.Monitor
+	}
+	if other.Waterfall != "" {
+		f.Waterfall = other.Waterfall
+	}
+	if other.Scan != "" {
+		f.Scan = other.Scan
+	}
+}
+
+func expandHome(path string) string {
+	if path != "~" && !strings.HasPrefix(path, "~/") {
+		return path
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return path
+	}
+	return filepath.Join(home, path[1:])
+}
+
+// settings returns the settings of the selected profile.
+func (g *Globals) settings() (Profile, error) {
+	return g.config.Profile(g.Profile)
+}
+
+// BeforeResolve validates the selected profile, before its settings are resolved.
+func (g *Globals) BeforeResolve(ctx *kong.Context) error {
+	_, err := g.config.Profile(profileName(ctx))
+	return err
+}
+
+// profileResolver resolves the device flags from the selected profile of the config file.
+// Flags given on the command line or by environment variables take precedence, a given device id
+// also over the device port of the profile.
+func profileResolver(globals *Globals) kong.ResolverFunc {
+	return func(ctx *kong.Context, parent *kong.Path, flag *kong.Flag) (any, error) {
+		if flagGiven(ctx, flag) || (flag.Name == "device" && flagGiven(ctx, modelFlag(ctx, "device-id"))) {
+			return nil, nil
+		}
+
+		profile, err := globals.config.Profile(profileName(ctx))
+		if err != nil {
+			return nil, err
+		}
+
+		switch {
+		case flag.Name == "device" && profile.Device != "":
+			return profile.Device, nil
+		case flag.Name == "baudrate" && profile.Baudrate != 0:
+			return profile.Baudrate, nil
+		case flag.Name == "device-id" && profile.DeviceID != nil:
+			return *profile.DeviceID, nil
+		}
+		return nil, nil
+	}
+}
+
+// profileName returns the value of the --profile flag during parsing.
+func profileName(ctx *kong.Context) string {
+	name, _ := ctx.FlagValue(modelFlag(ctx, "profile")).(string)
+	return name
+}
+
+// modelFlag returns the global flag with the given name.
+func modelFlag(ctx *kong.Context, name string) *kong.Flag {
+	for _, flag := range ctx.Model.Flags {
+		if flag.Name == name {
+			return flag
+		}
+	}
+	return nil
+}
+
+// flagGiven reports whether flag is given on the command line or by one of its environment variables.
+func flagGiven(ctx *kong.Context, flag *kong.Flag) bool {
+	for _, env := range flag.Envs {
+		if _, ok := os.LookupEnv(env); ok {
+			return true
+		}
+	}
+	for _, element := range ctx.Path {
+		if element.Flag == flag {
+			return true
+		}
+	}
+	return false
+}
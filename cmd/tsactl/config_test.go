@@ -0,0 +1,205 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+const testConfig = `
+output_dir: /data
+filenames:
+  trace: "trace_<trace>.csv"
+profiles:
+  bench1:
+    device: /dev/ttyACM0
+  bench2:
+    device_id: 2
+    baudrate: 9600
+    output_dir: /data/bench2
+    filenames:
+      capture: "bench2_<date>.png"
+`
+
+func writeTestConfig(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadConfig(t *testing.T) {
+	config, err := loadConfig(filepath.Join(t.TempDir(), "missing.yaml"))
+	if err != nil {
+		t.Fatalf("missing file: %v", err)
+	}
+	if !reflect.DeepEqual(config, &Config{}) {
+		t.Errorf("missing file: config = %+v, want empty", config)
+	}
+
+	if _, err := loadConfig(writeTestConfig(t, "profiles: [")); err == nil {
+		t.Error("invalid file: expected error")
+	}
+}
+
+func TestConfigProfile(t *testing.T) {
+	config, err := loadConfig(writeTestConfig(t, testConfig))
+	if err != nil {
+		t.Fatal(err)
+	}
+	id := uint(2)
+
+	tests := []struct {
+		name    string
+		profile string
+		want    Profile
+		wantErr bool
+	}{
+		{
+			name: "defaults",
+			want: Profile{
+				OutputDir: "/data",
+				Filenames: Filenames{
+					Capture: filenameCaptureDefault, Trace: "trace_<trace>.csv", TraceMulti: filenameTraceMultiDefault,
+					Monitor: filenameMonitorDefault, Waterfall: filenameWaterfallDefault, Scan: filenameScanDefault,
+				},
+			},
+		},
+		{
+			name:    "port",
+			profile: "bench1",
+			want: Profile{
+				Device:    "/dev/ttyACM0",
+				OutputDir: "/data",
+				Filenames: Filenames{
+					Capture: filenameCaptureDefault, Trace: "trace_<trace>.csv", TraceMulti: filenameTraceMultiDefault,
+					Monitor: filenameMonitorDefault, Waterfall: filenameWaterfallDefault, Scan: filenameScanDefault,
+				},
+			},
+		},
+		{
+			name:    "device id",
+			profile: "bench2",
+			want: Profile{
+				Baudrate:  9600,
+				DeviceID:  &id,
+				OutputDir: "/data/bench2",
+				Filenames: Filenames{
+					Capture: "bench2_<date>.png", Trace: "trace_<trace>.csv", TraceMulti: filenameTraceMultiDefault,
+					Monitor: filenameMonitorDefault, Waterfall: filenameWaterfallDefault, Scan: filenameScanDefault,
+				},
+			},
+		},
+		{
+			name:    "unknown",
+			profile: "bench3",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := config.Profile(tt.profile)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Profile() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestProfileResolver(t *testing.T) {
+	config, err := loadConfig(writeTestConfig(t, testConfig+"default_profile: bench1\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name         string
+		args         []string
+		env          map[string]string
+		wantDevice   string
+		wantBaudrate int
+		wantDeviceID uint
+		wantErr      bool
+	}{
+		{
+			name:         "default profile",
+			args:         []string{"sweep"},
+			wantDevice:   "/dev/ttyACM0",
+			wantBaudrate: 115200,
+		},
+		{
+			name:         "selected profile",
+			args:         []string{"--profile", "bench2", "sweep"},
+			wantBaudrate: 9600,
+			wantDeviceID: 2,
+		},
+		{
+			name:         "flags take precedence",
+			args:         []string{"-P", "bench2", "--baudrate", "19200", "-D", "/dev/ttyUSB0", "sweep"},
+			wantDevice:   "/dev/ttyUSB0",
+			wantBaudrate: 19200,
+			wantDeviceID: 2,
+		},
+		{
+			name:         "env takes precedence",
+			args:         []string{"sweep"},
+			env:          map[string]string{"TSACTL_DEVICE": "/dev/ttyUSB1"},
+			wantDevice:   "/dev/ttyUSB1",
+			wantBaudrate: 115200,
+		},
+		{
+			name:         "device id takes precedence over profile device",
+			args:         []string{"--device-id", "3", "sweep"},
+			wantBaudrate: 115200,
+			wantDeviceID: 3,
+		},
+		{
+			name:         "device id env takes precedence over profile device",
+			args:         []string{"sweep"},
+			env:          map[string]string{"TSACTL_DEVICE_ID": "4"},
+			wantBaudrate: 115200,
+			wantDeviceID: 4,
+		},
+		{
+			name:    "unknown profile",
+			args:    []string{"-P", "bench3", "sweep"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for k, v := range tt.env {
+				t.Setenv(k, v)
+			}
+
+			var c Cli
+			c.config = config
+			parser, err := newParser(&c)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			_, err = parser.Parse(tt.args)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if c.Globals.Device != tt.wantDevice || c.Baudrate != tt.wantBaudrate {
+				t.Errorf("device = %q, baudrate = %d, want %q, %d", c.Globals.Device, c.Baudrate, tt.wantDevice, tt.wantBaudrate)
+			}
+			if (c.DeviceID == nil && tt.wantDeviceID != 0) || (c.DeviceID != nil && *c.DeviceID != tt.wantDeviceID) {
+				t.Errorf("device id = %v, want %d", c.DeviceID, tt.wantDeviceID)
+			}
+		})
+	}
+}
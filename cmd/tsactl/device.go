@@ -1,11 +1,133 @@
 package main
 
 import (
-	"github.com/kkettinger/go-tinysa"
+	"fmt"
+	"image"
 	"log/slog"
+	"strings"
+
+	"github.com/kkettinger/go-tinysa"
+	"github.com/kkettinger/tsactl/internal/sim"
+	"go.bug.st/serial"
 )
 
-func initDevice(globals *Globals) (*tinysa.Device, error) {
+// Device contains the methods of *tinysa.Device used by the commands.
+type Device interface {
+	Close() error
+	Model() tinysa.Model
+	Version() string
+	HardwareVersion() string
+	SendCommand(cmd string) (string, error)
+
+	Reset(dfu bool) error
+	GetDeviceID() (uint, error)
+	SetDeviceID(id uint) error
+	GetBatteryVoltage() (uint, error)
+	GetBatteryOffsetVoltage() (uint, error)
+	SetBatteryOffsetVoltage(voltage uint) error
+
+	Capture() (image.Image, error)
+	TriggerMenu(menuIDs []uint) error
+	LoadPreset(presetID uint) error
+	SavePreset(presetID uint) error
+
+	EnableSpurRemoval() error
+	DisableSpurRemoval() error
+	EnableAutoSpurRemoval() error
+	EnableLNA() error
+	DisableLNA() error
+
+	GetSweep() (tinysa.Sweep, error)
+	GetSweepStatus() (tinysa.SweepStatus, error)
+	SetSweepMode(mode tinysa.SweepMode) error
+	SetSweepStart(freqHz uint64) error
+	SetSweepStop(freqHz uint64) error
+	SetSweepCenter(freqHz uint64) error
+	SetSweepSpan(freqHz uint64) error
+	SetSweepContinuousWave(freqHz uint64) error
+	SetSweepTime(timeUs uint64) error
+	SetSweepPoints(points uint) error
+	PauseSweep() error
+	ResumeSweep() error
+
+	GetMarker(markerID uint) (tinysa.Marker, error)
+	GetMarkerAll() ([]tinysa.Marker, error)
+	EnableMarker(markerID uint) error
+	DisableMarker(markerID uint) error
+	SetMarkerFreq(markerID uint, freqHz uint64) error
+	SetMarkerTrace(markerID uint, traceID uint) error
+	MoveMarkerPeak(markerID uint) error
+	EnableMarkerDelta(markerID uint, refMarkerID uint) error
+	DisableMarkerDelta(markerID uint) error
+	EnableMarkerTracking(markerID uint) error
+	DisableMarkerTracking(markerID uint) error
+
+	GetTrace(traceID uint) (tinysa.Trace, error)
+	GetTraceAll() ([]tinysa.Trace, error)
+	GetTraceData(traceID uint) ([]tinysa.TraceData, error)
+	EnableTrace(traceID uint) error
+	DisableTrace(traceID uint) error
+	EnableTraceCalc(traceID uint, calc tinysa.TraceCalc) error
+	DisableTraceCalc(traceID uint) error
+	SetTraceUnit(unit tinysa.TraceUnit) error
+	SetTraceRefLevel(levelDbm int) error
+	SetTraceRefLevelAuto() error
+	SetTraceScale(level float64) error
+}
+
+// modelLimit contains the maximum frequency and sweep points of a device model.
+type modelLimit struct {
+	maxFrequency uint64
+	maxPoints    uint
+}
+
+var modelLimits = map[tinysa.Model]modelLimit{
+	tinysa.ModelBasic: {maxFrequency: 960_000_000, maxPoints: 290},
+	tinysa.ModelUltra: {maxFrequency: 6_000_000_000, maxPoints: 450},
+}
+
+// deviceLimit returns the limits of the model, or the limits of the tinySA Basic for unknown models.
+func deviceLimit(model tinysa.Model) modelLimit {
+	if limit, ok := modelLimits[model]; ok {
+		return limit
+	}
+	return modelLimits[tinysa.ModelBasic]
+}
+
+// simDevice is a device connected to a simulator, which is stopped when the device is closed.
+type simDevice struct {
+	*tinysa.Device
+	port *sim.Port
+}
+
+func (d *simDevice) Close() error {
+	err := d.Device.Close()
+	_ = d.port.Close()
+	return err
+}
+
+// listPorts returns the names of all serial ports.
+var listPorts = serial.GetPortsList
+
+// openPort connects to the device on the given serial port.
+var openPort = func(port string, opts ...tinysa.DeviceOption) (Device, error) {
+	d, err := tinysa.NewDevice(port, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// portDevice is a device found on a serial port by listDevices.
+type portDevice struct {
+	Port     string `json:"port" yaml:"port"`
+	Model    string `json:"model" yaml:"model"`
+	Firmware string `json:"firmware" yaml:"firmware"`
+	Hardware string `json:"hardware" yaml:"hardware"`
+	ID       *uint  `json:"id" yaml:"id"`
+}
+
+func deviceOptions(globals *Globals) []tinysa.DeviceOption {
 	var logger *slog.Logger
 
 	// attach custom log handler when debug output is enabled
@@ -13,14 +135,103 @@ func initDevice(globals *Globals) (*tinysa.Device, error) {
 		logger = slog.New(&CustomHandler{})
 	}
 
+	return []tinysa.DeviceOption{
+		tinysa.WithBaudRate(globals.Baudrate),
+		tinysa.WithLogger(logger),
+	}
+}
+
+func initDevice(globals *Globals) (Device, error) {
+	// use the already connected device, if any
+	if globals.device != nil {
+		return globals.device, nil
+	}
+
+	opts := deviceOptions(globals)
+
+	// find device by its device id, when no port name is given
+	if globals.Device == "" && globals.DeviceID != nil {
+		return findDeviceByID(*globals.DeviceID, opts...)
+	}
+
 	// try to find device when no port name is given
 	if globals.Device == "" {
-		return tinysa.FindDevice(
-			tinysa.WithBaudRate(globals.Baudrate),
-			tinysa.WithLogger(logger))
+		d, err := tinysa.FindDevice(opts...)
+		if err != nil {
+			return nil, err
+		}
+		return d, nil
 	}
 
-	return tinysa.NewDevice(globals.Device,
-		tinysa.WithBaudRate(globals.Baudrate),
-		tinysa.WithLogger(logger))
+	// start simulated device, e.g. sim://ultra
+	if strings.HasPrefix(globals.Device, sim.Scheme+"://") {
+		simOpts, err := sim.ParseURL(globals.Device)
+		if err != nil {
+			return nil, err
+		}
+		port, err := sim.Open(simOpts)
+		if err != nil {
+			return nil, err
+		}
+		d, err := tinysa.NewDevice(port.Name, opts...)
+		if err != nil {
+			_ = port.Close()
+			return nil, err
+		}
+		return &simDevice{Device: d, port: port}, nil
+	}
+
+	return openPort(globals.Device, opts...)
+}
+
+// findDeviceByID probes all serial ports and returns the device with the given device id.
+func findDeviceByID(id uint, opts ...tinysa.DeviceOption) (Device, error) {
+	ports, err := listPorts()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list serial ports: %w", err)
+	}
+
+	for _, port := range ports {
+		d, err := openPort(port, opts...)
+		if err != nil {
+			continue
+		}
+		if deviceID, err := d.GetDeviceID(); err == nil && deviceID == id {
+			return d, nil
+		}
+		_ = d.Close()
+	}
+
+	return nil, fmt.Errorf("no device with id %d found", id)
+}
+
+// listDevices probes all serial ports and returns the found devices.
+func listDevices(opts ...tinysa.DeviceOption) ([]portDevice, error) {
+	ports, err := listPorts()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list serial ports: %w", err)
+	}
+
+	devices := []portDevice{}
+	for _, port := range ports {
+		d, err := openPort(port, opts...)
+		if err != nil {
+			continue
+		}
+
+		found := portDevice{
+			Port:     port,
+			Model:    string(d.Model()),
+			Firmware: d.Version(),
+			Hardware: d.HardwareVersion(),
+		}
+		if id, err := d.GetDeviceID(); err == nil {
+			found.ID = &id
+		}
+		_ = d.Close()
+
+		devices = append(devices, found)
+	}
+
+	return devices, nil
 }
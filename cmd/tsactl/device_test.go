@@ -0,0 +1,203 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"strings"
+
+	"github.com/kkettinger/go-tinysa"
+)
+
+// fakeDevice is a Device that records every call and returns canned data.
+type fakeDevice struct {
+	calls []string
+	errs  map[string]error // errors returned by method name
+
+	model         tinysa.Model
+	version       string
+	hwVersion     string
+	deviceID      uint
+	battery       uint
+	batteryOffset uint
+	sweep         tinysa.Sweep
+	status        tinysa.SweepStatus
+	markers       []tinysa.Marker
+	traces        []tinysa.Trace
+	traceData     map[uint][]tinysa.TraceData
+	capture       image.Image
+	responses     map[string]string // responses of SendCommand
+}
+
+// newFakeDevice returns a fakeDevice with the defaults of a tinySA Ultra.
+func newFakeDevice() *fakeDevice {
+	return &fakeDevice{
+		model:     tinysa.ModelUltra,
+		version:   "1.4-197-gaa78ccc",
+		hwVersion: "0.4.5.1",
+		battery:   4100,
+		sweep:     tinysa.Sweep{Start: 400_000_000, Stop: 500_000_000, Points: 450},
+		status:    tinysa.SweepStatusResumed,
+		markers: []tinysa.Marker{
+			{Marker: 1, Index: 45, Frequency: 410_000_000, Value: -89.4},
+			{Marker: 2, Index: 214, Frequency: 447_700_000, Value: -67.9},
+		},
+		traces: []tinysa.Trace{
+			{Trace: 1, Unit: tinysa.TraceUnitDBm, RefPos: -10, Scale: 10},
+		},
+		traceData: map[uint][]tinysa.TraceData{
+			1: {
+				{Trace: 1, Point: 0, Frequency: 400_000_000, Value: -90.25},
+				{Trace: 1, Point: 1, Frequency: 450_000_000, Value: -40.5},
+				{Trace: 1, Point: 2, Frequency: 500_000_000, Value: -89.75},
+			},
+			2: {
+				{Trace: 2, Point: 0, Frequency: 400_000_000, Value: -84.78},
+				{Trace: 2, Point: 1, Frequency: 450_000_000, Value: -35},
+				{Trace: 2, Point: 2, Frequency: 500_000_000, Value: -85.75},
+			},
+		},
+		capture:   image.NewRGBA(image.Rect(0, 0, 480, 320)),
+		responses: map[string]string{},
+	}
+}
+
+func (f *fakeDevice) record(method string, args ...any) error {
+	strs := make([]string, len(args))
+	for i, a := range args {
+		strs[i] = fmt.Sprint(a)
+	}
+	f.calls = append(f.calls, fmt.Sprintf("%s(%s)", method, strings.Join(strs, ", ")))
+	return f.errs[method]
+}
+
+func (f *fakeDevice) Close() error            { return f.record("Close") }
+func (f *fakeDevice) Model() tinysa.Model     { return f.model }
+func (f *fakeDevice) Version() string         { return f.version }
+func (f *fakeDevice) HardwareVersion() string { return f.hwVersion }
+
+func (f *fakeDevice) SendCommand(cmd string) (string, error) {
+	return f.responses[cmd], f.record("SendCommand", cmd)
+}
+
+func (f *fakeDevice) Reset(dfu bool) error { return f.record("Reset", dfu) }
+
+func (f *fakeDevice) GetDeviceID() (uint, error) { return f.deviceID, f.record("GetDeviceID") }
+func (f *fakeDevice) SetDeviceID(id uint) error  { return f.record("SetDeviceID", id) }
+
+func (f *fakeDevice) GetBatteryVoltage() (uint, error) {
+	return f.battery, f.record("GetBatteryVoltage")
+}
+
+func (f *fakeDevice) GetBatteryOffsetVoltage() (uint, error) {
+	return f.batteryOffset, f.record("GetBatteryOffsetVoltage")
+}
+
+func (f *fakeDevice) SetBatteryOffsetVoltage(voltage uint) error {
+	return f.record("SetBatteryOffsetVoltage", voltage)
+}
+
+func (f *fakeDevice) Capture() (image.Image, error)    { return f.capture, f.record("Capture") }
+func (f *fakeDevice) TriggerMenu(menuIDs []uint) error { return f.record("TriggerMenu", menuIDs) }
+func (f *fakeDevice) LoadPreset(presetID uint) error   { return f.record("LoadPreset", presetID) }
+func (f *fakeDevice) SavePreset(presetID uint) error   { return f.record("SavePreset", presetID) }
+
+func (f *fakeDevice) EnableSpurRemoval() error     { return f.record("EnableSpurRemoval") }
+func (f *fakeDevice) DisableSpurRemoval() error    { return f.record("DisableSpurRemoval") }
+func (f *fakeDevice) EnableAutoSpurRemoval() error { return f.record("EnableAutoSpurRemoval") }
+func (f *fakeDevice) EnableLNA() error             { return f.record("EnableLNA") }
+func (f *fakeDevice) DisableLNA() error            { return f.record("DisableLNA") }
+
+func (f *fakeDevice) GetSweep() (tinysa.Sweep, error) { return f.sweep, f.record("GetSweep") }
+
+func (f *fakeDevice) GetSweepStatus() (tinysa.SweepStatus, error) {
+	return f.status, f.record("GetSweepStatus")
+}
+
+func (f *fakeDevice) SetSweepMode(mode tinysa.SweepMode) error { return f.record("SetSweepMode", mode) }
+func (f *fakeDevice) SetSweepStart(freqHz uint64) error        { return f.record("SetSweepStart", freqHz) }
+func (f *fakeDevice) SetSweepStop(freqHz uint64) error         { return f.record("SetSweepStop", freqHz) }
+func (f *fakeDevice) SetSweepCenter(freqHz uint64) error       { return f.record("SetSweepCenter", freqHz) }
+func (f *fakeDevice) SetSweepSpan(freqHz uint64) error         { return f.record("SetSweepSpan", freqHz) }
+func (f *fakeDevice) SetSweepTime(timeUs uint64) error         { return f.record("SetSweepTime", timeUs) }
+func (f *fakeDevice) SetSweepPoints(points uint) error         { return f.record("SetSweepPoints", points) }
+func (f *fakeDevice) PauseSweep() error                        { return f.record("PauseSweep") }
+func (f *fakeDevice) ResumeSweep() error                       { return f.record("ResumeSweep") }
+
+func (f *fakeDevice) SetSweepContinuousWave(freqHz uint64) error {
+	return f.record("SetSweepContinuousWave", freqHz)
+}
+
+func (f *fakeDevice) GetMarker(markerID uint) (tinysa.Marker, error) {
+	for _, m := range f.markers {
+		if m.Marker == markerID {
+			return m, f.record("GetMarker", markerID)
+		}
+	}
+	return tinysa.Marker{Marker: markerID}, f.record("GetMarker", markerID)
+}
+
+func (f *fakeDevice) GetMarkerAll() ([]tinysa.Marker, error) {
+	return f.markers, f.record("GetMarkerAll")
+}
+
+func (f *fakeDevice) EnableMarker(markerID uint) error  { return f.record("EnableMarker", markerID) }
+func (f *fakeDevice) DisableMarker(markerID uint) error { return f.record("DisableMarker", markerID) }
+
+func (f *fakeDevice) SetMarkerFreq(markerID uint, freqHz uint64) error {
+	return f.record("SetMarkerFreq", markerID, freqHz)
+}
+
+func (f *fakeDevice) SetMarkerTrace(markerID uint, traceID uint) error {
+	return f.record("SetMarkerTrace", markerID, traceID)
+}
+
+func (f *fakeDevice) MoveMarkerPeak(markerID uint) error { return f.record("MoveMarkerPeak", markerID) }
+
+func (f *fakeDevice) EnableMarkerDelta(markerID uint, refMarkerID uint) error {
+	return f.record("EnableMarkerDelta", markerID, refMarkerID)
+}
+
+func (f *fakeDevice) DisableMarkerDelta(markerID uint) error {
+	return f.record("DisableMarkerDelta", markerID)
+}
+
+func (f *fakeDevice) EnableMarkerTracking(markerID uint) error {
+	return f.record("EnableMarkerTracking", markerID)
+}
+
+func (f *fakeDevice) DisableMarkerTracking(markerID uint) error {
+	return f.record("DisableMarkerTracking", markerID)
+}
+
+func (f *fakeDevice) GetTrace(traceID uint) (tinysa.Trace, error) {
+	for _, t := range f.traces {
+		if t.Trace == traceID {
+			return t, f.record("GetTrace", traceID)
+		}
+	}
+	return tinysa.Trace{Trace: traceID, Unit: tinysa.TraceUnitDBm}, f.record("GetTrace", traceID)
+}
+
+func (f *fakeDevice) GetTraceAll() ([]tinysa.Trace, error) { return f.traces, f.record("GetTraceAll") }
+
+func (f *fakeDevice) GetTraceData(traceID uint) ([]tinysa.TraceData, error) {
+	return f.traceData[traceID], f.record("GetTraceData", traceID)
+}
+
+func (f *fakeDevice) EnableTrace(traceID uint) error  { return f.record("EnableTrace", traceID) }
+func (f *fakeDevice) DisableTrace(traceID uint) error { return f.record("DisableTrace", traceID) }
+
+func (f *fakeDevice) EnableTraceCalc(traceID uint, calc tinysa.TraceCalc) error {
+	return f.record("EnableTraceCalc", traceID, calc)
+}
+
+func (f *fakeDevice) DisableTraceCalc(traceID uint) error {
+	return f.record("DisableTraceCalc", traceID)
+}
+
+func (f *fakeDevice) SetTraceUnit(unit tinysa.TraceUnit) error { return f.record("SetTraceUnit", unit) }
+func (f *fakeDevice) SetTraceRefLevel(levelDbm int) error {
+	return f.record("SetTraceRefLevel", levelDbm)
+}
+func (f *fakeDevice) SetTraceRefLevelAuto() error       { return f.record("SetTraceRefLevelAuto") }
+func (f *fakeDevice) SetTraceScale(level float64) error { return f.record("SetTraceScale", level) }
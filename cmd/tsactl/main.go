@@ -1,6 +1,7 @@
 package main
 
 import (
+	"io"
 	"os"
 	"strings"
 
@@ -8,9 +9,24 @@ import (
 )
 
 type Globals struct {
-	Device   string `help:"Device serial port, e.g. /dev/ttyACM0 or COM1" short:"D" placeholder:"PORT" env:"TSACTL_DEVICE"`
+	Device   string `help:"Device serial port, e.g. /dev/ttyACM0, COM1 or sim://ultra" short:"D" placeholder:"PORT" env:"TSACTL_DEVICE"`
+	DeviceID *uint  `help:"Device id of the device to find, when no port is given" name:"device-id" placeholder:"ID" env:"TSACTL_DEVICE_ID"`
 	Baudrate int    `help:"Device baudrate rate" default:"115200" env:"TSACTL_BAUDRATE"`
 	Debug    bool   `help:"Enable debug output" env:"TSACTL_DEBUG"`
+	Format   string `help:"Output format of status queries and tables (${enum})" short:"F" enum:"text,json,yaml,csv" default:"text" env:"TSACTL_FORMAT"`
+	Profile  string `help:"Device profile of the config file" short:"P" placeholder:"NAME" env:"TSACTL_PROFILE"`
+
+	// device is returned by initDevice instead of opening a new connection, when set
+	device Device
+
+	// config is the loaded config file
+	config *Config
+
+	// script is set while a script runs, which must not run scripts itself
+	script bool
+
+	// readLine reads a line of the session input, shared with the wait directive of scripts
+	readLine func() (string, error)
 }
 
 type Cli struct {
@@ -18,26 +34,64 @@ type Cli struct {
 
 	Version kong.VersionFlag `help:"Show tsactl version" short:"v"`
 
-	Device DeviceCmd `help:"Access device status, ID, battery, and firmware info" cmd:"" aliases:"dev"`
-	Level  LevelCmd  `help:"Set trace unit, reference level, and scale" cmd:"" aliases:"lv"`
-	Marker MarkerCmd `help:"Enable marker, set frequency, and tracking" cmd:"" aliases:"mk"`
-	Menu   MenuCmd   `help:"Trigger menu actions by ID" cmd:""`
-	Preset PresetCmd `help:"Load or save device presets" cmd:"" aliases:"pr"`
-	Raw    RawCmd    `help:"Send low-level raw commands" cmd:""`
-	Save   SaveCmd   `help:"Export screen capture or trace data to file" cmd:""`
-	Signal SignalCmd `help:"Configure signal processing options" cmd:"" aliases:"sig"`
-	Sweep  SweepCmd  `help:"Set sweep parameters like freq range and mode" cmd:"" aliases:"sw"`
-	Trace  TraceCmd  `help:"Enable traces and set calculation modes" cmd:"" aliases:"tr"`
+	Channels  ChannelsCmd  `help:"Measure the power of the channels of a channel plan" cmd:""`
+	Check     CheckCmd     `help:"Check a trace against a limit mask, exits with 2 on failure" cmd:""`
+	Device    DeviceCmd    `help:"Access device status, ID, battery, and firmware info" cmd:"" aliases:"dev"`
+	Diff      DiffCmd      `help:"Compare a trace against a reference CSV file" cmd:""`
+	Exporter  ExporterCmd  `help:"Serve device readings as Prometheus metrics" cmd:""`
+	Harmonics HarmonicsCmd `help:"Measure the harmonics of a transmitter relative to its fundamental" cmd:""`
+	Level     LevelCmd     `help:"Set trace unit, reference level, and scale" cmd:"" aliases:"lv"`
+	Marker    MarkerCmd    `help:"Enable marker, set frequency, and tracking" cmd:"" aliases:"mk"`
+	Measure   MeasureCmd   `help:"Measure channel power, occupied bandwidth, noise floor or third-order intercept" cmd:""`
+	Menu      MenuCmd      `help:"Trigger menu actions by ID" cmd:""`
+	Monitor   MonitorCmd   `help:"Poll traces continuously and log them to file" cmd:"" aliases:"mon"`
+	Peaks     PeaksCmd     `help:"Search the peaks of a trace and print them as ranked table" cmd:""`
+	Plot      PlotCmd      `help:"Render trace CSV files as SVG or PNG chart" cmd:""`
+	Preset    PresetCmd    `help:"Load or save device presets" cmd:"" aliases:"pr"`
+	Raw       RawCmd       `help:"Send low-level raw commands" cmd:""`
+	Run       RunCmd       `help:"Run a script of commands over a single connection" cmd:""`
+	Save      SaveCmd      `help:"Export screen capture or trace data to file" cmd:""`
+	Scan      ScanCmd      `help:"Scan a wide range in segments and stitch them into one high resolution trace" cmd:""`
+	Scpi      ScpiCmd      `help:"Serve device operations as SCPI over TCP" cmd:""`
+	Serve     ServeCmd     `help:"Serve device operations as HTTP JSON API" cmd:""`
+	Shell     ShellCmd     `help:"Run commands interactively over a single connection" cmd:""`
+	Signal    SignalCmd    `help:"Configure signal processing options" cmd:"" aliases:"sig"`
+	Sweep     SweepCmd     `help:"Set sweep parameters like freq range and mode" cmd:"" aliases:"sw"`
+	Trace     TraceCmd     `help:"Enable traces and set calculation modes" cmd:"" aliases:"tr"`
+	Watch     WatchCmd     `help:"Plot traces live in the terminal" cmd:""`
+	Waterfall WaterfallCmd `help:"Record successive sweeps as spectrogram image and CSV" cmd:""`
 }
 
 var cli Cli
 
+// stdout receives the output of all commands.
+var stdout io.Writer = os.Stdout
+
+// stdin is read by the interactive shell.
+var stdin io.Reader = os.Stdin
+
 func main() {
 	// If running without any extra arguments, default to the --help flag
 	if len(os.Args) < 2 {
 		os.Args = append(os.Args, "--help")
 	}
 
+	parser, err := newParser(&cli)
+	if err != nil {
+		panic(err)
+	}
+
+	cli.config, err = loadConfig(configPath())
+	parser.FatalIfErrorf(err)
+
+	ctx, err := parser.Parse(os.Args[1:])
+	parser.FatalIfErrorf(err)
+
+	err = ctx.Run(&cli.Globals)
+	ctx.FatalIfErrorf(err)
+}
+
+func newParser(cli *Cli, options ...kong.Option) (*kong.Kong, error) {
 	// Variables
 	traceCalc := TraceCalc{}
 	traceCalcOpts := strings.Join(traceCalc.ValidOpts(), ", ")
@@ -45,10 +99,16 @@ func main() {
 	traceUnit := TraceUnit{}
 	traceUnitOpts := strings.Join(traceUnit.ValidOpts(), ", ")
 
+	hostUnit := HostUnit{}
+	hostUnitOpts := strings.Join(hostUnit.ValidOpts(), ", ")
+
 	sweepMode := SweepMode{}
 	sweepModeOpts := strings.Join(sweepMode.ValidOpts(), ", ")
 
-	ctx := kong.Parse(&cli,
+	outputFormat := OutputFormat{}
+	formatOpts := strings.Join(outputFormat.ValidOpts(), ", ")
+
+	return kong.New(cli, append([]kong.Option{
 		kong.Name("tsactl"),
 		kong.Description("Command line tool for the tinySA spectrum analyzer."),
 		kong.Vars{"version": getVersion()},
@@ -58,11 +118,12 @@ func main() {
 		kong.Vars{
 			"trace_calc_opts": traceCalcOpts,
 			"trace_unit_opts": traceUnitOpts,
+			"host_unit_opts":  hostUnitOpts,
 			"sweep_mode_opts": sweepModeOpts,
+			"format_opts":     formatOpts,
 		},
 		kong.WithHyphenPrefixedParameters(true),
-	)
-
-	err := ctx.Run(&cli.Globals)
-	ctx.FatalIfErrorf(err)
+		kong.Resolvers(profileResolver(&cli.Globals)),
+		kong.Writers(stdout, os.Stderr),
+	}, options...)...)
 }
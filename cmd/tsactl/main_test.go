@@ -0,0 +1,188 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/alecthomas/kong"
+)
+
+// runCli parses and runs the given command line against d and returns the printed output.
+func runCli(t *testing.T, d Device, args ...string) (string, error) {
+	t.Helper()
+	return runCliConfig(t, d, nil, args...)
+}
+
+// runCliConfig is like runCli, with the given config file content.
+func runCliConfig(t *testing.T, d Device, config *Config, args ...string) (string, error) {
+	t.Helper()
+
+	var out bytes.Buffer
+	stdout = &out
+	t.Cleanup(func() { stdout = os.Stdout })
+
+	c := Cli{Globals: Globals{config: config}}
+	parser, err := newParser(&c, kong.Exit(func(code int) {
+		t.Fatalf("unexpected exit with code %d", code)
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, err := parser.Parse(args)
+	if err != nil {
+		return out.String(), err
+	}
+
+	c.device = d
+	err = ctx.Run(&c.Globals)
+	return out.String(), err
+}
+
+type cliTest struct {
+	name      string
+	args      []string
+	setup     func(d *fakeDevice)
+	wantOut   string
+	wantCalls []string
+	wantErr   bool
+}
+
+// runCliTests runs every test against a new fakeDevice and compares output and issued calls.
+func runCliTests(t *testing.T, tests []cliTest) {
+	t.Helper()
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := newFakeDevice()
+			if tt.setup != nil {
+				tt.setup(d)
+			}
+
+			out, err := runCli(t, d, tt.args...)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if out != tt.wantOut {
+				t.Errorf("output:\n%s\nwant:\n%s", out, tt.wantOut)
+			}
+			gotCalls := strings.Join(d.calls, "\n")
+			wantCalls := strings.Join(tt.wantCalls, "\n")
+			if gotCalls != wantCalls {
+				t.Errorf("calls:\n%s\nwant:\n%s", gotCalls, wantCalls)
+			}
+		})
+	}
+}
+
+// TestFormatCSV runs every command with --format csv. Commands printing status or tables must print a CSV
+// table, the others must accept the flag.
+func TestFormatCSV(t *testing.T) {
+	t.Chdir(t.TempDir())
+	files := map[string]string{
+		"mask.csv":  "frequency,upper\n400M,0\n500M,0\n",
+		"plan.csv":  "name,center,bandwidth\nWide,450M,100M\n",
+		"trace.csv": "trace,point,frequency,value\n1,0,400000000,-90\n1,1,450000000,-42\n1,2,500000000,-89.75\n",
+		"run.tsa":   "sweep\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(name, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	stubPorts(t, map[string]*fakeDevice{"/dev/ttyACM0": newFakeDevice()})
+
+	newSweepDevice := func() Device { return &sweepDevice{fakeDevice: newFakeDevice()} }
+	tests := map[string]struct {
+		args      []string
+		input     string
+		newDevice func() Device
+		table     bool
+	}{
+		"channels":      {args: []string{"channels", "-p", "plan.csv", "--dwell", "0"}, newDevice: newSweepDevice, table: true},
+		"check":         {args: []string{"check", "-m", "mask.csv"}, table: true},
+		"device":        {args: []string{"device", "--info"}, table: true},
+		"diff":          {args: []string{"diff", "-r", "trace.csv"}, table: true},
+		"harmonics":     {args: []string{"harmonics", "-f", "100M", "-n", "2", "--dwell", "0"}, newDevice: func() Device { return &harmonicDevice{&sweepDevice{fakeDevice: newFakeDevice()}} }, table: true},
+		"level":         {args: []string{"level", "--unit", "dbm"}},
+		"marker":        {args: []string{"marker"}, table: true},
+		"measure power": {args: []string{"measure", "--channel-power", "450M:50M", "--obw", "99%"}, table: true},
+		"measure noise": {args: []string{"measure", "noise", "--signal", "peak"}, table: true},
+		"measure toi":   {args: []string{"measure", "toi", "--f1", "100M", "--f2", "101M", "--dwell", "0"}, newDevice: func() Device { return &toneDevice{&sweepDevice{fakeDevice: newFakeDevice()}} }, table: true},
+		"menu":          {args: []string{"menu", "6", "2"}},
+		"monitor":       {args: []string{"monitor", "-n", "1", "-o", "monitor.csv"}},
+		"peaks":         {args: []string{"peaks"}, table: true},
+		"plot":          {args: []string{"plot", "trace.csv", "-o", "plot.svg"}},
+		"preset":        {args: []string{"preset", "--load", "1"}},
+		"raw":           {args: []string{"raw", "sd_list"}},
+		"run":           {args: []string{"run", "run.tsa"}},
+		"save":          {args: []string{"save", "--trace", "1", "-o", "save.csv"}},
+		"scan":          {args: []string{"scan", "--start", "100M", "--stop", "101.9M", "--rbw-step", "100k", "--points", "10", "--dwell", "0", "-o", "scan.csv"}, newDevice: newSweepDevice},
+		"shell":         {args: []string{"shell"}, input: "trace\n"},
+		"signal":        {args: []string{"signal", "--spur", "on"}},
+		"sweep":         {args: []string{"sweep"}, table: true},
+		"trace":         {args: []string{"trace"}, table: true},
+		"watch":         {args: []string{"watch", "-n", "1", "-i", "1ms"}},
+		"waterfall":     {args: []string{"waterfall", "-n", "1", "-i", "1ms", "-o", "waterfall.png"}},
+	}
+	skipped := map[string]string{
+		"exporter": "serves until interrupted, its output is the Prometheus format",
+		"scpi":     "serves until interrupted, its output is the SCPI protocol",
+		"serve":    "serves until interrupted, its output is the JSON API",
+	}
+
+	var c Cli
+	parser, err := newParser(&c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var commands []string
+	var walk func(n *kong.Node, path string)
+	walk = func(n *kong.Node, path string) {
+		if n.Type == kong.CommandNode {
+			path = strings.TrimSpace(path + " " + n.Name)
+			if len(n.Children) == 0 {
+				commands = append(commands, path)
+			}
+		}
+		for _, child := range n.Children {
+			walk(child, path)
+		}
+	}
+	walk(parser.Model.Node, "")
+
+	for _, name := range commands {
+		t.Run(name, func(t *testing.T) {
+			if reason, ok := skipped[name]; ok {
+				t.Skip(reason)
+			}
+			tt, ok := tests[name]
+			if !ok {
+				t.Fatalf("command '%s' is not covered", name)
+			}
+
+			var d Device = newFakeDevice()
+			if tt.newDevice != nil {
+				d = tt.newDevice()
+			}
+			stdin = strings.NewReader(tt.input)
+			t.Cleanup(func() { stdin = nil })
+
+			out, err := runCli(t, d, append([]string{"-F", "csv"}, tt.args...)...)
+			if err != nil {
+				t.Fatalf("error = %v, output:\n%s", err, out)
+			}
+			if !tt.table {
+				return
+			}
+			// a header of several columns, tables without matches have no rows
+			records, err := csv.NewReader(strings.NewReader(out)).ReadAll()
+			if err != nil || len(records) == 0 || len(records[0]) < 2 {
+				t.Errorf("output is no CSV table (%v):\n%s", err, out)
+			}
+		})
+	}
+}
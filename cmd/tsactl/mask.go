@@ -0,0 +1,173 @@
+package main
+
+import (
+	"cmp"
+	"encoding/csv"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/kkettinger/tsactl/internal/util"
+	"gopkg.in/yaml.v3"
+)
+
+// limitPoint is a point of a limit line, the line is linearly interpolated between the points.
+type limitPoint struct {
+	Frequency uint64
+	Level     float64
+}
+
+// mask is a pair of upper and lower limit lines, either may be empty.
+type mask struct {
+	Upper []limitPoint
+	Lower []limitPoint
+}
+
+type maskFile struct {
+	Upper []maskFilePoint `yaml:"upper"`
+	Lower []maskFilePoint `yaml:"lower"`
+}
+
+type maskFilePoint struct {
+	Frequency string  `yaml:"frequency"`
+	Level     float64 `yaml:"level"`
+}
+
+// loadMask reads a mask from a YAML file with upper and lower lists of frequency and level,
+// or from a CSV file with the columns frequency, upper and lower, where empty cells are skipped.
+func loadMask(path string) (*mask, error) {
+	var m *mask
+	var err error
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		m, err = loadMaskYAML(path)
+	case ".csv":
+		m, err = loadMaskCSV(path)
+	default:
+		return nil, fmt.Errorf("unsupported mask file '%s', must be .yaml, .yml or .csv", path)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if len(m.Upper) == 0 && len(m.Lower) == 0 {
+		return nil, fmt.Errorf("mask '%s' has no limits", path)
+	}
+	for i, line := range [][]limitPoint{m.Upper, m.Lower} {
+		name := []string{"upper", "lower"}[i]
+		if len(line) == 1 {
+			return nil, fmt.Errorf("%s limit of mask '%s' needs at least two points", name, path)
+		}
+		if !slices.IsSortedFunc(line, func(a, b limitPoint) int { return cmp.Compare(a.Frequency, b.Frequency) }) {
+			return nil, fmt.Errorf("%s limit of mask '%s' must be sorted by frequency", name, path)
+		}
+	}
+
+	return m, nil
+}
+
+func loadMaskYAML(path string) (*mask, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read mask '%s': %w", path, err)
+	}
+
+	var file maskFile
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse mask '%s': %w", path, err)
+	}
+
+	var m mask
+	for _, p := range file.Upper {
+		freq, err := util.ParseFrequency(p.Frequency)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse mask '%s': %w", path, err)
+		}
+		m.Upper = append(m.Upper, limitPoint{Frequency: freq, Level: p.Level})
+	}
+	for _, p := range file.Lower {
+		freq, err := util.ParseFrequency(p.Frequency)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse mask '%s': %w", path, err)
+		}
+		m.Lower = append(m.Lower, limitPoint{Frequency: freq, Level: p.Level})
+	}
+	return &m, nil
+}
+
+func loadMaskCSV(path string) (*mask, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open mask '%s': %w", path, err)
+	}
+	defer file.Close()
+
+	records, err := csv.NewReader(file).ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read mask '%s': %w", path, err)
+	}
+	if len(records) == 0 || records[0][0] != "frequency" {
+		return nil, fmt.Errorf("mask '%s' must start with a header of frequency, upper and lower", path)
+	}
+
+	header := records[0]
+	for _, column := range header[1:] {
+		if column != "upper" && column != "lower" {
+			return nil, fmt.Errorf("mask '%s' has unknown column '%s', must be upper or lower", path, column)
+		}
+	}
+
+	var m mask
+	for i, row := range records[1:] {
+		freq, err := util.ParseFrequency(row[0])
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse mask '%s' line %d: %w", path, i+2, err)
+		}
+		for j, column := range header[1:] {
+			cell := strings.TrimSpace(row[j+1])
+			if cell == "" {
+				continue
+			}
+			level, err := strconv.ParseFloat(cell, 64)
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse mask '%s' line %d: invalid level '%s'", path, i+2, cell)
+			}
+			if column == "upper" {
+				m.Upper = append(m.Upper, limitPoint{Frequency: freq, Level: level})
+			} else {
+				m.Lower = append(m.Lower, limitPoint{Frequency: freq, Level: level})
+			}
+		}
+	}
+	return &m, nil
+}
+
+// limitAt returns the level of the limit line at freq, and false if the line doesn't cover freq.
+// At steps with two points of the same frequency, the stricter level is returned.
+func limitAt(line []limitPoint, freq uint64, upper bool) (float64, bool) {
+	var level float64
+	found := false
+	for i := 1; i < len(line); i++ {
+		a, b := line[i-1], line[i]
+		if freq < a.Frequency || freq > b.Frequency {
+			continue
+		}
+
+		v := a.Level
+		if b.Frequency > a.Frequency {
+			v += (b.Level - a.Level) * float64(freq-a.Frequency) / float64(b.Frequency-a.Frequency)
+		} else if (upper && b.Level < v) || (!upper && b.Level > v) {
+			v = b.Level
+		}
+
+		if !found || (upper && v < level) || (!upper && v > level) {
+			level = v
+		}
+		found = true
+	}
+	return level, found
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestLoadMask(t *testing.T) {
+	tests := []struct {
+		name    string
+		file    string
+		content string
+		want    *mask
+		wantErr bool
+	}{
+		{
+			name:    "yaml",
+			file:    "mask.yaml",
+			content: "upper:\n  - {frequency: 100M, level: -30}\n  - {frequency: 200000000, level: -40}\n",
+			want:    &mask{Upper: []limitPoint{{100_000_000, -30}, {200_000_000, -40}}},
+		},
+		{
+			name:    "csv with empty cells",
+			file:    "mask.csv",
+			content: "frequency,upper,lower\n100M,-30,\n150M,-35,-90\n200M,-40,-90\n",
+			want: &mask{
+				Upper: []limitPoint{{100_000_000, -30}, {150_000_000, -35}, {200_000_000, -40}},
+				Lower: []limitPoint{{150_000_000, -90}, {200_000_000, -90}},
+			},
+		},
+		{name: "unknown column", file: "mask.csv", content: "frequency,limit\n100M,-30\n200M,-30\n", wantErr: true},
+		{name: "missing header", file: "mask.csv", content: "100M,-30\n200M,-30\n", wantErr: true},
+		{name: "invalid level", file: "mask.csv", content: "frequency,upper\n100M,high\n200M,-30\n", wantErr: true},
+		{name: "single point", file: "mask.yaml", content: "lower:\n  - {frequency: 100M, level: -30}\n", wantErr: true},
+		{name: "unsorted", file: "mask.csv", content: "frequency,upper\n200M,-30\n100M,-30\n", wantErr: true},
+		{name: "no limits", file: "mask.yaml", content: "upper: []\n", wantErr: true},
+		{name: "unsupported extension", file: "mask.txt", content: "", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), tt.file)
+			if err := os.WriteFile(path, []byte(tt.content), 0o600); err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := loadMask(path)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("mask = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLimitAt(t *testing.T) {
+	// a step from -30 to -50 at 200 MHz
+	line := []limitPoint{{100, -30}, {200, -30}, {200, -50}, {300, -40}}
+
+	tests := []struct {
+		freq   uint64
+		upper  bool
+		want   float64
+		wantOk bool
+	}{
+		{freq: 50, upper: true, wantOk: false},
+		{freq: 100, upper: true, want: -30, wantOk: true},
+		{freq: 150, upper: true, want: -30, wantOk: true},
+		{freq: 200, upper: true, want: -50, wantOk: true},
+		{freq: 200, upper: false, want: -30, wantOk: true},
+		{freq: 250, upper: true, want: -45, wantOk: true},
+		{freq: 300, upper: true, want: -40, wantOk: true},
+		{freq: 301, upper: true, wantOk: false},
+	}
+	for _, tt := range tests {
+		got, ok := limitAt(line, tt.freq, tt.upper)
+		if ok != tt.wantOk || got != tt.want {
+			t.Errorf("limitAt(%d, %v) = %v, %v, want %v, %v", tt.freq, tt.upper, got, ok, tt.want, tt.wantOk)
+		}
+	}
+}
@@ -0,0 +1,219 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	formatText = "text"
+	formatJSON = "json"
+	formatYAML = "yaml"
+	formatCSV  = "csv"
+)
+
+var formats = []string{formatText, formatJSON, formatYAML, formatCSV}
+
+// OutputFlag adds --output to the status queries, which selects the output format like the global --format. It is
+// not global, because the commands writing files already use -o/--output for their output path.
+type OutputFlag struct {
+	Output OutputFormat `help:"Output format, overrides --format (${format_opts})" placeholder:"FORMAT" group:"Output flags:"`
+}
+
+// outputFormat returns the format given by --output, or else the global format.
+func (f OutputFlag) outputFormat(globals *Globals) string {
+	if f.Output.Valid {
+		return f.Output.Format
+	}
+	return globals.Format
+}
+
+type sweepInfo struct {
+	Status string `json:"status" yaml:"status"`
+	Start  uint64 `json:"start" yaml:"start"`
+	Stop   uint64 `json:"stop" yaml:"stop"`
+	Center uint64 `json:"center" yaml:"center"`
+	Span   uint64 `json:"span" yaml:"span"`
+	Points uint   `json:"points" yaml:"points"`
+}
+
+type markerInfo struct {
+	Marker    uint    `json:"marker" yaml:"marker"`
+	Frequency uint64  `json:"frequency" yaml:"frequency"`
+	Value     float64 `json:"value" yaml:"value"`
+	Index     uint    `json:"index" yaml:"index"`
+}
+
+type traceInfo struct {
+	Trace  uint    `json:"trace" yaml:"trace"`
+	Unit   string  `json:"unit" yaml:"unit"`
+	Scale  float64 `json:"scale" yaml:"scale"`
+	RefPos float64 `json:"refpos" yaml:"refpos"`
+}
+
+type deviceInfo struct {
+	Model         string `json:"model,omitempty" yaml:"model,omitempty"`
+	Firmware      string `json:"firmware,omitempty" yaml:"firmware,omitempty"`
+	Hardware      string `json:"hardware,omitempty" yaml:"hardware,omitempty"`
+	ID            *uint  `json:"id,omitempty" yaml:"id,omitempty"`
+	Battery       *uint  `json:"battery_mv,omitempty" yaml:"battery_mv,omitempty"`
+	BatteryOffset *uint  `json:"battery_offset_mv,omitempty" yaml:"battery_offset_mv,omitempty"`
+}
+
+// printStructured writes v to stdout in the given structured output format.
+func printStructured(format string, v any) error {
+	switch format {
+	case formatJSON:
+		enc := json.NewEncoder(stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(v)
+	case formatYAML:
+		enc := yaml.NewEncoder(stdout)
+		enc.SetIndent(2)
+		if err := enc.Encode(v); err != nil {
+			return err
+		}
+		return enc.Close()
+	case formatCSV:
+		header, rows, err := csvTable(v)
+		if err != nil {
+			return err
+		}
+		return printCSV(header, rows)
+	default:
+		return fmt.Errorf("unsupported output format '%s'", format)
+	}
+}
+
+// printCSV writes the header and rows to stdout as CSV, for commands printing tables.
+func printCSV(header []string, rows [][]string) error {
+	writer := csv.NewWriter(stdout)
+	if err := writer.Write(header); err != nil {
+		return err
+	}
+	return writer.WriteAll(rows)
+}
+
+// csvTable converts a struct, or a slice of structs with one row each, to a CSV table. The columns are named
+// by the json tags, fields of nested structs are prefixed with the name of the struct field.
+func csvTable(v any) ([]string, [][]string, error) {
+	rv := reflect.ValueOf(v)
+	elemType := rv.Type()
+	if rv.Kind() == reflect.Slice {
+		elemType = elemType.Elem()
+	}
+	for elemType.Kind() == reflect.Pointer {
+		elemType = elemType.Elem()
+	}
+	if elemType.Kind() != reflect.Struct {
+		return nil, nil, fmt.Errorf("unsupported csv value of type %s", rv.Type())
+	}
+
+	header, err := csvHeader(elemType, "")
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var rows [][]string
+	if rv.Kind() == reflect.Slice {
+		for i := range rv.Len() {
+			rows = append(rows, csvRow(rv.Index(i), elemType, nil))
+		}
+	} else {
+		rows = append(rows, csvRow(rv, elemType, nil))
+	}
+	return header, rows, nil
+}
+
+// csvFieldName returns the column name of a struct field by its json tag, or false if it is skipped.
+func csvFieldName(f reflect.StructField) (string, bool) {
+	name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+	if !f.IsExported() || name == "-" {
+		return "", false
+	}
+	if name == "" {
+		name = strings.ToLower(f.Name)
+	}
+	return name, true
+}
+
+func csvHeader(t reflect.Type, prefix string) ([]string, error) {
+	var header []string
+	for i := range t.NumField() {
+		f := t.Field(i)
+		name, ok := csvFieldName(f)
+		if !ok {
+			continue
+		}
+		ft := f.Type
+		if ft.Kind() == reflect.Pointer {
+			ft = ft.Elem()
+		}
+		switch ft.Kind() {
+		case reflect.Struct:
+			nested, err := csvHeader(ft, prefix+name+"_")
+			if err != nil {
+				return nil, err
+			}
+			header = append(header, nested...)
+		case reflect.Slice, reflect.Array, reflect.Map:
+			return nil, fmt.Errorf("unsupported csv column '%s' of type %s", prefix+name, f.Type)
+		default:
+			header = append(header, prefix+name)
+		}
+	}
+	return header, nil
+}
+
+// csvRow appends the cells of the struct v of type t to row. The fields of a nil struct are empty cells.
+func csvRow(v reflect.Value, t reflect.Type, row []string) []string {
+	v = derefValue(v)
+	for i := range t.NumField() {
+		f := t.Field(i)
+		if _, ok := csvFieldName(f); !ok {
+			continue
+		}
+		var fv reflect.Value
+		if v.IsValid() {
+			fv = derefValue(v.Field(i))
+		}
+
+		ft := f.Type
+		if ft.Kind() == reflect.Pointer {
+			ft = ft.Elem()
+		}
+		if ft.Kind() == reflect.Struct {
+			row = csvRow(fv, ft, row)
+			continue
+		}
+		row = append(row, csvCell(fv))
+	}
+	return row
+}
+
+// derefValue follows pointers, a nil pointer results in the invalid zero value.
+func derefValue(v reflect.Value) reflect.Value {
+	for v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			return reflect.Value{}
+		}
+		v = v.Elem()
+	}
+	return v
+}
+
+func csvCell(v reflect.Value) string {
+	if !v.IsValid() {
+		return ""
+	}
+	if v.Kind() == reflect.Float32 || v.Kind() == reflect.Float64 {
+		return strconv.FormatFloat(v.Float(), 'f', -1, 64)
+	}
+	return fmt.Sprint(v.Interface())
+}
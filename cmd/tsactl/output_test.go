@@ -0,0 +1,37 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCSVTable(t *testing.T) {
+	type nested struct {
+		Level float64 `json:"level_dbm"`
+	}
+	type row struct {
+		Name   string  `json:"name"`
+		ID     *uint   `json:"id,omitempty"`
+		Signal *nested `json:"signal,omitempty"`
+		hidden int
+	}
+	id := uint(7)
+
+	header, rows, err := csvTable([]row{{Name: "a", ID: &id, Signal: &nested{Level: -40.5}}, {Name: "b"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"name", "id", "signal_level_dbm"}; !reflect.DeepEqual(header, want) {
+		t.Errorf("header = %q, want %q", header, want)
+	}
+	if want := [][]string{{"a", "7", "-40.5"}, {"b", "", ""}}; !reflect.DeepEqual(rows, want) {
+		t.Errorf("rows = %q, want %q", rows, want)
+	}
+
+	if _, rows, err := csvTable(row{Name: "c"}); err != nil || len(rows) != 1 {
+		t.Errorf("single struct: rows = %q, err = %v", rows, err)
+	}
+	if _, _, err := csvTable(struct{ List []int }{}); err == nil {
+		t.Error("expected error for slice column")
+	}
+}
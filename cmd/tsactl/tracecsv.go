@@ -0,0 +1,129 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"os"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// traceSeries is the data of a single trace, read from a CSV file written by the save command.
+type traceSeries struct {
+	trace       uint
+	frequencies []uint64
+	values      []float64
+}
+
+// readTraceCSV reads the traces of a CSV file in the single trace layout `trace,point,frequency,value`
+// or the multi trace layout `point,frequency,value_t1,value_t2,...`. The standard deviation columns
+// of reduced sweeps and the reference and difference columns of the diff command are ignored.
+func readTraceCSV(path string) ([]traceSeries, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open file '%s': %w", path, err)
+	}
+	defer file.Close()
+
+	records, err := csv.NewReader(file).ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file '%s': %w", path, err)
+	}
+	if len(records) < 2 {
+		return nil, fmt.Errorf("file '%s' contains no trace data", path)
+	}
+
+	var series []traceSeries
+	header := records[0]
+	switch {
+	case len(header) >= 4 && slices.Equal(header[:4], []string{"trace", "point", "frequency", "value"}):
+		series, err = parseSingleTraceCSV(records[1:])
+	case len(header) > 2 && header[0] == "point" && header[1] == "frequency":
+		series, err = parseMultiTraceCSV(header[2:], records[1:])
+	default:
+		return nil, fmt.Errorf("file '%s' has an unknown layout, expected a trace CSV of the save command", path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse file '%s': %w", path, err)
+	}
+
+	return series, nil
+}
+
+func parseSingleTraceCSV(rows [][]string) ([]traceSeries, error) {
+	var s traceSeries
+	for i, row := range rows {
+		trace, err := strconv.ParseUint(row[0], 10, 0)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: invalid trace '%s'", i+2, row[0])
+		}
+		freq, value, err := parseTraceCSVPoint(row[2], row[3])
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", i+2, err)
+		}
+		s.trace = uint(trace)
+		s.frequencies = append(s.frequencies, freq)
+		s.values = append(s.values, value)
+	}
+	return []traceSeries{s}, nil
+}
+
+func parseMultiTraceCSV(columns []string, rows [][]string) ([]traceSeries, error) {
+	var series []traceSeries
+	var indices []int
+	for i, column := range columns {
+		if strings.HasPrefix(column, "stddev_t") {
+			continue
+		}
+		trace, err := strconv.ParseUint(strings.TrimPrefix(column, "value_t"), 10, 0)
+		if err != nil || !strings.HasPrefix(column, "value_t") {
+			return nil, fmt.Errorf("invalid column '%s'", column)
+		}
+		series = append(series, traceSeries{trace: uint(trace)})
+		indices = append(indices, i+2)
+	}
+
+	for i, row := range rows {
+		for j, index := range indices {
+			freq, value, err := parseTraceCSVPoint(row[1], row[index])
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", i+2, err)
+			}
+			series[j].frequencies = append(series[j].frequencies, freq)
+			series[j].values = append(series[j].values, value)
+		}
+	}
+	return series, nil
+}
+
+func parseTraceCSVPoint(freqStr, valueStr string) (uint64, float64, error) {
+	freq, err := strconv.ParseUint(freqStr, 10, 64)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid frequency '%s'", freqStr)
+	}
+	value, err := strconv.ParseFloat(valueStr, 64)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid value '%s'", valueStr)
+	}
+	return freq, value, nil
+}
+
+// valueAt returns the value at freq, linearly interpolated between the points, and false if freq
+// is outside of the series.
+func (s traceSeries) valueAt(freq uint64) (float64, bool) {
+	n := len(s.frequencies)
+	i := sort.Search(n, func(i int) bool { return s.frequencies[i] >= freq })
+	switch {
+	case i == n:
+		return 0, false
+	case s.frequencies[i] == freq:
+		return s.values[i], true
+	case i == 0:
+		return 0, false
+	}
+
+	f0, f1 := s.frequencies[i-1], s.frequencies[i]
+	return s.values[i-1] + (s.values[i]-s.values[i-1])*float64(freq-f0)/float64(f1-f0), true
+}
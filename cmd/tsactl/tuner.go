@@ -0,0 +1,105 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/kkettinger/go-tinysa"
+	"github.com/kkettinger/tsactl/internal/util"
+)
+
+// sweepTuner retunes the sweep of a device and keeps track of the settings, so the original
+// settings can be restored afterwards.
+type sweepTuner struct {
+	d        Device
+	original tinysa.Sweep
+	current  tinysa.Sweep
+}
+
+func newSweepTuner(d Device) (*sweepTuner, error) {
+	sweep, err := d.GetSweep()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get sweep settings: %w", err)
+	}
+	return &sweepTuner{d: d, original: sweep, current: sweep}, nil
+}
+
+// tune changes the sweep to target. The start and stop frequency are set in the order that keeps
+// start below stop, since the device moves the other one otherwise. The points are only set when they change.
+func (t *sweepTuner) tune(target tinysa.Sweep) error {
+	if target.Start > t.current.Stop {
+		if err := t.d.SetSweepStop(target.Stop); err != nil {
+			return err
+		}
+		if err := t.d.SetSweepStart(target.Start); err != nil {
+			return err
+		}
+	} else {
+		if err := t.d.SetSweepStart(target.Start); err != nil {
+			return err
+		}
+		if err := t.d.SetSweepStop(target.Stop); err != nil {
+			return err
+		}
+	}
+	if target.Points != t.current.Points {
+		if err := t.d.SetSweepPoints(target.Points); err != nil {
+			return err
+		}
+	}
+	t.current = target
+	return nil
+}
+
+// continuousWave changes the sweep to zero span at freq.
+func (t *sweepTuner) continuousWave(freq uint64) error {
+	if err := t.d.SetSweepContinuousWave(freq); err != nil {
+		return err
+	}
+	t.current = tinysa.Sweep{Start: freq, Stop: freq, Points: t.current.Points}
+	return nil
+}
+
+// restore changes the sweep back to the original settings, if they were changed.
+func (t *sweepTuner) restore() error {
+	if t.current == t.original {
+		return nil
+	}
+	if err := t.tune(t.original); err != nil {
+		return fmt.Errorf("failed to restore sweep settings: %w", err)
+	}
+	return nil
+}
+
+// peakAround tunes the sweep to span around freq and returns the highest point of the trace with its level
+// in dBm. The trace is read after dwell, which should be at least one sweep time.
+func (t *sweepTuner) peakAround(ctx context.Context, traceID uint, unit tinysa.TraceUnit, freq, span uint64, dwell time.Duration) (tinysa.TraceData, float64, error) {
+	target := tinysa.Sweep{Start: freq - span/2, Stop: freq + span/2, Points: t.original.Points}
+	if err := t.tune(target); err != nil {
+		return tinysa.TraceData{}, 0, fmt.Errorf("failed to set sweep around %s: %w", util.FormatFrequency(freq), err)
+	}
+
+	// wait for a complete sweep with the new settings
+	select {
+	case <-ctx.Done():
+		return tinysa.TraceData{}, 0, fmt.Errorf("measurement interrupted")
+	case <-time.After(dwell):
+	}
+
+	data, err := t.d.GetTraceData(traceID)
+	if err != nil {
+		return tinysa.TraceData{}, 0, fmt.Errorf("failed to get trace data around %s: %w", util.FormatFrequency(freq), err)
+	}
+	if len(data) == 0 {
+		return tinysa.TraceData{}, 0, fmt.Errorf("trace contains no points")
+	}
+	peak := slices.MaxFunc(data, func(a, b tinysa.TraceData) int { return cmp.Compare(a.Value, b.Value) })
+	level, err := toDBm(peak.Value, unit)
+	if err != nil {
+		return tinysa.TraceData{}, 0, err
+	}
+	return peak, level, nil
+}
@@ -5,6 +5,7 @@ import (
 	"github.com/alecthomas/kong"
 	"github.com/kkettinger/go-tinysa"
 	"github.com/kkettinger/tsactl/internal/util"
+	"slices"
 	"strconv"
 	"strings"
 )
@@ -228,3 +229,220 @@ func (o *Spur) Decode(ctx *kong.DecodeContext) error {
 
 	return nil
 }
+
+// FrequencyBand is a frequency range given as START:STOP, optionally named by NAME=START:STOP.
+type FrequencyBand struct {
+	Name  string
+	Start uint64
+	Stop  uint64
+}
+
+func (b *FrequencyBand) Decode(ctx *kong.DecodeContext) error {
+	var val string
+	if err := ctx.Scan.PopValueInto(ctx.Value.Name, &val); err != nil {
+		return err
+	}
+
+	band, err := parseFrequencyBand(val)
+	if err != nil {
+		return err
+	}
+	*b = band
+
+	return nil
+}
+
+// String returns the name of the band, or START:STOP if it has none.
+func (b FrequencyBand) String() string {
+	if b.Name != "" {
+		return b.Name
+	}
+	return fmt.Sprintf("%s:%s", util.FormatFrequency(b.Start), util.FormatFrequency(b.Stop))
+}
+
+func parseFrequencyBand(val string) (FrequencyBand, error) {
+	var band FrequencyBand
+
+	if name, rng, ok := strings.Cut(val, "="); ok {
+		band.Name, val = name, rng
+	}
+
+	startStr, stopStr, ok := strings.Cut(val, ":")
+	if !ok {
+		return band, fmt.Errorf("invalid band '%s', must be START:STOP", val)
+	}
+
+	var err error
+	if band.Start, err = util.ParseFrequency(startStr); err != nil {
+		return band, fmt.Errorf("failed to parse band start: %w", err)
+	}
+	if band.Stop, err = util.ParseFrequency(stopStr); err != nil {
+		return band, fmt.Errorf("failed to parse band stop: %w", err)
+	}
+	if band.Start >= band.Stop {
+		return band, fmt.Errorf("band start must be lower than stop")
+	}
+
+	return band, nil
+}
+
+// Channel is a frequency band given by its center and bandwidth as CENTER:BW.
+type Channel struct {
+	Center    uint64
+	Bandwidth uint64
+	Valid     bool
+}
+
+func (c *Channel) Decode(ctx *kong.DecodeContext) error {
+	var val string
+	if err := ctx.Scan.PopValueInto(ctx.Value.Name, &val); err != nil {
+		return err
+	}
+
+	channel, err := parseChannel(val)
+	if err != nil {
+		return err
+	}
+	*c = channel
+
+	return nil
+}
+
+// Start returns the lower edge of the channel.
+func (c Channel) Start() uint64 {
+	return c.Center - c.Bandwidth/2
+}
+
+// Stop returns the upper edge of the channel.
+func (c Channel) Stop() uint64 {
+	return c.Center + c.Bandwidth/2
+}
+
+func parseChannel(val string) (Channel, error) {
+	centerStr, bwStr, ok := strings.Cut(val, ":")
+	if !ok {
+		return Channel{}, fmt.Errorf("invalid channel '%s', must be CENTER:BW", val)
+	}
+
+	center, err := util.ParseFrequency(centerStr)
+	if err != nil {
+		return Channel{}, fmt.Errorf("failed to parse channel center: %w", err)
+	}
+	bw, err := util.ParseFrequency(bwStr)
+	if err != nil {
+		return Channel{}, fmt.Errorf("failed to parse channel bandwidth: %w", err)
+	}
+	if bw == 0 {
+		return Channel{}, fmt.Errorf("channel bandwidth must be greater than zero")
+	}
+	if bw/2 > center {
+		return Channel{}, fmt.Errorf("channel must not extend below 0 Hz")
+	}
+
+	return Channel{Center: center, Bandwidth: bw, Valid: true}, nil
+}
+
+// Percentage is a value between 0 and 100 exclusive, given with or without percent sign, e.g. 99%.
+type Percentage struct {
+	Value float64
+	Valid bool
+}
+
+func (p *Percentage) Decode(ctx *kong.DecodeContext) error {
+	var val string
+	if err := ctx.Scan.PopValueInto(ctx.Value.Name, &val); err != nil {
+		return err
+	}
+
+	v, err := strconv.ParseFloat(strings.TrimSuffix(val, "%"), 64)
+	if err != nil {
+		return fmt.Errorf("invalid percentage '%s'", val)
+	}
+	if v <= 0 || v >= 100 {
+		return fmt.Errorf("percentage must be between 0%% and 100%%")
+	}
+	p.Value = v
+	p.Valid = true
+
+	return nil
+}
+
+// Carrier is the frequency of a carrier, or peak for the highest point of a trace.
+type Carrier struct {
+	Frequency uint64
+	Peak      bool
+	Valid     bool
+}
+
+func (c *Carrier) Decode(ctx *kong.DecodeContext) error {
+	var val string
+	if err := ctx.Scan.PopValueInto(ctx.Value.Name, &val); err != nil {
+		return err
+	}
+
+	if strings.EqualFold(val, "peak") {
+		*c = Carrier{Peak: true, Valid: true}
+		return nil
+	}
+	freq, err := util.ParseFrequency(val)
+	if err != nil {
+		return fmt.Errorf("invalid carrier '%s', must be a frequency or peak", val)
+	}
+	*c = Carrier{Frequency: freq, Valid: true}
+
+	return nil
+}
+
+// HostUnit is a unit the trace values are converted to on the host.
+type HostUnit struct {
+	Valid bool
+	Unit  string
+}
+
+func (o *HostUnit) ValidOpts() []string {
+	return hostUnits
+}
+
+func (o *HostUnit) Decode(ctx *kong.DecodeContext) error {
+	var val string
+	if err := ctx.Scan.PopValueInto(ctx.Value.Name, &val); err != nil {
+		return err
+	}
+
+	val = strings.ToLower(val)
+	if slices.Contains(o.ValidOpts(), val) {
+		o.Valid, o.Unit = true, val
+	} else {
+		validOpts := strings.Join(o.ValidOpts(), ", ")
+		return fmt.Errorf("invalid option '%s', must be one of: %s", val, validOpts)
+	}
+
+	return nil
+}
+
+// OutputFormat is the output format of a status query.
+type OutputFormat struct {
+	Valid  bool
+	Format string
+}
+
+func (o *OutputFormat) ValidOpts() []string {
+	return formats
+}
+
+func (o *OutputFormat) Decode(ctx *kong.DecodeContext) error {
+	var val string
+	if err := ctx.Scan.PopValueInto(ctx.Value.Name, &val); err != nil {
+		return err
+	}
+
+	val = strings.ToLower(val)
+	if slices.Contains(o.ValidOpts(), val) {
+		o.Valid, o.Format = true, val
+	} else {
+		validOpts := strings.Join(o.ValidOpts(), ", ")
+		return fmt.Errorf("invalid option '%s', must be one of: %s", val, validOpts)
+	}
+
+	return nil
+}
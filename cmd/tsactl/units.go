@@ -0,0 +1,147 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/kkettinger/go-tinysa"
+)
+
+// deviceImpedance is the input impedance of the device in ohm, the voltage units of the device refer to it.
+const deviceImpedance = 50.0
+
+// toDBm converts a trace value in the unit of the device to dBm. The voltage units V and Vpp
+// are converted by their RMS value.
+func toDBm(v float64, unit tinysa.TraceUnit) (float64, error) {
+	switch unit {
+	case tinysa.TraceUnitDBm:
+		return v, nil
+	case tinysa.TraceUnitDBmV:
+		return v - 60 + 10*math.Log10(1000/deviceImpedance), nil
+	case tinysa.TraceUnitDBuV:
+		return v - 120 + 10*math.Log10(1000/deviceImpedance), nil
+	case tinysa.TraceUnitV:
+		return 10 * math.Log10(v*v/deviceImpedance*1000), nil
+	case tinysa.TraceUnitVpp:
+		vrms := v / (2 * math.Sqrt2)
+		return 10 * math.Log10(vrms*vrms/deviceImpedance*1000), nil
+	case tinysa.TraceUnitW:
+		return 10 * math.Log10(v*1000), nil
+	default:
+		return 0, fmt.Errorf("unsupported trace unit '%s'", unit)
+	}
+}
+
+// fromDBm converts a level in dBm to the unit of the device, the inverse of toDBm.
+func fromDBm(dBm float64, unit tinysa.TraceUnit) (float64, error) {
+	hostUnit, ok := deviceHostUnits[unit]
+	if !ok {
+		return 0, fmt.Errorf("unsupported trace unit '%s'", unit)
+	}
+	return convertLevel(dBm, hostUnit, deviceImpedance)
+}
+
+// isLinearUnit reports whether the trace unit is a linear power or voltage, in which level differences aren't in dB.
+func isLinearUnit(unit tinysa.TraceUnit) bool {
+	return unit == tinysa.TraceUnitW || unit == tinysa.TraceUnitV || unit == tinysa.TraceUnitVpp
+}
+
+// dBmToMilliwatt converts a level in dBm to linear power in mW.
+func dBmToMilliwatt(dBm float64) float64 {
+	return math.Pow(10, dBm/10)
+}
+
+// milliwattToDBm converts linear power in mW to a level in dBm.
+func milliwattToDBm(mW float64) float64 {
+	return 10 * math.Log10(mW)
+}
+
+// deltaUnit returns the unit of the difference of two levels, which is dB for logarithmic units.
+func deltaUnit(unit string) string {
+	if strings.HasPrefix(unit, "dB") {
+		return "dB"
+	}
+	return unit
+}
+
+// units trace values can be converted to on the host, independent of the unit of the device
+const (
+	hostUnitDBm  = "dbm"
+	hostUnitDBmV = "dbmv"
+	hostUnitDBuV = "dbuv"
+	hostUnitMW   = "mw"
+	hostUnitW    = "w"
+	hostUnitVpp  = "vpp"
+	hostUnitVrms = "vrms"
+)
+
+var hostUnits = []string{hostUnitDBm, hostUnitDBmV, hostUnitDBuV, hostUnitMW, hostUnitW, hostUnitVpp, hostUnitVrms}
+
+// deviceHostUnits are the host units matching the trace units of the device, V is the RMS voltage.
+var deviceHostUnits = map[tinysa.TraceUnit]string{
+	tinysa.TraceUnitDBm:  hostUnitDBm,
+	tinysa.TraceUnitDBmV: hostUnitDBmV,
+	tinysa.TraceUnitDBuV: hostUnitDBuV,
+	tinysa.TraceUnitV:    hostUnitVrms,
+	tinysa.TraceUnitVpp:  hostUnitVpp,
+	tinysa.TraceUnitW:    hostUnitW,
+}
+
+// hostUnitLabels are the display names of the host units.
+var hostUnitLabels = map[string]string{
+	hostUnitDBm:  "dBm",
+	hostUnitDBmV: "dBmV",
+	hostUnitDBuV: "dBuV",
+	hostUnitMW:   "mW",
+	hostUnitW:    "W",
+	hostUnitVpp:  "Vpp",
+	hostUnitVrms: "Vrms",
+}
+
+// isVoltageUnit reports whether the host unit is a voltage, which depends on the impedance.
+func isVoltageUnit(unit string) bool {
+	return unit == hostUnitDBmV || unit == hostUnitDBuV || unit == hostUnitVpp || unit == hostUnitVrms
+}
+
+// convertLevel converts a level in dBm to a host unit. Voltages are the voltage across the impedance in ohm.
+func convertLevel(dBm float64, unit string, impedance float64) (float64, error) {
+	switch unit {
+	case hostUnitDBm:
+		return dBm, nil
+	case hostUnitDBmV:
+		return dBm + 60 - 10*math.Log10(1000/impedance), nil
+	case hostUnitDBuV:
+		return dBm + 120 - 10*math.Log10(1000/impedance), nil
+	case hostUnitMW:
+		return dBmToMilliwatt(dBm), nil
+	case hostUnitW:
+		return dBmToMilliwatt(dBm) / 1000, nil
+	case hostUnitVpp:
+		return 2 * math.Sqrt2 * math.Sqrt(dBmToMilliwatt(dBm)/1000*impedance), nil
+	case hostUnitVrms:
+		return math.Sqrt(dBmToMilliwatt(dBm) / 1000 * impedance), nil
+	default:
+		return 0, fmt.Errorf("unsupported unit '%s'", unit)
+	}
+}
+
+// convertTraceData converts the values of the trace data in place from the unit of the device to a host unit.
+func convertTraceData(data []tinysa.TraceData, from tinysa.TraceUnit, to string, impedance float64) error {
+	for i, dp := range data {
+		dBm, err := toDBm(dp.Value, from)
+		if err != nil {
+			return err
+		}
+		v, err := convertLevel(dBm, to, impedance)
+		if err != nil {
+			return err
+		}
+		if strings.HasPrefix(to, "db") {
+			// remove the float noise of the conversion, the device reports two decimals
+			v = math.Round(v*1e6) / 1e6
+		}
+		data[i].Value = v
+	}
+	return nil
+}
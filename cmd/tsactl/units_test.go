@@ -0,0 +1,74 @@
+package main
+
+import (
+	"math"
+	"testing"
+
+	"github.com/kkettinger/go-tinysa"
+)
+
+func TestToFromDBm(t *testing.T) {
+	tests := []struct {
+		value float64
+		unit  tinysa.TraceUnit
+		want  float64
+	}{
+		{-30, tinysa.TraceUnitDBm, -30},
+		{46.9897, tinysa.TraceUnitDBmV, 0},
+		{106.9897, tinysa.TraceUnitDBuV, 0},
+		{0.2236068, tinysa.TraceUnitV, 0},
+		{0.6324555, tinysa.TraceUnitVpp, 0},
+		{0.001, tinysa.TraceUnitW, 0},
+	}
+
+	for _, tt := range tests {
+		got, err := toDBm(tt.value, tt.unit)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if math.Abs(got-tt.want) > 1e-4 {
+			t.Errorf("toDBm(%v, %s) = %v, want %v", tt.value, tt.unit, got, tt.want)
+		}
+
+		back, err := fromDBm(got, tt.unit)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if math.Abs(back-tt.value) > 1e-9 {
+			t.Errorf("fromDBm(%v, %s) = %v, want %v", got, tt.unit, back, tt.value)
+		}
+	}
+}
+
+func TestConvertLevel(t *testing.T) {
+	tests := []struct {
+		unit      string
+		impedance float64
+		want      float64
+	}{
+		{hostUnitDBm, 50, 0},
+		{hostUnitDBmV, 50, 46.9897},
+		{hostUnitDBmV, 75, 48.7506},
+		{hostUnitDBuV, 50, 106.9897},
+		{hostUnitDBuV, 75, 108.7506},
+		{hostUnitMW, 75, 1},
+		{hostUnitW, 50, 0.001},
+		{hostUnitVpp, 50, 0.6324555},
+		{hostUnitVrms, 50, 0.2236068},
+		{hostUnitVrms, 75, 0.2738613},
+	}
+
+	for _, tt := range tests {
+		got, err := convertLevel(0, tt.unit, tt.impedance)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if math.Abs(got-tt.want) > 1e-4 {
+			t.Errorf("convertLevel(0, %s, %g) = %v, want %v", tt.unit, tt.impedance, got, tt.want)
+		}
+	}
+
+	if _, err := convertLevel(0, "dbw", 50); err == nil {
+		t.Error("expected error for unsupported unit")
+	}
+}
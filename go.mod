@@ -6,10 +6,11 @@ require (
 	github.com/alecthomas/kong v1.12.1
 	github.com/govalues/decimal v0.1.36
 	github.com/kkettinger/go-tinysa v0.4.3
+	go.bug.st/serial v1.6.4
+	golang.org/x/image v0.40.0
+	golang.org/x/sys v0.36.0
+	golang.org/x/term v0.35.0
+	gopkg.in/yaml.v3 v3.0.1
 )
 
-require (
-	github.com/creack/goselect v0.1.3 // indirect
-	go.bug.st/serial v1.6.4 // indirect
-	golang.org/x/sys v0.36.0 // indirect
-)
+require github.com/creack/goselect v0.1.3 // indirect
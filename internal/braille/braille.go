@@ -0,0 +1,98 @@
+// Package braille draws pixel graphics into terminal cells using Unicode braille patterns,
+// which provide 2x4 dots per cell.
+package braille
+
+// dots are the bits of the braille dots by their position within a cell
+var dots = [4][2]rune{
+	{0x01, 0x08},
+	{0x02, 0x10},
+	{0x04, 0x20},
+	{0x40, 0x80},
+}
+
+// Canvas is a grid of braille cells. Every cell has the color of the last dot set in it.
+type Canvas struct {
+	cols, rows int
+	bits       []rune
+	colors     []int
+}
+
+// New returns an empty canvas of cols x rows cells.
+func New(cols, rows int) *Canvas {
+	cols, rows = max(cols, 0), max(rows, 0)
+	return &Canvas{
+		cols:   cols,
+		rows:   rows,
+		bits:   make([]rune, cols*rows),
+		colors: make([]int, cols*rows),
+	}
+}
+
+// Width returns the width in dots.
+func (c *Canvas) Width() int {
+	return c.cols * 2
+}
+
+// Height returns the height in dots.
+func (c *Canvas) Height() int {
+	return c.rows * 4
+}
+
+// Set sets the dot at x, y, where 0, 0 is the top left. Dots outside the canvas are ignored.
+func (c *Canvas) Set(x, y, color int) {
+	if x < 0 || y < 0 || x >= c.Width() || y >= c.Height() {
+		return
+	}
+	i := y/4*c.cols + x/2
+	c.bits[i] |= dots[y%4][x%2]
+	c.colors[i] = color
+}
+
+// Line sets the dots of the line from x0, y0 to x1, y1.
+func (c *Canvas) Line(x0, y0, x1, y1, color int) {
+	dx, dy := abs(x1-x0), -abs(y1-y0)
+	sx, sy := sign(x1-x0), sign(y1-y0)
+	e := dx + dy
+
+	for {
+		c.Set(x0, y0, color)
+		if x0 == x1 && y0 == y1 {
+			return
+		}
+		e2 := 2 * e
+		if e2 >= dy {
+			e += dy
+			x0 += sx
+		}
+		if e2 <= dx {
+			e += dx
+			y0 += sy
+		}
+	}
+}
+
+// Cell returns the character and color of the cell at col, row. Empty cells are spaces.
+func (c *Canvas) Cell(col, row int) (rune, int) {
+	i := row*c.cols + col
+	if c.bits[i] == 0 {
+		return ' ', 0
+	}
+	return 0x2800 + c.bits[i], c.colors[i]
+}
+
+func abs(v int) int {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
+
+func sign(v int) int {
+	switch {
+	case v < 0:
+		return -1
+	case v > 0:
+		return 1
+	}
+	return 0
+}
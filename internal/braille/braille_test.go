@@ -0,0 +1,53 @@
+package braille
+
+import "testing"
+
+func render(c *Canvas) string {
+	var s []rune
+	for row := range c.rows {
+		for col := range c.cols {
+			r, _ := c.Cell(col, row)
+			s = append(s, r)
+		}
+		s = append(s, '\n')
+	}
+	return string(s)
+}
+
+func TestCanvasSet(t *testing.T) {
+	c := New(2, 1)
+	c.Set(0, 0, 1)
+	c.Set(1, 3, 2)
+	c.Set(4, 0, 3)  // outside
+	c.Set(-1, 0, 3) // outside
+
+	if got := render(c); got != "⢁ \n" {
+		t.Errorf("canvas = %q", got)
+	}
+	if _, color := c.Cell(0, 0); color != 2 {
+		t.Errorf("color = %d, want 2", color)
+	}
+}
+
+func TestCanvasLine(t *testing.T) {
+	tests := []struct {
+		name           string
+		x0, y0, x1, y1 int
+		want           string
+	}{
+		{"horizontal", 0, 0, 3, 0, "⠉⠉\n  \n"},
+		{"vertical", 0, 0, 0, 7, "⡇ \n⡇ \n"},
+		{"diagonal", 0, 0, 3, 7, "⢣ \n ⢣\n"},
+		{"reverse", 3, 7, 0, 0, "⢣ \n ⢣\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := New(2, 2)
+			c.Line(tt.x0, tt.y0, tt.x1, tt.y1, 0)
+			if got := render(c); got != tt.want {
+				t.Errorf("canvas:\n%s\nwant:\n%s", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,62 @@
+// Package colormap maps values between 0 and 1 to colors for heatmaps like spectrograms.
+package colormap
+
+import (
+	"image/color"
+	"math"
+	"slices"
+)
+
+// Colormap is a color gradient, linearly interpolated between evenly spaced colors.
+type Colormap []color.RGBA
+
+var colormaps = map[string]Colormap{
+	"viridis": {
+		{68, 1, 84, 255}, {72, 40, 120, 255}, {62, 73, 137, 255}, {49, 104, 142, 255}, {38, 130, 142, 255},
+		{31, 158, 137, 255}, {53, 183, 121, 255}, {109, 205, 89, 255}, {180, 222, 44, 255}, {253, 231, 37, 255},
+	},
+	"inferno": {
+		{0, 0, 4, 255}, {27, 12, 65, 255}, {74, 12, 107, 255}, {120, 28, 109, 255}, {165, 44, 96, 255},
+		{207, 68, 70, 255}, {237, 105, 37, 255}, {251, 155, 6, 255}, {247, 209, 61, 255}, {252, 255, 164, 255},
+	},
+	"turbo": {
+		{48, 18, 59, 255}, {70, 107, 227, 255}, {41, 187, 236, 255}, {49, 241, 153, 255}, {164, 252, 60, 255},
+		{237, 208, 58, 255}, {251, 128, 34, 255}, {210, 49, 5, 255}, {122, 4, 3, 255},
+	},
+	"gray": {
+		{0, 0, 0, 255}, {255, 255, 255, 255},
+	},
+}
+
+// Get returns the colormap of the given name.
+func Get(name string) (Colormap, bool) {
+	c, ok := colormaps[name]
+	return c, ok
+}
+
+// Names returns the names of all colormaps.
+func Names() []string {
+	names := make([]string, 0, len(colormaps))
+	for name := range colormaps {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// At returns the color at t, which is clamped to 0..1.
+func (c Colormap) At(t float64) color.RGBA {
+	if math.IsNaN(t) {
+		t = 0
+	}
+	t = min(max(t, 0), 1) * float64(len(c)-1)
+
+	i := min(int(t), len(c)-2)
+	f := t - float64(i)
+	a, b := c[i], c[i+1]
+
+	lerp := func(x, y uint8) uint8 {
+		return uint8(math.Round(float64(x) + f*(float64(y)-float64(x))))
+	}
+	return color.RGBA{R: lerp(a.R, b.R), G: lerp(a.G, b.G), B: lerp(a.B, b.B), A: 255}
+}
@@ -0,0 +1,42 @@
+package colormap
+
+import (
+	"image/color"
+	"testing"
+)
+
+func TestColormapAt(t *testing.T) {
+	gray, _ := Get("gray")
+
+	tests := []struct {
+		t    float64
+		want color.RGBA
+	}{
+		{0, color.RGBA{0, 0, 0, 255}},
+		{0.5, color.RGBA{128, 128, 128, 255}},
+		{1, color.RGBA{255, 255, 255, 255}},
+		{-1, color.RGBA{0, 0, 0, 255}},
+		{2, color.RGBA{255, 255, 255, 255}},
+	}
+
+	for _, tt := range tests {
+		if got := gray.At(tt.t); got != tt.want {
+			t.Errorf("At(%v) = %v, want %v", tt.t, got, tt.want)
+		}
+	}
+}
+
+func TestGet(t *testing.T) {
+	for _, name := range Names() {
+		c, ok := Get(name)
+		if !ok || len(c) < 2 {
+			t.Errorf("colormap %s is invalid", name)
+		}
+		if got := c.At(1); got != c[len(c)-1] {
+			t.Errorf("colormap %s: At(1) = %v, want last color %v", name, got, c[len(c)-1])
+		}
+	}
+	if _, ok := Get("rainbow"); ok {
+		t.Error("unknown colormap found")
+	}
+}
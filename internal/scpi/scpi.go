@@ -0,0 +1,201 @@
+// Package scpi parses SCPI program messages and matches command headers against patterns.
+package scpi
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Command is a single command of a program message, e.g. `FREQ:STAR 100 MHz`.
+type Command struct {
+	// Header is the absolute command header without leading colon and query mark, e.g. `FREQ:STAR`.
+	Header string
+	Query  bool
+	Args   []string
+}
+
+// Parse splits a program message into its commands, which are separated by semicolons.
+// Following headers without leading colon are relative to the path of the previous command,
+// e.g. `FREQ:STAR 1e6;STOP 2e6` sets both FREQ:STAR and FREQ:STOP.
+func Parse(line string) ([]Command, error) {
+	var (
+		commands []Command
+		path     string
+	)
+
+	parts, err := split(line, ';')
+	if err != nil {
+		return nil, err
+	}
+
+	for _, part := range parts {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		header, params, _ := strings.Cut(part, " ")
+		params = strings.TrimSpace(params)
+
+		cmd := Command{}
+		if strings.HasSuffix(header, "?") {
+			cmd.Query = true
+			header = strings.TrimSuffix(header, "?")
+		}
+
+		switch {
+		case strings.HasPrefix(header, "*"):
+			// common commands don't change the path
+			cmd.Header = header
+		case strings.HasPrefix(header, ":"):
+			cmd.Header = strings.TrimPrefix(header, ":")
+		default:
+			cmd.Header = path + header
+		}
+
+		if cmd.Header == "" {
+			return nil, fmt.Errorf("missing command header")
+		}
+
+		if !strings.HasPrefix(header, "*") {
+			if i := strings.LastIndex(cmd.Header, ":"); i >= 0 {
+				path = cmd.Header[:i+1]
+			} else {
+				path = ""
+			}
+		}
+
+		if params != "" {
+			args, err := split(params, ',')
+			if err != nil {
+				return nil, err
+			}
+			for _, arg := range args {
+				cmd.Args = append(cmd.Args, strings.TrimSpace(arg))
+			}
+		}
+
+		commands = append(commands, cmd)
+	}
+
+	return commands, nil
+}
+
+// split splits s at sep outside of quoted strings.
+func split(s string, sep rune) ([]string, error) {
+	var (
+		parts []string
+		start int
+		quote rune
+	)
+
+	for i, r := range s {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case r == '"' || r == '\'':
+			quote = r
+		case r == sep:
+			parts = append(parts, s[start:i])
+			start = i + 1
+		}
+	}
+
+	if quote != 0 {
+		return nil, fmt.Errorf("unterminated string")
+	}
+
+	return append(parts, s[start:]), nil
+}
+
+// node is a single node of a header pattern.
+type node struct {
+	short    string
+	long     string
+	optional bool
+	suffix   bool
+}
+
+// parsePattern parses a header pattern like `[SENSe:]FREQuency:STARt` or `CALCulate:MARKer#:Y`,
+// where lower case letters are omitted in the short form, brackets denote optional nodes
+// and # denotes an optional numeric suffix.
+func parsePattern(pattern string) []node {
+	pattern = strings.ReplaceAll(pattern, "[:", ":[")
+	pattern = strings.ReplaceAll(pattern, ":]", "]:")
+
+	var nodes []node
+	for _, token := range strings.Split(pattern, ":") {
+		n := node{}
+		if strings.HasPrefix(token, "[") && strings.HasSuffix(token, "]") {
+			n.optional = true
+			token = token[1 : len(token)-1]
+		}
+		if strings.HasSuffix(token, "#") {
+			n.suffix = true
+			token = strings.TrimSuffix(token, "#")
+		}
+		n.long = strings.ToUpper(token)
+		n.short = strings.TrimRightFunc(token, unicode.IsLower)
+		nodes = append(nodes, n)
+	}
+	return nodes
+}
+
+// Match reports whether header matches pattern, see parsePattern. It returns the numeric suffixes
+// of the nodes marked with #, which default to 1.
+func Match(pattern, header string) ([]int, bool) {
+	header = strings.TrimPrefix(header, ":")
+	if header == "" {
+		return nil, false
+	}
+	return match(parsePattern(pattern), strings.Split(strings.ToUpper(header), ":"))
+}
+
+func match(nodes []node, parts []string) ([]int, bool) {
+	if len(nodes) == 0 {
+		return nil, len(parts) == 0
+	}
+
+	n := nodes[0]
+
+	if len(parts) > 0 {
+		if suffix, ok := matchNode(n, parts[0]); ok {
+			if rest, ok := match(nodes[1:], parts[1:]); ok {
+				if n.suffix {
+					return append([]int{suffix}, rest...), true
+				}
+				return rest, true
+			}
+		}
+	}
+
+	if n.optional {
+		rest, ok := match(nodes[1:], parts)
+		if ok && n.suffix {
+			return append([]int{1}, rest...), true
+		}
+		return rest, ok
+	}
+
+	return nil, false
+}
+
+func matchNode(n node, part string) (int, bool) {
+	suffix := 1
+	if n.suffix {
+		word := strings.TrimRightFunc(part, unicode.IsDigit)
+		if word != part {
+			v, err := strconv.Atoi(part[len(word):])
+			if err != nil {
+				return 0, false
+			}
+			suffix = v
+		}
+		part = word
+	}
+	return suffix, part == n.short || part == n.long
+}
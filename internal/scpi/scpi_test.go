@@ -0,0 +1,89 @@
+package scpi
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		line    string
+		want    []Command
+		wantErr bool
+	}{
+		{"*IDN?", []Command{{Header: "*IDN", Query: true}}, false},
+		{":FREQ:STAR 100 MHz", []Command{{Header: "FREQ:STAR", Args: []string{"100 MHz"}}}, false},
+		{
+			"FREQ:STAR 1e6;STOP 2e6;:SWE:POIN?",
+			[]Command{
+				{Header: "FREQ:STAR", Args: []string{"1e6"}},
+				{Header: "FREQ:STOP", Args: []string{"2e6"}},
+				{Header: "SWE:POIN", Query: true},
+			},
+			false,
+		},
+		{
+			"CALC:MARK1:X 1e6;*OPC?;Y?",
+			[]Command{
+				{Header: "CALC:MARK1:X", Args: []string{"1e6"}},
+				{Header: "*OPC", Query: true},
+				{Header: "CALC:MARK1:Y", Query: true},
+			},
+			false,
+		},
+		{"TRAC:DATA? TRACE1, 'a;b'", []Command{{Header: "TRAC:DATA", Query: true, Args: []string{"TRACE1", "'a;b'"}}}, false},
+		{"  ", nil, false},
+		{"?", nil, true},
+		{"SYST:ERR? \"abc", nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.line, func(t *testing.T) {
+			got, err := Parse(tt.line)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Parse() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Parse() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMatch(t *testing.T) {
+	tests := []struct {
+		pattern      string
+		header       string
+		wantSuffixes []int
+		wantOk       bool
+	}{
+		{"*IDN", "*idn", nil, true},
+		{"[SENSe:]FREQuency:STARt", "FREQ:STAR", nil, true},
+		{"[SENSe:]FREQuency:STARt", "sense:frequency:start", nil, true},
+		{"[SENSe:]FREQuency:STARt", ":SENS:FREQ:STAR", nil, true},
+		{"[SENSe:]FREQuency:STARt", "FREQ:STA", nil, false},
+		{"[SENSe:]FREQuency:STARt", "FREQ:STARTX", nil, false},
+		{"[SENSe:]FREQuency:STARt", "FREQ", nil, false},
+		{"CALCulate:MARKer#:Y", "CALC:MARK:Y", []int{1}, true},
+		{"CALCulate:MARKer#:Y", "CALC:MARK3:Y", []int{3}, true},
+		{"CALCulate:MARKer#:Y", "CALC:MARKER12:Y", []int{12}, true},
+		{"CALCulate:MARKer#:Y", "CALC1:MARK:Y", nil, false},
+		{"TRACe#[:DATA]", "TRAC:DATA", []int{1}, true},
+		{"TRACe#[:DATA]", "TRACE2", []int{2}, true},
+		{"TRACe#[:DATA]:X", "TRAC:X", []int{1}, true},
+		{"TRACe#[:DATA]:X", "TRAC:DATA:X", []int{1}, true},
+		{"[TRACe#]:DATA", "DATA", []int{1}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.pattern+" "+tt.header, func(t *testing.T) {
+			suffixes, ok := Match(tt.pattern, tt.header)
+			if ok != tt.wantOk {
+				t.Fatalf("Match() ok = %v, want %v", ok, tt.wantOk)
+			}
+			if ok && !reflect.DeepEqual(suffixes, tt.wantSuffixes) {
+				t.Errorf("Match() suffixes = %v, want %v", suffixes, tt.wantSuffixes)
+			}
+		})
+	}
+}
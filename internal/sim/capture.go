@@ -0,0 +1,67 @@
+package sim
+
+// RGB565 colors used for rendering the simulated screen.
+const (
+	colorBackground uint16 = 0x0000
+	colorGrid       uint16 = 0x4208
+)
+
+var traceColors = [numTraces]uint16{0xFFE0, 0x07FF, 0xF81F, 0x07E0}
+
+const (
+	gridDivisionsX = 10
+	gridDivisionsY = 10
+)
+
+// capture renders the enabled traces into a RGB565 big-endian screen buffer like the `capture` command.
+func (s *Simulator) capture() []byte {
+	w, h := s.info.width, s.info.height
+	pixels := make([]uint16, w*h)
+
+	for i := range pixels {
+		pixels[i] = colorBackground
+	}
+
+	// grid
+	for i := range gridDivisionsX + 1 {
+		x := min(i*w/gridDivisionsX, w-1)
+		for y := range h {
+			pixels[y*w+x] = colorGrid
+		}
+	}
+	for i := range gridDivisionsY + 1 {
+		y := min(i*h/gridDivisionsY, h-1)
+		for x := range w {
+			pixels[y*w+x] = colorGrid
+		}
+	}
+
+	// traces, with the reference level at the top edge
+	for t := range s.traces {
+		if !s.traces[t].enabled {
+			continue
+		}
+		values := s.traceValues(uint(t + 1)) // #nosec G115
+		prevY := -1
+		for x := range w {
+			i := x * len(values) / w
+			div := (s.refLevel - values[i]) / s.scale
+			y := min(max(int(div*float64(h)/gridDivisionsY), 0), h-1)
+			from, to := y, y
+			if prevY >= 0 {
+				from, to = min(y, prevY), max(y, prevY)
+			}
+			for yy := from; yy <= to; yy++ {
+				pixels[yy*w+x] = traceColors[t]
+			}
+			prevY = y
+		}
+	}
+
+	buf := make([]byte, len(pixels)*2)
+	for i, p := range pixels {
+		buf[i*2] = byte(p >> 8)
+		buf[i*2+1] = byte(p)
+	}
+	return buf
+}
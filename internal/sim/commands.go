@@ -0,0 +1,496 @@
+package sim
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/kkettinger/go-tinysa"
+)
+
+// Handle executes a single command line and returns the response without echo and prompt.
+// The binary flag is set for responses that are not terminated by a line break, like `capture`.
+func (s *Simulator) Handle(line string) (response []byte, binary bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	fields := strings.Fields(line)
+	if len(fields) == 0 {
+		return nil, false
+	}
+
+	args := fields[1:]
+
+	var res string
+	var err error
+
+	switch fields[0] {
+	case "version":
+		res = fmt.Sprintf("%s\r\nHW Version:V%s", s.info.banner, s.info.hwVersion)
+	case "capture":
+		return s.capture(), true
+	case "vbat":
+		res = fmt.Sprintf("%d mV", s.vbat)
+	case "vbat_offset":
+		res, err = s.cmdVbatOffset(args)
+	case "deviceid":
+		res, err = s.cmdDeviceID(args)
+	case "reset":
+		s.settings = s.defaultSettings()
+		s.paused = false
+	case "sweep":
+		res, err = s.cmdSweep(args)
+	case "sweeptime":
+		err = s.cmdSweepTime(args)
+	case "status":
+		res = "Resumed"
+		if s.paused {
+			res = "Paused"
+		}
+	case "pause":
+		s.paused = true
+	case "resume":
+		s.paused = false
+	case "frequencies":
+		res = s.cmdFrequencies()
+	case "trace":
+		res, err = s.cmdTrace(args)
+	case "calc":
+		err = s.cmdCalc(args)
+	case "marker":
+		res, err = s.cmdMarker(args)
+	case "spur":
+		err = s.cmdSpur(args)
+	case "lna":
+		err = s.cmdLNA(args)
+	case "menu":
+		// menu actions only affect the screen of the real device
+	case "load":
+		err = s.cmdLoad(args)
+	case "save":
+		err = s.cmdSave(args)
+	default:
+		res = fields[0] + "?"
+	}
+
+	if err != nil {
+		res = err.Error()
+	}
+
+	return []byte(res), false
+}
+
+func (s *Simulator) cmdVbatOffset(args []string) (string, error) {
+	if len(args) == 0 {
+		return strconv.FormatUint(uint64(s.vbatOffset), 10), nil
+	}
+	v, err := parseUint(args[0])
+	if err != nil {
+		return "", err
+	}
+	s.vbatOffset = v
+	return "", nil
+}
+
+func (s *Simulator) cmdDeviceID(args []string) (string, error) {
+	if len(args) == 0 {
+		return fmt.Sprintf("deviceid %d", s.deviceID), nil
+	}
+	v, err := parseUint(args[0])
+	if err != nil {
+		return "", err
+	}
+	s.deviceID = v
+	return "", nil
+}
+
+func (s *Simulator) cmdSweep(args []string) (string, error) {
+	if len(args) == 0 {
+		return fmt.Sprintf("%d %d %d", s.start, s.stop, s.points), nil
+	}
+
+	switch args[0] {
+	case "normal", "precise", "fast", "noise":
+		s.mode = args[0]
+		return "", nil
+	case "start", "stop", "center", "span", "cw":
+		if len(args) != 2 {
+			return "", usageError("sweep {start(Hz)} [stop(Hz)] [points]")
+		}
+		freq, err := strconv.ParseUint(args[1], 10, 64)
+		if err != nil {
+			return "", usageError("sweep {start(Hz)} [stop(Hz)] [points]")
+		}
+		center := s.start + (s.stop-s.start)/2
+		span := s.stop - s.start
+		switch args[0] {
+		case "start":
+			s.setSweep(freq, max(s.stop, freq), s.points)
+		case "stop":
+			s.setSweep(min(s.start, freq), freq, s.points)
+		case "center":
+			s.setSweep(freq-min(freq, span/2), freq+span/2, s.points)
+		case "span":
+			s.setSweep(center-min(center, freq/2), center+freq/2, s.points)
+		case "cw":
+			s.setSweep(freq, freq, s.points)
+		}
+		return "", nil
+	}
+
+	if len(args) > 3 {
+		return "", usageError("sweep {start(Hz)} [stop(Hz)] [points]")
+	}
+	values := make([]uint64, len(args))
+	for i, a := range args {
+		v, err := strconv.ParseUint(a, 10, 64)
+		if err != nil {
+			return "", usageError("sweep {start(Hz)} [stop(Hz)] [points]")
+		}
+		values[i] = v
+	}
+	start, stop, points := values[0], s.stop, s.points
+	if len(values) > 1 {
+		stop = values[1]
+	}
+	if len(values) > 2 {
+		points = uint(values[2])
+	}
+	s.setSweep(start, stop, points)
+
+	return "", nil
+}
+
+func (s *Simulator) cmdSweepTime(args []string) error {
+	if len(args) != 1 {
+		return usageError("sweeptime 0.003..60")
+	}
+	v, err := parseUint(strings.TrimSuffix(args[0], "u"))
+	if err != nil {
+		return usageError("sweeptime 0.003..60")
+	}
+	s.sweepTime = uint64(v)
+	return nil
+}
+
+func (s *Simulator) cmdFrequencies() string {
+	freqs := s.frequencies()
+	lines := make([]string, len(freqs))
+	for i, f := range freqs {
+		lines[i] = strconv.FormatUint(f, 10)
+	}
+	return strings.Join(lines, "\r\n")
+}
+
+func (s *Simulator) cmdTrace(args []string) (string, error) {
+	const usage = "trace {dBm|dBmV|dBuV|RAW|V|Vpp|W}\r\ntrace {scale|reflevel} auto|{value}\r\ntrace [{trace#}] value|view on|off"
+
+	if len(args) == 0 {
+		var lines []string
+		for i, t := range s.traces {
+			if t.enabled {
+				lines = append(lines, s.traceLine(uint(i+1))) // #nosec G115
+			}
+		}
+		return strings.Join(lines, "\r\n"), nil
+	}
+
+	if unit, ok := tinysa.TraceUnitFromString(args[0]); ok {
+		s.unit = unit
+		return "", nil
+	}
+
+	switch args[0] {
+	case "reflevel":
+		if len(args) != 2 {
+			return "", usageError(usage)
+		}
+		if args[1] == "auto" {
+			s.refLevel = math.Ceil((s.peakLevel()+10)/10) * 10
+			return "", nil
+		}
+		v, err := strconv.ParseFloat(args[1], 64)
+		if err != nil {
+			return "", usageError(usage)
+		}
+		s.refLevel = v
+		return "", nil
+	case "scale":
+		if len(args) != 2 {
+			return "", usageError(usage)
+		}
+		v, err := strconv.ParseFloat(args[1], 64)
+		if err != nil || v <= 0 {
+			return "", usageError(usage)
+		}
+		s.scale = v
+		return "", nil
+	}
+
+	id, err := s.parseTraceID(args[0])
+	if err != nil {
+		return "", err
+	}
+
+	if len(args) == 1 {
+		return s.traceLine(id), nil
+	}
+
+	switch {
+	case args[1] == "value":
+		s.sweep()
+		values := s.traceValues(id)
+		lines := make([]string, len(values))
+		for i, v := range values {
+			lines[i] = fmt.Sprintf("trace %d value %d %s", id, i, formatValue(s.unit, v))
+		}
+		return strings.Join(lines, "\r\n"), nil
+	case args[1] == "view" && len(args) == 3 && (args[2] == "on" || args[2] == "off"):
+		s.traces[id-1].enabled = args[2] == "on"
+		return "", nil
+	}
+
+	return "", usageError(usage)
+}
+
+func (s *Simulator) traceLine(id uint) string {
+	return fmt.Sprintf("%d: %s %.9f %.9f", id, s.unit.String(), s.refLevel, s.scale)
+}
+
+func (s *Simulator) cmdCalc(args []string) error {
+	const usage = "calc [{trace#}] off|minh|maxh|maxd|aver4|aver16|quasi"
+
+	if len(args) != 2 {
+		return usageError(usage)
+	}
+
+	id, err := s.parseTraceID(args[0])
+	if err != nil {
+		return err
+	}
+
+	switch args[1] {
+	case "off":
+		s.traces[id-1].calc = ""
+	case "minh", "maxh", "maxd", "aver4", "aver16", "quasi":
+		s.traces[id-1].calc = args[1]
+	default:
+		return usageError(usage)
+	}
+
+	return nil
+}
+
+func (s *Simulator) cmdMarker(args []string) (string, error) {
+	const usage = "marker [n] [on|off|peak|{freq}|trace {n}|delta {n}|delta off|tracking on|off]"
+
+	if len(args) == 0 {
+		var lines []string
+		for i, m := range s.markers {
+			if m.enabled {
+				lines = append(lines, s.markerLine(uint(i+1))) // #nosec G115
+			}
+		}
+		return strings.Join(lines, "\r\n"), nil
+	}
+
+	id, err := parseUint(args[0])
+	if err != nil || id < 1 || id > numMarkers {
+		return "", usageError(usage)
+	}
+	m := &s.markers[id-1]
+
+	if len(args) == 1 {
+		return s.markerLine(id), nil
+	}
+
+	switch args[1] {
+	case "on":
+		m.enabled = true
+		return "", nil
+	case "off":
+		m.enabled = false
+		return "", nil
+	case "peak":
+		m.enabled = true
+		m.index = s.peakIndex(m.trace)
+		return "", nil
+	case "trace":
+		if len(args) != 3 {
+			return "", usageError(usage)
+		}
+		traceID, err := s.parseTraceID(args[2])
+		if err != nil {
+			return "", err
+		}
+		m.trace = traceID
+		return "", nil
+	case "delta":
+		if len(args) != 3 {
+			return "", usageError(usage)
+		}
+		if args[2] == "off" {
+			m.delta = 0
+			return "", nil
+		}
+		ref, err := parseUint(args[2])
+		if err != nil || ref < 1 || ref > numMarkers || ref == id {
+			return "", usageError(usage)
+		}
+		m.delta = ref
+		return "", nil
+	case "tracking":
+		if len(args) != 3 || (args[2] != "on" && args[2] != "off") {
+			return "", usageError(usage)
+		}
+		m.tracking = args[2] == "on"
+		return "", nil
+	}
+
+	freq, err := strconv.ParseUint(args[1], 10, 64)
+	if err != nil {
+		return "", usageError(usage)
+	}
+	m.enabled = true
+	m.index = s.nearestIndex(freq)
+	m.tracking = false
+
+	return "", nil
+}
+
+func (s *Simulator) markerLine(id uint) string {
+	m := &s.markers[id-1]
+	if m.tracking {
+		m.index = s.peakIndex(m.trace)
+	}
+	freqs := s.frequencies()
+	index := min(m.index, uint(len(freqs)-1)) // #nosec G115
+	value := s.traceValues(m.trace)[index]
+	out := convertValue(s.unit, value)
+	if m.delta != 0 {
+		ref := s.markers[m.delta-1]
+		refIndex := min(ref.index, uint(len(freqs)-1)) // #nosec G115
+		out = value - s.traceValues(ref.trace)[refIndex]
+	}
+	return fmt.Sprintf("%d %d %d %.2e", id, index, freqs[index], out)
+}
+
+// nearestIndex returns the index of the sweep point closest to the given frequency.
+func (s *Simulator) nearestIndex(freq uint64) uint {
+	freqs := s.frequencies()
+	best := 0
+	for i, f := range freqs {
+		if absDiff(f, freq) < absDiff(freqs[best], freq) {
+			best = i
+		}
+	}
+	return uint(best) // #nosec G115
+}
+
+// peakLevel returns the highest level in dBm of the first trace.
+func (s *Simulator) peakLevel() float64 {
+	return s.traceValues(1)[s.peakIndex(1)]
+}
+
+func (s *Simulator) cmdSpur(args []string) error {
+	if len(args) != 1 || (args[0] != "on" && args[0] != "off" && args[0] != "auto") {
+		return usageError("spur on|off|auto")
+	}
+	s.spur = args[0]
+	return nil
+}
+
+func (s *Simulator) cmdLNA(args []string) error {
+	if len(args) != 1 || (args[0] != "on" && args[0] != "off") {
+		return usageError("lna on|off")
+	}
+	s.lna = args[0] == "on"
+	return nil
+}
+
+func (s *Simulator) cmdLoad(args []string) error {
+	if len(args) != 1 {
+		return usageError("load {id}")
+	}
+	id, err := parseUint(args[0])
+	if err != nil {
+		return usageError("load {id}")
+	}
+	preset, ok := s.presets[id]
+	if !ok {
+		preset = s.defaultSettings()
+	}
+	s.settings = preset
+	s.resetSweep()
+	return nil
+}
+
+func (s *Simulator) cmdSave(args []string) error {
+	if len(args) != 1 {
+		return usageError("save {id}")
+	}
+	id, err := parseUint(args[0])
+	if err != nil {
+		return usageError("save {id}")
+	}
+	s.presets[id] = s.settings
+	return nil
+}
+
+func (s *Simulator) parseTraceID(str string) (uint, error) {
+	id, err := parseUint(str)
+	if err != nil || id < 1 || id > numTraces {
+		return 0, fmt.Errorf("invalid trace %s", str)
+	}
+	return id, nil
+}
+
+// convertValue converts a level in dBm into the given trace unit, assuming a 50 ohm system.
+func convertValue(unit tinysa.TraceUnit, dbm float64) float64 {
+	watt := dbmToMw(dbm) / 1000
+	vrms := math.Sqrt(watt * 50)
+
+	switch unit {
+	case tinysa.TraceUnitDBmV:
+		return 20 * math.Log10(vrms/1e-3)
+	case tinysa.TraceUnitDBuV:
+		return 20 * math.Log10(vrms/1e-6)
+	case tinysa.TraceUnitW:
+		return watt
+	case tinysa.TraceUnitV:
+		return vrms
+	case tinysa.TraceUnitVpp:
+		return vrms * 2 * math.Sqrt2
+	default:
+		return dbm
+	}
+}
+
+// formatValue formats a level in dBm in the given trace unit like the trace value output of the device.
+func formatValue(unit tinysa.TraceUnit, dbm float64) string {
+	switch unit {
+	case tinysa.TraceUnitW, tinysa.TraceUnitV, tinysa.TraceUnitVpp:
+		return strconv.FormatFloat(convertValue(unit, dbm), 'e', 3, 64)
+	default:
+		return strconv.FormatFloat(convertValue(unit, dbm), 'f', 2, 64)
+	}
+}
+
+type usageError string
+
+func (e usageError) Error() string {
+	return "usage: " + string(e)
+}
+
+func parseUint(s string) (uint, error) {
+	v, err := strconv.ParseUint(s, 10, 0)
+	return uint(v), err
+}
+
+func absDiff(a, b uint64) uint64 {
+	if a > b {
+		return a - b
+	}
+	return b - a
+}
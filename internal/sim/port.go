@@ -0,0 +1,54 @@
+package sim
+
+import (
+	"os"
+	"sync"
+)
+
+// Port is a simulator attached to a pseudo terminal, which can be opened like a serial port.
+type Port struct {
+	Name string
+
+	master *os.File
+	slave  *os.File
+	done   chan struct{}
+	once   sync.Once
+}
+
+// Open starts a simulator on a new pseudo terminal. The returned port name can be passed to tinysa.NewDevice.
+func Open(opts Options) (*Port, error) {
+	s, err := New(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	master, slave, name, err := openPty()
+	if err != nil {
+		return nil, err
+	}
+
+	p := &Port{
+		Name:   name,
+		master: master,
+		slave:  slave,
+		done:   make(chan struct{}),
+	}
+
+	go func() {
+		defer close(p.done)
+		_ = s.Serve(master)
+	}()
+
+	return p, nil
+}
+
+// Close stops the simulator and releases the pseudo terminal.
+func (p *Port) Close() error {
+	var err error
+	p.once.Do(func() {
+		err = p.master.Close()
+		_ = p.slave.Close()
+		<-p.done
+	})
+	return err
+}
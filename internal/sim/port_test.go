@@ -0,0 +1,69 @@
+package sim
+
+import (
+	"runtime"
+	"testing"
+
+	"github.com/kkettinger/go-tinysa"
+)
+
+func TestOpen(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skipf("simulator needs a pseudo terminal, which is only implemented on linux, not %s", runtime.GOOS)
+	}
+
+	opts, err := ParseURL("sim://ultra?signal=433.92M:-40&jitter=0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	port, err := Open(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer port.Close()
+
+	d, err := tinysa.NewDevice(port.Name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer d.Close()
+
+	if d.Model() != tinysa.ModelUltra {
+		t.Errorf("model = %s, want %s", d.Model(), tinysa.ModelUltra)
+	}
+
+	if err := d.SetSweepStartStopWithPoints(433_420_000, 434_420_000, 101); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := d.GetTraceData(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(data) != 101 {
+		t.Fatalf("got %d points, want 101", len(data))
+	}
+	if data[50].Frequency != 433_920_000 || data[50].Value != -40 {
+		t.Errorf("center point = %+v, want 433.92 MHz at -40 dBm", data[50])
+	}
+
+	if err := d.MoveMarkerPeak(1); err != nil {
+		t.Fatal(err)
+	}
+	m, err := d.GetMarker(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.Frequency != 433_920_000 {
+		t.Errorf("marker frequency = %d, want 433920000", m.Frequency)
+	}
+
+	img, err := d.Capture()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if img.Bounds().Dx() != 480 || img.Bounds().Dy() != 320 {
+		t.Errorf("capture size = %v, want 480x320", img.Bounds())
+	}
+}
@@ -0,0 +1,38 @@
+package sim
+
+import (
+	"fmt"
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// openPty allocates a new pseudo terminal and returns both ends together with the name of the slave device.
+// The slave end is kept open, so reading the master does not fail before a client connects.
+func openPty() (master *os.File, slave *os.File, name string, err error) {
+	fd, err := unix.Open("/dev/ptmx", unix.O_RDWR|unix.O_NOCTTY|unix.O_NONBLOCK|unix.O_CLOEXEC, 0)
+	if err != nil {
+		return nil, nil, "", fmt.Errorf("failed to open pseudo terminal: %w", err)
+	}
+	master = os.NewFile(uintptr(fd), "/dev/ptmx")
+
+	if err := unix.IoctlSetPointerInt(fd, unix.TIOCSPTLCK, 0); err != nil {
+		_ = master.Close()
+		return nil, nil, "", fmt.Errorf("failed to unlock pseudo terminal: %w", err)
+	}
+
+	n, err := unix.IoctlGetUint32(fd, unix.TIOCGPTN)
+	if err != nil {
+		_ = master.Close()
+		return nil, nil, "", fmt.Errorf("failed to get pseudo terminal name: %w", err)
+	}
+	name = fmt.Sprintf("/dev/pts/%d", n)
+
+	slave, err = os.OpenFile(name, os.O_RDWR|unix.O_NOCTTY, 0)
+	if err != nil {
+		_ = master.Close()
+		return nil, nil, "", fmt.Errorf("failed to open pseudo terminal %s: %w", name, err)
+	}
+
+	return master, slave, name, nil
+}
@@ -0,0 +1,13 @@
+//go:build !linux
+
+package sim
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+)
+
+func openPty() (master *os.File, slave *os.File, name string, err error) {
+	return nil, nil, "", fmt.Errorf("simulated devices need a pseudo terminal, which is only implemented on linux, not %s", runtime.GOOS)
+}
@@ -0,0 +1,47 @@
+package sim
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"strings"
+)
+
+const (
+	responsePrompt    = "ch> "
+	commandTerminator = "\r\n"
+)
+
+// Serve reads commands from rw and answers them like the serial console of the device: every response starts
+// with the echoed command and ends with the prompt. It returns when rw is closed.
+func (s *Simulator) Serve(rw io.ReadWriter) error {
+	r := bufio.NewReader(rw)
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return err
+		}
+
+		line = strings.TrimRight(line, commandTerminator)
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+
+		res, binary := s.Handle(line)
+
+		var out strings.Builder
+		out.WriteString(line + commandTerminator)
+		out.Write(res)
+		if len(res) > 0 && !binary {
+			out.WriteString(commandTerminator)
+		}
+		out.WriteString(responsePrompt)
+
+		if _, err := io.WriteString(rw, out.String()); err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,357 @@
+// Package sim implements a simulated tinySA that speaks the serial protocol of the real device.
+package sim
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/kkettinger/go-tinysa"
+	"github.com/kkettinger/tsactl/internal/util"
+)
+
+// Scheme is the URL scheme used to select a simulated device, e.g. sim://ultra.
+const Scheme = "sim"
+
+const (
+	numTraces  = 4
+	numMarkers = 4
+)
+
+// modelInfo contains the properties of a simulated model.
+type modelInfo struct {
+	banner        string
+	hwVersion     string
+	width         int
+	height        int
+	maxFrequency  uint64
+	maxPoints     uint
+	defaultStart  uint64
+	defaultStop   uint64
+	defaultPoints uint
+}
+
+var models = map[tinysa.Model]modelInfo{
+	tinysa.ModelBasic: {
+		banner:        "tinySA_v1.4-143-g864bb27",
+		hwVersion:     "0.3.1",
+		width:         320,
+		height:        280,
+		maxFrequency:  960_000_000,
+		maxPoints:     290,
+		defaultStart:  0,
+		defaultStop:   350_000_000,
+		defaultPoints: 290,
+	},
+	tinysa.ModelUltra: {
+		banner:        "tinySA4_v1.4-197-gaa78ccc",
+		hwVersion:     "0.4.5.1",
+		width:         480,
+		height:        320,
+		maxFrequency:  6_000_000_000,
+		maxPoints:     450,
+		defaultStart:  0,
+		defaultStop:   800_000_000,
+		defaultPoints: 450,
+	},
+}
+
+// Options configures a Simulator.
+type Options struct {
+	Model    tinysa.Model
+	Spectrum Spectrum
+	Seed     uint64
+}
+
+// ParseURL parses a device URL like sim://ultra?noise=-95&signal=433.92M:-40,100M:-30:200k&seed=7.
+//
+// Supported query parameters:
+//   - noise: displayed noise floor in dBm
+//   - jitter: standard deviation of the noise in dB
+//   - signal: comma separated list of FREQ:LEVEL[:BANDWIDTH], replaces the default signals
+//   - seed: seed for the noise generator
+func ParseURL(rawURL string) (Options, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return Options{}, fmt.Errorf("invalid simulator url '%s': %w", rawURL, err)
+	}
+	if u.Scheme != Scheme {
+		return Options{}, fmt.Errorf("invalid simulator url '%s': scheme must be %s://", rawURL, Scheme)
+	}
+
+	opts := Options{Spectrum: DefaultSpectrum(), Seed: 1}
+
+	switch strings.ToLower(u.Host) {
+	case "basic":
+		opts.Model = tinysa.ModelBasic
+	case "ultra":
+		opts.Model = tinysa.ModelUltra
+	default:
+		return Options{}, fmt.Errorf("invalid simulator model '%s', must be one of: basic, ultra", u.Host)
+	}
+
+	q := u.Query()
+
+	if v := q.Get("noise"); v != "" {
+		if opts.Spectrum.NoiseFloor, err = strconv.ParseFloat(v, 64); err != nil {
+			return Options{}, fmt.Errorf("invalid noise floor '%s': %w", v, err)
+		}
+	}
+
+	if v := q.Get("jitter"); v != "" {
+		if opts.Spectrum.Jitter, err = strconv.ParseFloat(v, 64); err != nil {
+			return Options{}, fmt.Errorf("invalid jitter '%s': %w", v, err)
+		}
+	}
+
+	if v := q.Get("seed"); v != "" {
+		if opts.Seed, err = strconv.ParseUint(v, 10, 64); err != nil {
+			return Options{}, fmt.Errorf("invalid seed '%s': %w", v, err)
+		}
+	}
+
+	if values, ok := q["signal"]; ok {
+		opts.Spectrum.Signals = nil
+		for _, v := range values {
+			for _, s := range strings.Split(v, ",") {
+				if s == "" {
+					continue
+				}
+				sig, err := parseSignal(s)
+				if err != nil {
+					return Options{}, err
+				}
+				opts.Spectrum.Signals = append(opts.Spectrum.Signals, sig)
+			}
+		}
+	}
+
+	return opts, nil
+}
+
+// parseSignal parses a signal definition in the form FREQ:LEVEL[:BANDWIDTH].
+func parseSignal(s string) (Signal, error) {
+	parts := strings.Split(s, ":")
+	if len(parts) < 2 || len(parts) > 3 {
+		return Signal{}, fmt.Errorf("invalid signal '%s', expected FREQ:LEVEL[:BANDWIDTH]", s)
+	}
+
+	freq, err := util.ParseFrequency(parts[0])
+	if err != nil {
+		return Signal{}, fmt.Errorf("invalid signal frequency '%s': %w", parts[0], err)
+	}
+
+	level, err := strconv.ParseFloat(parts[1], 64)
+	if err != nil {
+		return Signal{}, fmt.Errorf("invalid signal level '%s': %w", parts[1], err)
+	}
+
+	sig := Signal{Frequency: freq, Level: level}
+	if len(parts) == 3 {
+		if sig.Bandwidth, err = util.ParseFrequency(parts[2]); err != nil {
+			return Signal{}, fmt.Errorf("invalid signal bandwidth '%s': %w", parts[2], err)
+		}
+	}
+
+	return sig, nil
+}
+
+// traceState holds the settings and data of a single trace.
+type traceState struct {
+	enabled bool
+	calc    string
+	values  []float64 // last values in dBm
+}
+
+// markerState holds the settings of a single marker.
+type markerState struct {
+	enabled  bool
+	trace    uint
+	index    uint
+	tracking bool
+	delta    uint
+}
+
+// settings holds everything that is stored in a preset.
+type settings struct {
+	start     uint64
+	stop      uint64
+	points    uint
+	mode      string
+	sweepTime uint64
+	unit      tinysa.TraceUnit
+	refLevel  float64
+	scale     float64
+	spur      string
+	lna       bool
+	traces    [numTraces]traceState
+	markers   [numMarkers]markerState
+}
+
+// Simulator emulates the command interpreter of a tinySA.
+type Simulator struct {
+	mu       sync.Mutex
+	model    tinysa.Model
+	info     modelInfo
+	spectrum Spectrum
+	rnd      *rand.Rand
+
+	settings
+	paused     bool
+	deviceID   uint
+	vbat       uint
+	vbatOffset uint
+	presets    map[uint]settings
+}
+
+// New creates a new Simulator for the given options.
+func New(opts Options) (*Simulator, error) {
+	info, ok := models[opts.Model]
+	if !ok {
+		return nil, fmt.Errorf("unknown model %s", opts.Model)
+	}
+
+	s := &Simulator{
+		model:    opts.Model,
+		info:     info,
+		spectrum: opts.Spectrum,
+		rnd:      rand.New(rand.NewPCG(opts.Seed, opts.Seed)), // #nosec G404
+		vbat:     4100,
+		presets:  map[uint]settings{},
+	}
+	s.settings = s.defaultSettings()
+
+	return s, nil
+}
+
+// Model returns the simulated model.
+func (s *Simulator) Model() tinysa.Model {
+	return s.model
+}
+
+func (s *Simulator) defaultSettings() settings {
+	st := settings{
+		start:    s.info.defaultStart,
+		stop:     s.info.defaultStop,
+		points:   s.info.defaultPoints,
+		mode:     "normal",
+		unit:     tinysa.TraceUnitDBm,
+		refLevel: -10,
+		scale:    10,
+		spur:     "auto",
+	}
+	st.traces[0].enabled = true
+	for i := range st.markers {
+		st.markers[i].trace = 1
+		st.markers[i].index = s.info.defaultPoints / 2
+	}
+	st.markers[0].enabled = true
+	st.markers[0].tracking = true
+	return st
+}
+
+// frequencies returns the frequency of every sweep point.
+func (s *Simulator) frequencies() []uint64 {
+	freqs := make([]uint64, s.points)
+	if s.points == 1 {
+		freqs[0] = s.start
+		return freqs
+	}
+	step := float64(s.stop-s.start) / float64(s.points-1)
+	for i := range freqs {
+		freqs[i] = s.start + uint64(float64(i)*step+0.5)
+	}
+	return freqs
+}
+
+// rbw returns the resolution bandwidth in Hz used for rendering, derived from the point spacing.
+func (s *Simulator) rbw() float64 {
+	spacing := float64(s.stop-s.start) / float64(max(s.points-1, 1))
+	return max(spacing*1.5, 3000)
+}
+
+// sweep renders a new sweep into all enabled traces, applying the trace calculations.
+func (s *Simulator) sweep() {
+	if s.paused && s.traces[0].values != nil {
+		return
+	}
+
+	values := s.spectrum.render(s.rnd, s.frequencies(), s.rbw())
+
+	for i := range s.traces {
+		t := &s.traces[i]
+		if t.values == nil || len(t.values) != len(values) || t.calc == "" {
+			t.values = append([]float64(nil), values...)
+			continue
+		}
+		for j, v := range values {
+			switch t.calc {
+			case "maxh", "maxd", "quasi":
+				t.values[j] = max(t.values[j], v)
+			case "minh":
+				t.values[j] = min(t.values[j], v)
+			case "aver4":
+				t.values[j] = mwToDbm((dbmToMw(t.values[j])*3 + dbmToMw(v)) / 4)
+			case "aver16":
+				t.values[j] = mwToDbm((dbmToMw(t.values[j])*15 + dbmToMw(v)) / 16)
+			default:
+				t.values[j] = v
+			}
+		}
+	}
+
+	// move tracking markers to the new peak
+	for i := range s.markers {
+		if m := &s.markers[i]; m.enabled && m.tracking {
+			m.index = s.peakIndex(m.trace)
+		}
+	}
+}
+
+// traceValues returns the current values of a trace in dBm, rendering a sweep if there is no data yet.
+func (s *Simulator) traceValues(traceID uint) []float64 {
+	t := &s.traces[traceID-1]
+	if len(t.values) != int(s.points) {
+		s.sweep()
+	}
+	return t.values
+}
+
+// peakIndex returns the point index of the highest value of the given trace.
+func (s *Simulator) peakIndex(traceID uint) uint {
+	values := s.traceValues(traceID)
+	peak := 0
+	for i, v := range values {
+		if v > values[peak] {
+			peak = i
+		}
+	}
+	return uint(peak) // #nosec G115
+}
+
+// resetSweep clears the trace data after the sweep range changed.
+func (s *Simulator) resetSweep() {
+	for i := range s.traces {
+		s.traces[i].values = nil
+	}
+	for i := range s.markers {
+		s.markers[i].index = min(s.markers[i].index, s.points-1)
+	}
+}
+
+// setSweep validates and applies a new sweep range.
+func (s *Simulator) setSweep(start, stop uint64, points uint) {
+	start = min(start, s.info.maxFrequency)
+	stop = min(stop, s.info.maxFrequency)
+	if stop < start {
+		stop = start
+	}
+	points = min(max(points, 1), s.info.maxPoints)
+	if start != s.start || stop != s.stop || points != s.points {
+		s.start, s.stop, s.points = start, stop, points
+		s.resetSweep()
+	}
+}
@@ -0,0 +1,155 @@
+package sim
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/kkettinger/go-tinysa"
+)
+
+func TestParseURL(t *testing.T) {
+	tests := []struct {
+		input       string
+		model       tinysa.Model
+		signals     int
+		expectError bool
+	}{
+		{"sim://ultra", tinysa.ModelUltra, 3, false},
+		{"sim://basic", tinysa.ModelBasic, 3, false},
+		{"sim://ULTRA", tinysa.ModelUltra, 3, false},
+		{"sim://ultra?signal=433.92M:-40", tinysa.ModelUltra, 1, false},
+		{"sim://ultra?signal=433.92M:-40,100M:-30:200k", tinysa.ModelUltra, 2, false},
+		{"sim://ultra?signal=433.92M:-40&signal=1G:-20", tinysa.ModelUltra, 2, false},
+		{"sim://ultra?signal=", tinysa.ModelUltra, 0, false},
+		{"sim://ultra?noise=-90&jitter=0&seed=3", tinysa.ModelUltra, 3, false},
+		{"sim://zs405", "", 0, true},
+		{"tcp://ultra", "", 0, true},
+		{"sim://ultra?signal=433.92M", "", 0, true},
+		{"sim://ultra?signal=foo:-40", "", 0, true},
+		{"sim://ultra?noise=low", "", 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			opts, err := ParseURL(tt.input)
+			if (err != nil) != tt.expectError {
+				t.Fatalf("error = %v, wantErr %v", err, tt.expectError)
+			}
+			if tt.expectError {
+				return
+			}
+			if opts.Model != tt.model {
+				t.Errorf("model = %s, want %s", opts.Model, tt.model)
+			}
+			if len(opts.Spectrum.Signals) != tt.signals {
+				t.Errorf("signals = %d, want %d", len(opts.Spectrum.Signals), tt.signals)
+			}
+		})
+	}
+}
+
+func newTestSimulator(t *testing.T) *Simulator {
+	t.Helper()
+	s, err := New(Options{
+		Model: tinysa.ModelUltra,
+		Spectrum: Spectrum{
+			NoiseFloor: -100,
+			Signals:    []Signal{{Frequency: 100_000_000, Level: -30}},
+		},
+		Seed: 1,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func TestHandle(t *testing.T) {
+	s := newTestSimulator(t)
+
+	tests := []struct {
+		cmd      string
+		expected string
+	}{
+		{"version", "tinySA4_v1.4-197-gaa78ccc\r\nHW Version:V0.4.5.1"},
+		{"sweep", "0 800000000 450"},
+		{"sweep 50000000 150000000 101", ""},
+		{"sweep", "50000000 150000000 101"},
+		{"sweep center 100000000", ""},
+		{"sweep span 10000000", ""},
+		{"sweep", "95000000 105000000 101"},
+		{"sweep cw 7000000000", ""},
+		{"sweep", "6000000000 6000000000 101"},
+		{"sweep 95000000 105000000 101", ""},
+		{"status", "Resumed"},
+		{"pause", ""},
+		{"status", "Paused"},
+		{"deviceid", "deviceid 0"},
+		{"deviceid 7", ""},
+		{"deviceid", "deviceid 7"},
+		{"vbat", "4100 mV"},
+		{"trace", "1: dBm -10.000000000 10.000000000"},
+		{"trace 2 view on", ""},
+		{"trace dBuV", ""},
+		{"trace scale 5", ""},
+		{"trace 2", "2: dBuV -10.000000000 5.000000000"},
+		{"trace reflevel -40", ""},
+		{"trace", "1: dBuV -40.000000000 5.000000000\r\n2: dBuV -40.000000000 5.000000000"},
+		{"trace dBm", ""},
+		{"marker 1 100000000", ""},
+		{"marker", "1 50 100000000 -3.00e+01"},
+		{"marker 2 95000000", ""},
+		{"marker 2 delta 1", ""},
+		{"marker 2", "2 0 95000000 -7.00e+01"},
+		{"marker 5", "usage: marker [n] [on|off|peak|{freq}|trace {n}|delta {n}|delta off|tracking on|off]"},
+		{"calc 1 maxh", ""},
+		{"calc 1 foo", "usage: calc [{trace#}] off|minh|maxh|maxd|aver4|aver16|quasi"},
+		{"save 1", ""},
+		{"sweep 0 800000000 450", ""},
+		{"load 1", ""},
+		{"sweep", "95000000 105000000 101"},
+		{"foo bar", "foo?"},
+	}
+
+	for _, tt := range tests {
+		res, binary := s.Handle(tt.cmd)
+		if binary {
+			t.Errorf("Handle(%q) returned binary response", tt.cmd)
+		}
+		if string(res) != tt.expected {
+			t.Errorf("Handle(%q) = %q, want %q", tt.cmd, res, tt.expected)
+		}
+	}
+}
+
+func TestHandleTraceValues(t *testing.T) {
+	s := newTestSimulator(t)
+	s.Handle("sweep 90000000 110000000 101")
+
+	res, _ := s.Handle("trace 1 value")
+	lines := strings.Split(string(res), "\r\n")
+	if len(lines) != 101 {
+		t.Fatalf("got %d lines, want 101", len(lines))
+	}
+	if lines[50] != "trace 1 value 50 -30.00" {
+		t.Errorf("peak line = %q, want %q", lines[50], "trace 1 value 50 -30.00")
+	}
+
+	res, _ = s.Handle("frequencies")
+	freqs := strings.Split(string(res), "\r\n")
+	if len(freqs) != 101 || freqs[0] != "90000000" || freqs[50] != "100000000" || freqs[100] != "110000000" {
+		t.Errorf("unexpected frequencies: %v", freqs)
+	}
+}
+
+func TestHandleCapture(t *testing.T) {
+	s := newTestSimulator(t)
+
+	res, binary := s.Handle("capture")
+	if !binary {
+		t.Errorf("capture response is not binary")
+	}
+	if len(res) != 480*320*2 {
+		t.Errorf("capture size = %d, want %d", len(res), 480*320*2)
+	}
+}
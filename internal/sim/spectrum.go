@@ -0,0 +1,79 @@
+package sim
+
+import (
+	"math"
+	"math/rand/v2"
+)
+
+// Signal is a synthetic carrier rendered into the simulated spectrum.
+type Signal struct {
+	Frequency uint64  // Center frequency in Hz
+	Level     float64 // Total signal power in dBm
+	Bandwidth uint64  // Occupied bandwidth in Hz, 0 for a CW carrier
+}
+
+// Spectrum describes the synthetic spectrum seen by the simulated device.
+type Spectrum struct {
+	NoiseFloor float64 // Displayed noise floor in dBm
+	Jitter     float64 // Standard deviation of the noise in dB
+	Signals    []Signal
+}
+
+// DefaultSpectrum returns a spectrum with a few carriers inside the default sweep range of the model.
+func DefaultSpectrum() Spectrum {
+	return Spectrum{
+		NoiseFloor: -100,
+		Jitter:     1.5,
+		Signals: []Signal{
+			{Frequency: 100_000_000, Level: -30},
+			{Frequency: 145_500_000, Level: -55, Bandwidth: 12_500},
+			{Frequency: 433_920_000, Level: -45},
+		},
+	}
+}
+
+// render returns one sweep of the spectrum in dBm at the given frequencies, measured with the given RBW.
+func (s Spectrum) render(rnd *rand.Rand, freqs []uint64, rbw float64) []float64 {
+	values := make([]float64, len(freqs))
+	for i, f := range freqs {
+		noise := s.NoiseFloor
+		if s.Jitter > 0 {
+			noise += rnd.NormFloat64() * s.Jitter
+		}
+		power := dbmToMw(noise)
+		for _, sig := range s.Signals {
+			power += sig.powerAt(float64(f), rbw)
+		}
+		values[i] = mwToDbm(power)
+	}
+	return values
+}
+
+// powerAt returns the signal power in mW that falls into a filter with the given RBW, centered at freq.
+func (sig Signal) powerAt(freq, rbw float64) float64 {
+	total := dbmToMw(sig.Level)
+	offset := math.Abs(freq - float64(sig.Frequency))
+	sigma := rbw / 2.3548 // gaussian filter with rbw as FWHM
+
+	// CW carriers (or carriers narrower than the filter) show up as the filter shape
+	bw := float64(sig.Bandwidth)
+	if bw <= rbw {
+		return total * math.Exp(-0.5*(offset/sigma)*(offset/sigma))
+	}
+
+	// wide signals have a flat top with a density of total/bw and filter shaped skirts
+	density := total / bw
+	edge := offset - bw/2
+	if edge <= 0 {
+		return density * rbw
+	}
+	return density * rbw * math.Exp(-0.5*(edge/sigma)*(edge/sigma))
+}
+
+func dbmToMw(dbm float64) float64 {
+	return math.Pow(10, dbm/10)
+}
+
+func mwToDbm(mw float64) float64 {
+	return 10 * math.Log10(mw)
+}